@@ -24,19 +24,32 @@ func wireApp(bc *conf.Bootstrap, log *slog.Logger) (http.Handler, func(), error)
 	}
 	core := api.NewVersion(db)
 	versionAPI := api.NewVersionAPI(core)
-	smsCore := api.NewSMSCore(db, bc)
+	bus := api.NewEventBus()
+	client := api.NewRedisClient(bc)
+	cluster, cleanup := api.NewCluster(bc)
+	smsCore := api.NewSMSCore(db, bc, bus, cluster)
 	smsAPI := api.NewSmsAPI(smsCore)
 	uniqueidCore := api.NewUniqueID(db)
 	mediaCore := api.NewMediaCore(db, uniqueidCore)
-	storer := api.NewGB28181Store(db)
-	gb28181 := api.NewGB28181(storer, uniqueidCore)
-	server, cleanup := gbs.NewServer(bc, gb28181, smsCore)
-	gb28181Core := api.NewGB28181Core(storer, uniqueidCore)
-	webHookAPI := api.NewWebHookAPI(smsCore, mediaCore, bc, server, gb28181Core)
+	storer := api.NewGB28181Store(db, bc, client, cluster)
+	gb28181 := api.NewGB28181(storer, uniqueidCore, bus)
+	playSessionStorer := api.NewPlaySessionStore(db, bc, client)
+	server, cleanup2 := gbs.NewServer(bc, gb28181, smsCore, bus, playSessionStorer, cluster, client)
+	registry := api.NewRegionRegistry(bc)
+	gb28181Core := api.NewGB28181Core(storer, uniqueidCore, registry)
+	proxyCore := api.NewProxyCore(db, uniqueidCore)
+	webHookAPI := api.NewWebHookAPI(smsCore, mediaCore, bc, server, gb28181Core, proxyCore, bus)
 	mediaAPI := api.NewMediaAPI(mediaCore, smsCore, bc)
 	gb28181API := api.NewGB28181API(gb28181Core)
-	proxyAPI := api.NewProxyAPI(db, uniqueidCore)
+	proxyAPI := api.NewProxyAPI(proxyCore)
 	configAPI := api.NewConfigAPI(db, bc)
+	userAPI := api.NewUserAPI(db, uniqueidCore, bc)
+	auditAPI := api.NewAuditAPI(db, bc)
+	notifyAPI := api.NewNotifyAPI(db, uniqueidCore, bc, bus)
+	eventAPI := api.NewEventAPI(bus)
+	groupCore := api.NewGroupCore(db, uniqueidCore, bc)
+	groupAPI := api.NewGroupAPI(groupCore)
+	regionAPI := api.NewRegionAPI(registry, bc)
 	usecase := &api.Usecase{
 		Conf:       bc,
 		DB:         db,
@@ -48,10 +61,18 @@ func wireApp(bc *conf.Bootstrap, log *slog.Logger) (http.Handler, func(), error)
 		GB28181API: gb28181API,
 		ProxyAPI:   proxyAPI,
 		ConfigAPI:  configAPI,
+		UserAPI:    userAPI,
+		AuditAPI:   auditAPI,
+		NotifyAPI:  notifyAPI,
+		EventAPI:   eventAPI,
+		GroupAPI:   groupAPI,
+		RegionAPI:  regionAPI,
 		SipServer:  server,
+		Cluster:    cluster,
 	}
 	handler := api.NewHTTPHandler(usecase)
 	return handler, func() {
+		cleanup2()
 		cleanup()
 	}, nil
 }
@@ -8,24 +8,69 @@ type Bootstrap struct {
 	ConfigDir    string `toml:"-" json:"-"`
 	ConfigPath   string `toml:"-" json:"-"`
 
-	Server Server // 服务器
-	Data   Data   // 数据
-	Log    Log    // 日志
-	Sip    SIP
-	Media  Media // 媒体
+	Server  Server // 服务器
+	Data    Data   // 数据
+	Log     Log    // 日志
+	Sip     SIP
+	Media   Media   // 媒体
+	Cluster Cluster `comment:"集群部署，多个实例通过 Data.Redis 共享设备状态，定时任务仅在主节点执行"`
+}
+
+// Cluster 集群配置
+type Cluster struct {
+	Enabled   bool     `comment:"是否启用集群，启用后 Sip.Session.Store 应使用 db 或 redis"`
+	NodeID    string   `comment:"实例标识，集群内唯一，空串时使用主机名"`
+	Advertise string   `comment:"其它实例访问本实例的 http 地址，如 http://10.0.0.2:15123"`
+	Secret    string   `comment:"实例间转发请求的密钥，各实例需保持一致"`
+	LeaseTTL  Duration `comment:"主节点租约有效期，主节点宕机后其它实例最迟在该时间后接管"`
 }
 
 type Server struct {
 	Debug      bool
-	RTMPSecret string     `comment:"rtmp 推流秘钥"`
-	HTTP       ServerHTTP `comment:"对外提供的服务，建议由 nginx 代理"` // HTTP服务器
+	RTMPSecret string          `comment:"rtmp 推流秘钥"`
+	PlayToken  ServerPlayToken `comment:"播放鉴权，开启后播放地址将携带有时效的签名令牌"`
+	HTTP       ServerHTTP      `comment:"对外提供的服务，建议由 nginx 代理"` // HTTP服务器
+	Admin      ServerAdmin     `comment:"初始管理员账号，仅在不存在任何用户时创建"`
+	Audit      ServerAudit     `comment:"操作审计，记录登录用户的播放、控制、修改、删除等操作"`
+	Notify     ServerNotify    `comment:"事件回调，将设备、通道、流、报警等事件推送到订阅的地址"`
+}
+
+// ServerNotify 事件回调配置
+type ServerNotify struct {
+	Workers     int      `comment:"并发投递数"`
+	MaxAttempts int      `comment:"最大尝试次数，失败后按指数退避重试"`
+	Timeout     Duration `comment:"单次请求超时时间"`
+	Retention   Duration `comment:"投递记录保留时长，0 表示永久保留"`
+}
+
+// ServerAudit 操作审计配置
+type ServerAudit struct {
+	Enabled   bool     `comment:"是否记录审计日志"`
+	Retention Duration `comment:"审计日志保留时长，0 表示永久保留"`
+}
+
+// ServerAdmin 初始管理员账号
+type ServerAdmin struct {
+	Username string `comment:"用户名"`
+	Password string `comment:"密码，空串时随机生成并输出到日志"`
+}
+
+// ServerPlayToken 播放令牌配置
+type ServerPlayToken struct {
+	Enabled bool     `comment:"是否启用播放鉴权"`
+	Secret  string   `comment:"签名秘钥，空串时使用 jwt 秘钥"`
+	TTL     Duration `comment:"令牌有效期"`
+	BindIP  bool     `comment:"令牌是否绑定客户端 IP，经过代理播放时请关闭"`
+	Realm   string   `comment:"rtsp 摘要认证域，为空时不启用 rtsp 专用认证"`
 }
 
 type ServerHTTP struct {
-	Port      int         `comment:"http 端口"`                // 服务器端口号
-	Timeout   Duration    `comment:"请求超时时间"`                 // 请求超时时间
-	JwtSecret string      `comment:"jwt 秘钥，空串时，每次启动程序将随机赋值"` // JWT密钥
-	PProf     ServerPPROF // Pprof配置
+	Port      int           `comment:"http 端口"`                // 服务器端口号
+	Timeout   Duration      `comment:"请求超时时间"`                 // 请求超时时间
+	JwtSecret string        `comment:"jwt 秘钥，空串时，每次启动程序将随机赋值"` // JWT密钥
+	TokenTTL  Duration      `comment:"登录令牌有效期"`                // 登录令牌有效期
+	PProf     ServerPPROF   // Pprof配置
+	Metrics   ServerMetrics `comment:"prometheus 指标，地址 /metrics"` // 指标配置
 }
 
 // ServerPPROF 结构体，包含 Enabled 和 AccessIps 两个字段
@@ -34,12 +79,26 @@ type ServerPPROF struct {
 	AccessIps []string `comment:"访问白名单" json:"access_ips"` // 允许访问的IP地址列表
 }
 
+// ServerMetrics prometheus 指标
+type ServerMetrics struct {
+	Enabled   bool     `comment:"是否启用 /metrics 指标接口"`             // 是否启用
+	AccessIps []string `comment:"访问白名单，为空时不限制" json:"access_ips"` // 允许访问的IP地址列表
+}
+
 // Data 结构体，包含 Database 和 Redis 两个字段
 type Data struct {
 	// Database 数据库
 	Database Database `comment:"数据库支持 sqlite 和 postgres 两种，使用 sqlite 时 dsn 应当填写文件存储路径"`
 	// Redis Redis数据库
-	// Redis DataRedis
+	Redis DataRedis `comment:"redis 连接，兼容 RESP 协议的服务均可使用，Sip.Session.Store 为 redis 或启用集群时使用"`
+}
+
+// DataRedis redis 连接
+type DataRedis struct {
+	Addr     string `comment:"地址，如 127.0.0.1:6379"` // 地址
+	Password string `comment:"密码"`                  // 密码
+	DB       int    `comment:"数据库编号"`               // 数据库编号
+	Prefix   string `comment:"键前缀，多个服务共用时用于区分"`     // 键前缀
 }
 
 // Database 结构体，包含 Dsn、MaxIdleConns、MaxOpenConns、ConnMaxLifetime 和 SlowThreshold 五个字段
@@ -61,10 +120,54 @@ type Log struct {
 }
 
 type SIP struct {
-	Port     int    `comment:"服务监听的 tcp/udp 端口号" json:"port"`
-	ID       string `comment:"gb/t28181 20 位国标 ID" json:"id"`
-	Domain   string `comment:"域" json:"domain"`
-	Password string `comment:"注册密码" json:"password"`
+	Port     int        `comment:"服务监听的 tcp/udp 端口号" json:"port"`
+	ID       string     `comment:"gb/t28181 20 位国标 ID" json:"id"`
+	Domain   string     `comment:"域" json:"domain"`
+	Password string     `comment:"注册密码" json:"password"`
+	TLS      SIPTLS     `comment:"sip over tls，证书文件变更后自动加载" json:"tls"`
+	Auth     SIPAuth    `comment:"注册鉴权" json:"auth"`
+	Guard    SIPGuard   `comment:"信令防护，按来源 IP 限速，鉴权失败过多时临时封禁" json:"guard"`
+	Capture  SIPCapture `comment:"信令抓包，按设备保留最近的原始报文，用于排查设备兼容问题" json:"capture"`
+	Session  SIPSession `comment:"点播会话，持久化后程序重启时恢复仍在推流的会话，关闭失效的会话" json:"session"`
+
+	CatalogInterval Duration `comment:"定时查询在线设备目录的间隔，0 表示不查询" json:"catalog_interval"`
+	CivilCodeFile   string   `comment:"行政区划数据文件，每行 编码,名称，为空时使用内置数据" json:"civil_code_file"`
+
+	AdmissionPolicy string `comment:"设备准入策略 open:自动添加未知设备 allowlist:仅允许已添加的设备 pending:未知设备等待审批" json:"admission_policy"`
+}
+
+// SIPSession 点播会话
+type SIPSession struct {
+	Store string `comment:"存储方式 memory:内存，重启后丢失 db:数据库 redis:使用 Data.Redis" json:"store"`
+}
+
+// SIPCapture 信令抓包
+type SIPCapture struct {
+	Enabled bool `comment:"是否开启抓包，报文包含鉴权摘要等敏感信息" json:"enabled"`
+	Size    int  `comment:"每个设备保留的报文数" json:"size"`
+}
+
+// SIPGuard 信令防护
+type SIPGuard struct {
+	Rate        float64  `comment:"单个来源 IP 每秒允许的请求数，0 表示不限速" json:"rate"`
+	Burst       int      `comment:"单个来源 IP 允许的突发请求数" json:"burst"`
+	MaxFailures int      `comment:"窗口内鉴权失败达到该次数后封禁，0 表示不封禁" json:"max_failures"`
+	FailWindow  Duration `comment:"鉴权失败统计窗口" json:"fail_window"`
+	BanDuration Duration `comment:"封禁时长" json:"ban_duration"`
+}
+
+// SIPAuth 注册鉴权
+type SIPAuth struct {
+	Algorithm    string   `comment:"摘要算法 MD5/SHA-256" json:"algorithm"`
+	NonceExpires Duration `comment:"nonce 有效期，过期后要求设备使用新的 nonce 重新鉴权" json:"nonce_expires"`
+}
+
+// SIPTLS 加密信令
+type SIPTLS struct {
+	Enabled  bool   `comment:"是否启用 tls 监听" json:"enabled"`
+	Port     int    `comment:"tls 端口" json:"port"`
+	CertFile string `comment:"证书文件路径" json:"cert_file"`
+	KeyFile  string `comment:"私钥文件路径" json:"key_file"`
 }
 
 type Media struct {
@@ -10,14 +10,37 @@ func DefaultConfig() Bootstrap {
 	return Bootstrap{
 		Server: Server{
 			RTMPSecret: "123",
+			PlayToken: ServerPlayToken{
+				Enabled: true,
+				TTL:     Duration(2 * time.Hour),
+				Realm:   "wvp",
+			},
 			HTTP: ServerHTTP{
 				Port:      15123,
 				Timeout:   Duration(60 * time.Second),
 				JwtSecret: orm.GenerateRandomString(24),
+				TokenTTL:  Duration(24 * time.Hour),
 				PProf: ServerPPROF{
 					Enabled:   true,
 					AccessIps: []string{"::1", "127.0.0.1"},
 				},
+				Metrics: ServerMetrics{
+					Enabled:   true,
+					AccessIps: []string{"::1", "127.0.0.1"},
+				},
+			},
+			Admin: ServerAdmin{
+				Username: "admin",
+			},
+			Audit: ServerAudit{
+				Enabled:   true,
+				Retention: Duration(90 * 24 * time.Hour),
+			},
+			Notify: ServerNotify{
+				Workers:     4,
+				MaxAttempts: 6,
+				Timeout:     Duration(5 * time.Second),
+				Retention:   Duration(7 * 24 * time.Hour),
 			},
 		},
 		Data: Data{
@@ -28,12 +51,39 @@ func DefaultConfig() Bootstrap {
 				ConnMaxLifetime: Duration(6 * time.Hour),
 				SlowThreshold:   Duration(200 * time.Millisecond),
 			},
+			Redis: DataRedis{
+				Addr:   "127.0.0.1:6379",
+				Prefix: "wvp:",
+			},
 		},
 		Sip: SIP{
-			Port:     15060,
-			ID:       "3402000000200000001",
-			Domain:   "3402000000",
-			Password: "",
+			Port:            15060,
+			ID:              "3402000000200000001",
+			Domain:          "3402000000",
+			Password:        "",
+			AdmissionPolicy: "open",
+			TLS: SIPTLS{
+				Port: 15061,
+			},
+			Guard: SIPGuard{
+				Rate:        0, // 默认不限速，避免大型 NVR 上报目录或级联平台共用 IP 时丢包
+				Burst:       50,
+				MaxFailures: 5,
+				FailWindow:  Duration(10 * time.Minute),
+				BanDuration: Duration(30 * time.Minute),
+			},
+			Capture: SIPCapture{
+				Enabled: false,
+				Size:    200,
+			},
+			Session: SIPSession{
+				Store: "db",
+			},
+			CatalogInterval: Duration(6 * time.Hour),
+			Auth: SIPAuth{
+				Algorithm:    "MD5",
+				NonceExpires: Duration(5 * time.Minute),
+			},
 		},
 		Media: Media{
 			IP:           "127.0.0.1",
@@ -43,6 +93,9 @@ func DefaultConfig() Bootstrap {
 			SDPIP:        "127.0.0.1",
 			RTPPortRange: "20000-20500",
 		},
+		Cluster: Cluster{
+			LeaseTTL: Duration(15 * time.Second),
+		},
 		Log: Log{
 			Dir:          "./logs",
 			Level:        "info",
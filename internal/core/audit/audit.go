@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// 业务层显式记录的操作
+const (
+	ActionPlay              = "play"
+	ActionConfigEdit        = "config.edit"
+	ActionConfigDelete      = "config.delete"
+	ActionDeviceEdit        = "device.edit"
+	ActionDeviceDelete      = "device.delete"
+	ActionDeviceApprove     = "device.approve"
+	ActionDeviceReject      = "device.reject"
+	ActionChannelDelete     = "channel.delete"
+	ActionStreamPushDelete  = "stream_push.delete"
+	ActionStreamProxyDelete = "stream_proxy.delete"
+	ActionMediaServerDelete = "media_server.delete"
+	ActionUserEdit          = "user.edit"
+	ActionUserDelete        = "user.delete"
+	ActionRoleEdit          = "role.edit"
+	ActionRoleDelete        = "role.delete"
+	ActionGrantDelete       = "grant.delete"
+	ActionAPIKeyDelete      = "api_key.delete"
+	ActionSIPUnban          = "sip.unban"
+	ActionGroupMove         = "group.move"
+	ActionGroupDelete       = "group.delete"
+)
+
+type ctxKey struct{}
+
+// Entry 一次请求的审计信息，由中间件创建，业务层通过 Record 补充操作内容
+type Entry struct {
+	mu       sync.Mutex
+	action   string
+	targetID string
+	params   string
+	result   string // 业务层记录的操作结果，为空时按响应状态码判断
+	reason   string // 失败原因
+}
+
+// NewContext 将审计信息放入上下文
+func NewContext(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, ctxKey{}, e)
+}
+
+// Record 记录本次请求的操作，同一请求仅保留首次记录
+// 上下文中不存在审计信息时(如内部调用)，忽略
+func Record(ctx context.Context, action, targetID string, params any) {
+	record(ctx, action, targetID, params, "", "")
+}
+
+// RecordResult 在操作完成后记录操作及其结果，err 为空表示成功
+func RecordResult(ctx context.Context, action, targetID string, params any, err error) {
+	if err != nil {
+		record(ctx, action, targetID, params, ResultFailure, err.Error())
+		return
+	}
+	record(ctx, action, targetID, params, ResultSuccess, "")
+}
+
+func record(ctx context.Context, action, targetID string, params any, result, reason string) {
+	e, ok := ctx.Value(ctxKey{}).(*Entry)
+	if !ok || e == nil {
+		return
+	}
+	var s string
+	if params != nil {
+		b, _ := json.Marshal(params)
+		s = string(b)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.action != "" {
+		return
+	}
+	e.action, e.targetID, e.params = action, targetID, s
+	e.result, e.reason = result, reason
+}
+
+// Get 获取已记录的操作
+func (e *Entry) Get() (action, targetID, params string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.action, e.targetID, e.params
+}
+
+// Result 获取业务层记录的操作结果
+func (e *Entry) Result() (result, reason string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.result, e.reason
+}
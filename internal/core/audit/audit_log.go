@@ -0,0 +1,69 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+)
+
+// AuditLogStorer Instantiation interface
+type AuditLogStorer interface {
+	Find(context.Context, *[]*AuditLog, orm.Pager, ...orm.QueryOption) (int64, error)
+	Add(context.Context, *AuditLog) error
+	Del(context.Context, *AuditLog, ...orm.QueryOption) error
+}
+
+// FindAuditLog Paginated search
+func (c Core) FindAuditLog(ctx context.Context, in *FindAuditLogInput) ([]*AuditLog, int64, error) {
+	items := make([]*AuditLog, 0)
+
+	query := orm.NewQuery(7)
+	query.OrderBy("id DESC")
+	if in.UserID != "" {
+		query.Where("user_id=?", in.UserID)
+	}
+	if in.APIKeyID != "" {
+		query.Where("api_key_id=?", in.APIKeyID)
+	}
+	if in.Action != "" {
+		query.Where("action=?", in.Action)
+	}
+	if in.TargetID != "" {
+		query.Where("target_id=?", in.TargetID)
+	}
+	if in.Result != "" {
+		query.Where("result=?", in.Result)
+	}
+	if in.StartAt > 0 {
+		query.Where("created_at>=?", time.Unix(in.StartAt, 0))
+	}
+	if in.EndAt > 0 {
+		query.Where("created_at<?", time.Unix(in.EndAt, 0))
+	}
+
+	total, err := c.store.AuditLog().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// AddAuditLog Insert into database
+func (c Core) AddAuditLog(ctx context.Context, in *AuditLog) error {
+	in.CreatedAt = orm.Now()
+	if err := c.store.AuditLog().Add(ctx, in); err != nil {
+		return web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return nil
+}
+
+// DelExpired 删除指定时间之前的审计日志
+func (c Core) DelExpired(ctx context.Context, before time.Time) error {
+	if err := c.store.AuditLog().Del(ctx, new(AuditLog), orm.Where("created_at<?", before)); err != nil {
+		return web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package audit
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// 审计结果
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// AuditLog domain model
+type AuditLog struct {
+	ID         int64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt  orm.Time `gorm:"column:created_at;index;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:操作时间" json:"created_at"` // 操作时间
+	UserID     string   `gorm:"column:user_id;index;notNull;default:'';comment:操作人 id" json:"user_id"`                                   // 操作人 id
+	Username   string   `gorm:"column:username;notNull;default:'';comment:操作人" json:"username"`                                          // 操作人
+	APIKeyID   string   `gorm:"column:api_key_id;index;notNull;default:'';comment:API Key id" json:"api_key_id"`                         // 使用 API Key 访问时的密钥 id
+	APIKeyName string   `gorm:"column:api_key_name;notNull;default:'';comment:API Key 名称" json:"api_key_name"`                           // 使用 API Key 访问时的密钥名称
+	Action     string   `gorm:"column:action;index;notNull;default:'';comment:操作" json:"action"`                                         // 操作
+	TargetID   string   `gorm:"column:target_id;index;notNull;default:'';comment:操作对象 id" json:"target_id"`                              // 操作对象 id
+	Params     string   `gorm:"column:params;notNull;default:'';comment:操作参数" json:"params"`                                             // 操作参数，json 格式
+	Method     string   `gorm:"column:method;notNull;default:'';comment:请求方法" json:"method"`                                             // 请求方法
+	Path       string   `gorm:"column:path;notNull;default:'';comment:请求路径" json:"path"`                                                 // 请求路径
+	IP         string   `gorm:"column:ip;notNull;default:'';comment:客户端 IP" json:"ip"`                                                   // 客户端 IP
+	Result     string   `gorm:"column:result;notNull;default:'';comment:操作结果" json:"result"`                                             // 操作结果 success/failure
+	Reason     string   `gorm:"column:reason;notNull;default:'';comment:失败原因" json:"reason"`                                             // 失败原因
+	Code       int      `gorm:"column:code;notNull;default:0;comment:响应状态码" json:"code"`                                                 // 响应状态码
+}
+
+// TableName database table name
+func (*AuditLog) TableName() string {
+	return "audit_logs"
+}
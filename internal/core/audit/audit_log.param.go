@@ -0,0 +1,15 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package audit
+
+import "github.com/ixugo/goweb/pkg/web"
+
+type FindAuditLogInput struct {
+	web.PagerFilter
+	UserID   string `form:"user_id"`    // 操作人 id
+	APIKeyID string `form:"api_key_id"` // API Key id
+	Action   string `form:"action"`     // 操作
+	TargetID string `form:"target_id"`  // 操作对象 id
+	Result   string `form:"result"`     // 操作结果 success/failure
+	StartAt  int64  `form:"start_at"`   // 开始时间，unix 秒
+	EndAt    int64  `form:"end_at"`     // 结束时间，unix 秒
+}
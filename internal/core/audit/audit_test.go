@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRecordResult(t *testing.T) {
+	var e Entry
+	ctx := NewContext(context.Background(), &e)
+	RecordResult(ctx, ActionPlay, "ch1", nil, errors.New("device offline"))
+	// 同一请求仅保留首次记录
+	RecordResult(ctx, ActionPlay, "ch2", nil, nil)
+
+	if action, target, _ := e.Get(); action != ActionPlay || target != "ch1" {
+		t.Fatalf("unexpected record %s %s", action, target)
+	}
+	if result, reason := e.Result(); result != ResultFailure || reason != "device offline" {
+		t.Fatalf("unexpected result %s %s", result, reason)
+	}
+
+	var e2 Entry
+	Record(NewContext(context.Background(), &e2), ActionDeviceEdit, "gb1", nil)
+	if result, _ := e2.Result(); result != "" {
+		t.Fatalf("Record should leave result to the response status, got %s", result)
+	}
+}
@@ -0,0 +1,17 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package audit
+
+// Storer data persistence
+type Storer interface {
+	AuditLog() AuditLogStorer
+}
+
+// Core business domain
+type Core struct {
+	store Storer
+}
+
+// NewCore create business domain
+func NewCore(store Storer) Core {
+	return Core{store: store}
+}
@@ -0,0 +1,35 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package auditdb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/audit"
+)
+
+var _ audit.AuditLogStorer = AuditLog{}
+
+// AuditLog Related business namespaces
+type AuditLog DB
+
+// NewAuditLog instance object
+func NewAuditLog(db *gorm.DB) AuditLog {
+	return AuditLog{db: db}
+}
+
+// Find implements audit.AuditLogStorer.
+func (d AuditLog) Find(ctx context.Context, bs *[]*audit.AuditLog, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Add implements audit.AuditLogStorer.
+func (d AuditLog) Add(ctx context.Context, model *audit.AuditLog) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Del implements audit.AuditLogStorer.
+func (d AuditLog) Del(ctx context.Context, model *audit.AuditLog, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
@@ -0,0 +1,31 @@
+package auditdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"wvp/internal/core/audit"
+)
+
+func TestAuditLogAdd(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	auditDB := NewAuditLog(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	log := audit.AuditLog{UserID: "us1", Action: audit.ActionPlay, TargetID: "ch1", Result: audit.ResultSuccess}
+	if err := auditDB.Add(context.Background(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.ID != 1 {
+		t.Fatalf("expect id[1], got[%d]", log.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
@@ -0,0 +1,37 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package auditdb
+
+import (
+	"gorm.io/gorm"
+	"wvp/internal/core/audit"
+)
+
+var _ audit.Storer = DB{}
+
+// DB Related business namespaces
+type DB struct {
+	db *gorm.DB
+}
+
+// NewDB instance object
+func NewDB(db *gorm.DB) DB {
+	return DB{db: db}
+}
+
+// AuditLog Get business instance
+func (d DB) AuditLog() audit.AuditLogStorer {
+	return AuditLog(d)
+}
+
+// AutoMigrate sync database
+func (d DB) AutoMigrate(ok bool) DB {
+	if !ok {
+		return d
+	}
+	if err := d.db.AutoMigrate(
+		new(audit.AuditLog),
+	); err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -5,4 +5,10 @@ const (
 	IDPrefixGBChannel = "ch" // 国标通道 id 前缀
 	IDPrefixRTMP      = "mp" // rtmp ID 前缀，取 rtmp 后缀的 mp，不好记但是清晰
 	IDPrefixRTSP      = "sp" // rtsp ID 前缀，取 rtsp 后缀的 sp，不好记但是清晰
+	IDPrefixUser      = "us" // 用户 ID 前缀
+	IDPrefixRole      = "ro" // 角色 ID 前缀
+	IDPrefixAPIKey    = "ak" // API Key ID 前缀
+	IDPrefixWebhook   = "wh" // 事件订阅 ID 前缀
+	IDPrefixGBPending = "gp" // 待审批国标设备 ID 前缀
+	IDPrefixGroup     = "gr" // 分组 ID 前缀
 )
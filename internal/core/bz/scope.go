@@ -0,0 +1,44 @@
+package bz
+
+// 授权资源类型
+const (
+	ResourceDevice  = "device"  // 国标设备
+	ResourceChannel = "channel" // 国标通道
+	ResourcePush    = "push"    // rtmp 推流
+	ResourceProxy   = "proxy"   // rtsp 拉流代理
+)
+
+// Scope 数据权限范围
+// Limited 为 false 时不限制；为 true 时仅可访问 IDs 中的资源，
+// ParentIDs 为可访问的上级资源，如通道所属设备
+type Scope struct {
+	Limited   bool
+	IDs       []string
+	ParentIDs []string
+}
+
+// Contains 是否可访问该资源
+func (s Scope) Contains(id string) bool {
+	if !s.Limited {
+		return true
+	}
+	for _, v := range s.IDs {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsParent 是否可访问该上级资源
+func (s Scope) ContainsParent(id string) bool {
+	if !s.Limited {
+		return true
+	}
+	for _, v := range s.ParentIDs {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
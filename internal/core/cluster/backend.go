@@ -0,0 +1,172 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"wvp/pkg/redis"
+)
+
+// ErrNotFound 键不存在或已过期
+var ErrNotFound = errors.New("cluster: key not found")
+
+// Backend 集群共享存储，用于选主、实例注册与共享心跳
+type Backend interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	Renew(ctx context.Context, key, value string, ttl time.Duration) (bool, error) // 值相同时续期
+	Release(ctx context.Context, key, value string) error                          // 值相同时删除
+}
+
+var (
+	_ Backend = &MemoryBackend{}
+	_ Backend = RedisBackend{}
+)
+
+type memoryEntry struct {
+	value    string
+	expireAt time.Time
+}
+
+// MemoryBackend 进程内存储，用于单实例部署与测试
+type MemoryBackend struct {
+	mu    sync.Mutex
+	items map[string]memoryEntry
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{items: make(map[string]memoryEntry)}
+}
+
+// load 调用方需持有锁
+func (m *MemoryBackend) load(key string) (memoryEntry, bool) {
+	v, ok := m.items[key]
+	if ok && !v.expireAt.IsZero() && time.Now().After(v.expireAt) {
+		delete(m.items, key)
+		return v, false
+	}
+	return v, ok
+}
+
+func expireAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// Get implements Backend.
+func (m *MemoryBackend) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.load(key)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v.value, nil
+}
+
+// Set implements Backend.
+func (m *MemoryBackend) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = memoryEntry{value: value, expireAt: expireAt(ttl)}
+	return nil
+}
+
+// SetNX implements Backend.
+func (m *MemoryBackend) SetNX(_ context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.load(key); ok {
+		return false, nil
+	}
+	m.items[key] = memoryEntry{value: value, expireAt: expireAt(ttl)}
+	return true, nil
+}
+
+// Renew implements Backend.
+func (m *MemoryBackend) Renew(_ context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.load(key)
+	if !ok || v.value != value {
+		return false, nil
+	}
+	m.items[key] = memoryEntry{value: value, expireAt: expireAt(ttl)}
+	return true, nil
+}
+
+// Release implements Backend.
+func (m *MemoryBackend) Release(_ context.Context, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.load(key); ok && v.value == value {
+		delete(m.items, key)
+	}
+	return nil
+}
+
+const (
+	renewScript   = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) end return 0`
+	releaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`
+)
+
+// RedisBackend 多个实例共用的 redis 存储
+type RedisBackend struct {
+	cli    *redis.Client
+	prefix string
+}
+
+// NewRedisBackend prefix 为键前缀
+func NewRedisBackend(cli *redis.Client, prefix string) RedisBackend {
+	return RedisBackend{cli: cli, prefix: prefix + "cluster:"}
+}
+
+func ms(ttl time.Duration) string {
+	return strconv.FormatInt(max(1, ttl.Milliseconds()), 10)
+}
+
+// Get implements Backend.
+func (r RedisBackend) Get(ctx context.Context, key string) (string, error) {
+	v, err := redis.String(r.cli.Do(ctx, "GET", r.prefix+key))
+	if errors.Is(err, redis.ErrNil) {
+		return "", ErrNotFound
+	}
+	return v, err
+}
+
+// Set implements Backend.
+func (r RedisBackend) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	args := []string{"SET", r.prefix + key, value}
+	if ttl > 0 {
+		args = append(args, "PX", ms(ttl))
+	}
+	_, err := r.cli.Do(ctx, args...)
+	return err
+}
+
+// SetNX implements Backend.
+func (r RedisBackend) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	v, err := r.cli.Do(ctx, "SET", r.prefix+key, value, "NX", "PX", ms(ttl))
+	if err != nil {
+		return false, err
+	}
+	return v != nil, nil
+}
+
+// Renew implements Backend.
+func (r RedisBackend) Renew(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	n, err := redis.Int64(r.cli.Do(ctx, "EVAL", renewScript, "1", r.prefix+key, value, ms(ttl)))
+	return n == 1, err
+}
+
+// Release implements Backend.
+func (r RedisBackend) Release(ctx context.Context, key, value string) error {
+	_, err := r.cli.Do(ctx, "EVAL", releaseScript, "1", r.prefix+key, value)
+	return err
+}
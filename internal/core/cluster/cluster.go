@@ -0,0 +1,201 @@
+// Package cluster 多实例部署，负责实例注册、选主与跨实例请求转发
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	leaderKey  = "leader"
+	nodePrefix = "node:"
+	// ForwardPrefix 实例间转发接口的路由前缀
+	ForwardPrefix = "/internal/cluster"
+)
+
+// Config 集群配置
+type Config struct {
+	Enabled   bool
+	NodeID    string        // 实例标识，集群内唯一
+	Advertise string        // 其它实例访问本实例的 http 地址，如 http://10.0.0.2:15123
+	Secret    string        // 实例间转发请求的密钥
+	LeaseTTL  time.Duration // 主节点租约与实例注册的有效期
+}
+
+// Cluster 未启用时本实例始终为主节点，所有设备均视为本地设备
+type Cluster struct {
+	cfg     Config
+	backend Backend
+	client  *http.Client
+
+	leader atomic.Bool
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建集群，需调用 Start 开始选主
+func New(cfg Config, backend Backend) *Cluster {
+	if cfg.LeaseTTL <= 0 {
+		cfg.LeaseTTL = 15 * time.Second
+	}
+	c := Cluster{
+		cfg:     cfg,
+		backend: backend,
+		client:  &http.Client{Timeout: 40 * time.Second},
+	}
+	if !cfg.Enabled {
+		c.leader.Store(true)
+	}
+	return &c
+}
+
+// Start 注册实例并参与选主，每 1/3 租约周期续期一次
+func (c *Cluster) Start() {
+	if !c.cfg.Enabled {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.tick(ctx)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.cfg.LeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.tick(ctx)
+			}
+		}
+	}()
+}
+
+// Close 退出集群，主节点主动释放租约以便其它实例尽快接管
+func (c *Cluster) Close() {
+	if !c.cfg.Enabled || c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if c.leader.Swap(false) {
+		if err := c.backend.Release(ctx, leaderKey, c.cfg.NodeID); err != nil {
+			slog.Error("释放主节点租约失败", "err", err)
+		}
+	}
+	if err := c.backend.Release(ctx, nodePrefix+c.cfg.NodeID, c.cfg.Advertise); err != nil {
+		slog.Error("注销实例失败", "err", err)
+	}
+}
+
+func (c *Cluster) tick(ctx context.Context) {
+	ttl := c.cfg.LeaseTTL
+	if err := c.backend.Set(ctx, nodePrefix+c.cfg.NodeID, c.cfg.Advertise, ttl); err != nil {
+		slog.Error("实例注册失败", "err", err, "node", c.cfg.NodeID)
+	}
+
+	var ok bool
+	var err error
+	if c.leader.Load() {
+		ok, err = c.backend.Renew(ctx, leaderKey, c.cfg.NodeID, ttl)
+	} else {
+		ok, err = c.backend.SetNX(ctx, leaderKey, c.cfg.NodeID, ttl)
+	}
+	if err != nil {
+		// 无法续期时主动放弃，避免出现两个主节点
+		slog.Error("选主失败", "err", err, "node", c.cfg.NodeID)
+		ok = false
+	}
+	if prev := c.leader.Swap(ok); prev != ok {
+		slog.Info("主节点状态变更", "node", c.cfg.NodeID, "leader", ok)
+	}
+}
+
+// Enabled 是否启用集群
+func (c *Cluster) Enabled() bool {
+	return c.cfg.Enabled
+}
+
+// NodeID 本实例标识
+func (c *Cluster) NodeID() string {
+	return c.cfg.NodeID
+}
+
+// Backend 集群共享存储
+func (c *Cluster) Backend() Backend {
+	return c.backend
+}
+
+// IsLeader 本实例是否为主节点，定时任务仅在主节点执行
+func (c *Cluster) IsLeader() bool {
+	return c.leader.Load()
+}
+
+// IsLocal 设备连接是否由本实例持有，node 为空表示未知，按本地处理
+func (c *Cluster) IsLocal(node string) bool {
+	return !c.cfg.Enabled || node == "" || node == c.cfg.NodeID
+}
+
+// VerifySecret 校验转发请求携带的密钥
+func (c *Cluster) VerifySecret(token string) bool {
+	token = strings.TrimPrefix(token, "Bearer ")
+	return c.cfg.Secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.cfg.Secret)) == 1
+}
+
+// Forward 将请求转发至持有设备连接的实例执行，out 不为空时解析响应内容
+func (c *Cluster) Forward(ctx context.Context, node, path string, in, out any) error {
+	addr, err := c.backend.Get(ctx, nodePrefix+node)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("cluster node %s offline", node)
+		}
+		return err
+	}
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(addr, "/")+ForwardPrefix+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.cfg.Secret)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if out == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	var e struct {
+		Msg string `json:"msg"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(b, &e) != nil || e.Msg == "" {
+		e.Msg = strings.TrimSpace(string(b))
+	}
+	return fmt.Errorf("cluster node %s: %s", node, e.Msg)
+}
@@ -0,0 +1,69 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClusterElection(t *testing.T) {
+	backend := NewMemoryBackend()
+	a := New(Config{Enabled: true, NodeID: "a", LeaseTTL: 300 * time.Millisecond}, backend)
+	b := New(Config{Enabled: true, NodeID: "b", LeaseTTL: 300 * time.Millisecond}, backend)
+	a.Start()
+	b.Start()
+	defer b.Close()
+
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("expect a is leader, got a=%v b=%v", a.IsLeader(), b.IsLeader())
+	}
+	time.Sleep(400 * time.Millisecond)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatal("leader should renew lease")
+	}
+
+	a.Close()
+	deadline := time.Now().Add(time.Second)
+	for !b.IsLeader() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if a.IsLeader() || !b.IsLeader() {
+		t.Fatalf("expect b takes over, got a=%v b=%v", a.IsLeader(), b.IsLeader())
+	}
+}
+
+func TestClusterDisabled(t *testing.T) {
+	c := New(Config{}, NewMemoryBackend())
+	if !c.IsLeader() || !c.IsLocal("other") {
+		t.Fatal("disabled cluster should be leader and treat every node as local")
+	}
+}
+
+func TestClusterForward(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != ForwardPrefix+"/sip/play" || r.Header.Get("Authorization") != "Bearer s" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_ = json.NewEncoder(w).Encode(map[string]string{"msg": "unauthorized"})
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"msg": "device offline"})
+	}))
+	defer srv.Close()
+
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+	_ = backend.Set(ctx, nodePrefix+"b", srv.URL, time.Minute)
+	a := New(Config{Enabled: true, NodeID: "a", Secret: "s"}, backend)
+
+	err := a.Forward(ctx, "b", "/sip/play", map[string]string{"device_id": "1"}, nil)
+	if err == nil || err.Error() != "cluster node b: device offline" {
+		t.Fatalf("unexpected err %v", err)
+	}
+	if err := a.Forward(ctx, "c", "/sip/play", nil, nil); err == nil {
+		t.Fatal("expect offline node error")
+	}
+}
@@ -4,10 +4,12 @@ package config
 import (
 	"context"
 	"log/slog"
+	"strconv"
 
 	"github.com/ixugo/goweb/pkg/orm"
 	"github.com/ixugo/goweb/pkg/web"
 	"github.com/jinzhu/copier"
+	"wvp/internal/core/audit"
 )
 
 // ConfigStorer Instantiation interface
@@ -57,6 +59,7 @@ func (c *Core) AddConfig(ctx context.Context, in *AddConfigInput) (*Config, erro
 
 // EditConfig Update object information
 func (c *Core) EditConfig(ctx context.Context, in *EditConfigInput, id int) (*Config, error) {
+	audit.Record(ctx, audit.ActionConfigEdit, strconv.Itoa(id), in)
 	var out Config
 	if err := c.store.Config().Edit(ctx, &out, func(b *Config) {
 		if err := copier.Copy(b, in); err != nil {
@@ -70,6 +73,7 @@ func (c *Core) EditConfig(ctx context.Context, in *EditConfigInput, id int) (*Co
 
 // DelConfig Delete object
 func (c *Core) DelConfig(ctx context.Context, id int) (*Config, error) {
+	audit.Record(ctx, audit.ActionConfigDelete, strconv.Itoa(id), nil)
 	var out Config
 	if err := c.store.Config().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
 		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
@@ -0,0 +1,86 @@
+package event
+
+// Device 设备上下线事件内容
+type Device struct {
+	ID        string `json:"id"`        // 设备 id
+	DeviceID  string `json:"device_id"` // 国标编码
+	Address   string `json:"address"`   // 设备地址
+	Transport string `json:"transport"` // 信令传输协议
+	Reason    string `json:"reason"`    // 离线原因
+}
+
+// Channel 通道状态事件内容
+type Channel struct {
+	ID        string `json:"id"`         // 通道 id
+	DID       string `json:"did"`        // 设备 id
+	DeviceID  string `json:"device_id"`  // 设备国标编码
+	ChannelID string `json:"channel_id"` // 通道国标编码
+	Name      string `json:"name"`       // 通道名称
+	IsOnline  bool   `json:"is_online"`  // 是否在线
+}
+
+// Stream 流注册/注销事件内容
+type Stream struct {
+	App           string `json:"app"`
+	Stream        string `json:"stream"`
+	Schema        string `json:"schema"`
+	MediaServerID string `json:"media_server_id"`
+	ResourceType  string `json:"resource_type"` // 流对应的资源类型 channel/push/proxy，未知时为空
+	ResourceID    string `json:"resource_id"`   // 资源 id
+	DID           string `json:"did"`           // 国标通道所属设备 id
+}
+
+// Proxy 拉流代理状态事件内容
+type Proxy struct {
+	ID      string `json:"id"`
+	App     string `json:"app"`
+	Stream  string `json:"stream"`
+	Pulling bool   `json:"pulling"` // 是否正在拉流
+}
+
+// Alarm 设备报警事件内容
+type Alarm struct {
+	DID         string `json:"did"`         // 设备 id
+	DeviceID    string `json:"device_id"`   // 设备国标编码
+	ChannelID   string `json:"channel_id"`  // 报警设备/通道国标编码
+	Priority    string `json:"priority"`    // 报警级别 1:一级警情 2:二级警情 3:三级警情 4:四级警情
+	Method      string `json:"method"`      // 报警方式 1:电话 2:设备 3:短信 4:GPS 5:视频 6:设备故障 7:其他
+	Type        string `json:"type"`        // 报警类型
+	Time        string `json:"time"`        // 报警时间
+	Description string `json:"description"` // 报警描述
+	Longitude   string `json:"longitude"`   // 经度
+	Latitude    string `json:"latitude"`    // 纬度
+}
+
+// Catalog 设备目录更新事件内容
+type Catalog struct {
+	DID      string `json:"did"`       // 设备 id
+	DeviceID string `json:"device_id"` // 设备国标编码
+	Total    int    `json:"total"`     // 通道数量
+	Added    int    `json:"added"`     // 新增通道数
+	Updated  int    `json:"updated"`   // 属性变化的通道数
+	Removed  int    `json:"removed"`   // 移除的通道数
+}
+
+// MediaServer 媒体服务器状态事件内容
+type MediaServer struct {
+	ID       string `json:"id"`
+	IsOnline bool   `json:"is_online"`
+}
+
+// DeviceOf 获取事件关联的设备，返回设备 id 与国标编码，与设备无关的事件返回空串
+func DeviceOf(e Event) (did, deviceID string) {
+	switch v := e.Data.(type) {
+	case Device:
+		return v.ID, v.DeviceID
+	case Channel:
+		return v.DID, v.DeviceID
+	case Catalog:
+		return v.DID, v.DeviceID
+	case Alarm:
+		return v.DID, v.DeviceID
+	case Stream:
+		return v.DID, ""
+	}
+	return "", ""
+}
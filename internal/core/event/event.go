@@ -0,0 +1,102 @@
+// Package event 进程内事件总线，设备、通道、流等状态变化通过总线分发给订阅者
+package event
+
+import (
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 事件类型
+const (
+	TypeDeviceOnline    = "device.online"       // 设备上线
+	TypeDeviceOffline   = "device.offline"      // 设备离线
+	TypeDevicePending   = "device.pending"      // 未知设备注册，等待审批
+	TypeChannelStatus   = "channel.status"      // 通道状态变化
+	TypeCatalogUpdate   = "catalog.update"      // 设备目录更新
+	TypeStreamPublish   = "stream.publish"      // 流注册
+	TypeStreamUnpublish = "stream.unpublish"    // 流注销
+	TypeProxyState      = "proxy.state"         // 拉流代理状态变化
+	TypeAlarm           = "alarm"               // 设备报警
+	TypeMediaServer     = "media_server.status" // 媒体服务器状态变化
+)
+
+// Types 全部事件类型
+var Types = []string{
+	TypeDeviceOnline, TypeDeviceOffline, TypeDevicePending, TypeChannelStatus, TypeCatalogUpdate,
+	TypeStreamPublish, TypeStreamUnpublish, TypeProxyState, TypeAlarm, TypeMediaServer,
+}
+
+// Event 事件
+type Event struct {
+	ID   string    `json:"id"`   // 事件 id
+	Type string    `json:"type"` // 事件类型
+	Time time.Time `json:"time"` // 发生时间
+	Data any       `json:"data"` // 事件内容
+}
+
+var seq atomic.Uint64
+
+// NewEvent 创建事件
+func NewEvent(typ string, data any) Event {
+	now := time.Now()
+	return Event{
+		ID:   strconv.FormatInt(now.UnixMilli(), 36) + strconv.FormatUint(seq.Add(1), 36),
+		Type: typ,
+		Time: now,
+		Data: data,
+	}
+}
+
+// Handler 事件处理函数，不应阻塞
+type Handler func(Event)
+
+// Bus 事件总线
+type Bus struct {
+	mu       sync.RWMutex
+	handlers map[uint64]Handler
+	nextID   uint64
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[uint64]Handler)}
+}
+
+// Publish 发布事件，总线为 nil 时忽略
+func (b *Bus) Publish(typ string, data any) {
+	if b == nil {
+		return
+	}
+	e := NewEvent(typ, data)
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, h := range b.handlers {
+		b.call(h, e)
+	}
+}
+
+func (b *Bus) call(h Handler, e Event) {
+	defer func() {
+		if err := recover(); err != nil {
+			slog.Error("事件处理异常", "type", e.Type, "err", err)
+		}
+	}()
+	h(e)
+}
+
+// Subscribe 订阅全部事件，返回取消订阅函数
+func (b *Bus) Subscribe(h Handler) (cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	b.handlers[id] = h
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}
+}
@@ -0,0 +1,28 @@
+package event
+
+import "testing"
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var got []Event
+	cancel := bus.Subscribe(func(e Event) { got = append(got, e) })
+	bus.Subscribe(func(Event) { panic("ignore") })
+
+	bus.Publish(TypeDeviceOnline, Device{DeviceID: "34020000001320000001"})
+	bus.Publish(TypeAlarm, Alarm{Priority: "1"})
+	cancel()
+	bus.Publish(TypeDeviceOffline, nil)
+
+	if len(got) != 2 {
+		t.Fatalf("expect 2 events, got %d", len(got))
+	}
+	if got[0].ID == got[1].ID {
+		t.Fatal("event id should be unique")
+	}
+	if got[1].Type != TypeAlarm {
+		t.Fatalf("expect %s, got %s", TypeAlarm, got[1].Type)
+	}
+
+	var nilBus *Bus
+	nilBus.Publish(TypeAlarm, nil)
+}
@@ -0,0 +1,244 @@
+package gb28181
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+)
+
+// 目录节点类型，按国标编码第 11~13 位区分 GB/T28181 附录 D
+const (
+	CatalogCivilCode     = "civil_code"     // 行政区划，编码长度 2/4/6/8 位
+	CatalogBusinessGroup = "business_group" // 业务分组 215
+	CatalogVirtualOrg    = "virtual_org"    // 虚拟组织 216
+	CatalogNVR           = "nvr"            // DVR/视频服务器/编码器/NVR 111~113,118
+	CatalogCamera        = "camera"         // 摄像机/网络摄像机 131,132
+	CatalogOther         = "other"
+)
+
+// CatalogType 根据国标编码判断目录节点类型
+func CatalogType(id string) string {
+	if n := len(id); n <= 8 && n > 0 && n%2 == 0 {
+		return CatalogCivilCode
+	}
+	if len(id) < 13 {
+		return CatalogOther
+	}
+	switch id[10:13] {
+	case "215":
+		return CatalogBusinessGroup
+	case "216":
+		return CatalogVirtualOrg
+	case "111", "112", "113", "118":
+		return CatalogNVR
+	case "131", "132":
+		return CatalogCamera
+	}
+	return CatalogOther
+}
+
+// SetCatalog 使用设备目录上报的属性更新通道，保留用户修改的通道名称
+func (c *Channel) SetCatalog(src *Channel) {
+	c.IsOnline = src.IsOnline
+	c.PTZType = src.PTZType
+	c.Ext.Manufacturer = src.Ext.Manufacturer
+	c.Ext.Model = src.Ext.Model
+	c.ParentID = src.ParentID
+	c.CivilCode = src.CivilCode
+	c.BusinessGroupID = src.BusinessGroupID
+	c.Owner = src.Owner
+	c.Address = src.Address
+	c.Block = src.Block
+	c.Parental = src.Parental
+	c.SafetyWay = src.SafetyWay
+	c.RegisterWay = src.RegisterWay
+	c.Secrecy = src.Secrecy
+	c.CertNum = src.CertNum
+	c.Certifiable = src.Certifiable
+	c.ErrCode = src.ErrCode
+	c.EndTime = src.EndTime
+	c.IPAddress = src.IPAddress
+	c.Port = src.Port
+	c.Longitude = src.Longitude
+	c.Latitude = src.Latitude
+	c.Info = src.Info
+}
+
+// catalogColumns SetCatalog 更新的字段，批量写入已存在的通道时仅更新这些字段
+var catalogColumns = []string{
+	"did", "is_online", "ptztype", "ext", "parent_id", "civil_code", "business_group_id", "owner", "address",
+	"block", "parental", "safety_way", "register_way", "secrecy", "cert_num", "certifiable", "err_code",
+	"end_time", "ip_address", "port", "longitude", "latitude", "info", "removed_at",
+}
+
+// CatalogDiff 目录核对结果，通道以国标编码表示
+type CatalogDiff struct {
+	DeviceID  string   `json:"device_id"`
+	Total     int      `json:"total"`     // 本次上报的通道数
+	Added     []string `json:"added"`     // 新增或重新上报的通道
+	Updated   []string `json:"updated"`   // 属性变化的通道
+	Removed   []string `json:"removed"`   // 设备已移除的通道
+	Unchanged int      `json:"unchanged"` // 未变化的通道数
+	Partial   bool     `json:"partial"`   // 目录未接收完整，不标记移除
+}
+
+// CatalogNode 目录树节点
+type CatalogNode struct {
+	ID        string         `json:"id"`         // 通道 ID，设备未上报的行政区划节点为空
+	ChannelID string         `json:"channel_id"` // 国标编码
+	Name      string         `json:"name"`
+	Type      string         `json:"type"` // 节点类型
+	IsOnline  bool           `json:"is_online"`
+	Children  []*CatalogNode `json:"children"`
+}
+
+// CatalogTree 设备目录树
+type CatalogTree struct {
+	DID      string         `json:"did"`
+	DeviceID string         `json:"device_id"`
+	Name     string         `json:"name"`
+	Items    []*CatalogNode `json:"items"`
+}
+
+// CatalogTree 按 ParentID、业务分组与行政区划组织设备目录
+func (c Core) CatalogTree(ctx context.Context, id string) (*CatalogTree, error) {
+	var dev Device
+	if err := c.store.Device().Get(ctx, &dev, orm.Where("id=?", id)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrNotFound.Withf(`Get err[%s]`, err.Error())
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	channels := make([]*Channel, 0, 8)
+	if _, err := c.store.Channel().Find(ctx, &channels, web.NewPagerFilterMaxSize(), orm.Where("device_id=? AND removed_at IS NULL", dev.DeviceID)); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return &CatalogTree{
+		DID:      dev.ID,
+		DeviceID: dev.DeviceID,
+		Name:     dev.Name,
+		Items:    BuildCatalogTree(dev.DeviceID, channels),
+	}, nil
+}
+
+// BuildCatalogTree 构建目录树
+// 父节点依次取 ParentID、虚拟组织的业务分组、行政区划，均不存在时挂在根节点
+// 通道引用但设备未上报的行政区划自动补全，行政区划按编码前缀逐级归属
+func BuildCatalogTree(deviceID string, channels []*Channel) []*CatalogNode {
+	nodes := make(map[string]*CatalogNode, len(channels))
+	for _, ch := range channels {
+		nodes[ch.ChannelID] = &CatalogNode{
+			ID:        ch.ID,
+			ChannelID: ch.ChannelID,
+			Name:      ch.Name,
+			Type:      CatalogType(ch.ChannelID),
+			IsOnline:  ch.IsOnline,
+		}
+	}
+	for _, ch := range channels {
+		if code := ch.CivilCode; CatalogType(code) == CatalogCivilCode {
+			for ; code != ""; code = code[:len(code)-2] {
+				if _, ok := nodes[code]; !ok {
+					nodes[code] = &CatalogNode{ChannelID: code, Name: code, Type: CatalogCivilCode}
+				}
+			}
+		}
+	}
+
+	parents := make(map[string]string, len(nodes))
+	for _, ch := range channels {
+		parents[ch.ChannelID] = catalogParent(deviceID, ch, nodes)
+	}
+	for id, node := range nodes {
+		if _, ok := parents[id]; !ok && node.Type == CatalogCivilCode {
+			parents[id] = civilParent(id, nodes)
+		}
+	}
+
+	roots := make([]*CatalogNode, 0, 8)
+	for id, node := range nodes {
+		parent := parents[id]
+		if parent == "" || isCatalogCycle(id, parent, parents) {
+			roots = append(roots, node)
+			continue
+		}
+		p := nodes[parent]
+		p.Children = append(p.Children, node)
+	}
+	sortCatalogNodes(roots)
+	return roots
+}
+
+func catalogParent(deviceID string, ch *Channel, nodes map[string]*CatalogNode) string {
+	// 部分设备使用 "/" 分隔的路径表示多级父节点
+	parent := ch.ParentID
+	if i := strings.LastIndex(parent, "/"); i >= 0 {
+		parent = parent[i+1:]
+	}
+	if parent != "" && parent != deviceID && parent != ch.ChannelID {
+		if _, ok := nodes[parent]; ok {
+			return parent
+		}
+	}
+	typ := CatalogType(ch.ChannelID)
+	if typ == CatalogVirtualOrg && ch.BusinessGroupID != "" {
+		if _, ok := nodes[ch.BusinessGroupID]; ok {
+			return ch.BusinessGroupID
+		}
+	}
+	if typ == CatalogCivilCode {
+		return civilParent(ch.ChannelID, nodes)
+	}
+	if ch.CivilCode != "" && ch.CivilCode != ch.ChannelID {
+		if _, ok := nodes[ch.CivilCode]; ok {
+			return ch.CivilCode
+		}
+	}
+	return ""
+}
+
+// civilParent 编码前缀最长的上级行政区划
+func civilParent(code string, nodes map[string]*CatalogNode) string {
+	for p := code[:len(code)-2]; p != ""; p = p[:len(p)-2] {
+		if _, ok := nodes[p]; ok {
+			return p
+		}
+	}
+	return ""
+}
+
+// isCatalogCycle 设备上报的父子关系成环时，沿父节点向上会回到自身
+func isCatalogCycle(id, parent string, parents map[string]string) bool {
+	for i := 0; parent != "" && i <= len(parents); i++ {
+		if parent == id {
+			return true
+		}
+		parent = parents[parent]
+	}
+	return parent != ""
+}
+
+var catalogTypeOrder = map[string]int{
+	CatalogCivilCode:     0,
+	CatalogBusinessGroup: 1,
+	CatalogVirtualOrg:    2,
+	CatalogNVR:           3,
+	CatalogCamera:        4,
+	CatalogOther:         5,
+}
+
+func sortCatalogNodes(items []*CatalogNode) {
+	slices.SortFunc(items, func(a, b *CatalogNode) int {
+		if n := cmp.Compare(catalogTypeOrder[a.Type], catalogTypeOrder[b.Type]); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.ChannelID, b.ChannelID)
+	})
+	for _, v := range items {
+		sortCatalogNodes(v.Children)
+	}
+}
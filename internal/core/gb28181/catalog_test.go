@@ -0,0 +1,65 @@
+package gb28181
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCatalogType(t *testing.T) {
+	cases := map[string]string{
+		"34":                   CatalogCivilCode,
+		"34020000":             CatalogCivilCode,
+		"34020000002150000001": CatalogBusinessGroup,
+		"34020000002160000001": CatalogVirtualOrg,
+		"34020000001180000001": CatalogNVR,
+		"34020000001320000001": CatalogCamera,
+		"34020000002000000001": CatalogOther,
+		"340":                  CatalogOther,
+	}
+	for id, expect := range cases {
+		if got := CatalogType(id); got != expect {
+			t.Errorf("CatalogType(%s) = %s, expect %s", id, got, expect)
+		}
+	}
+}
+
+// dump 以缩进格式输出目录树，便于比较
+func dump(nodes []*CatalogNode, depth int, b *strings.Builder) {
+	for _, n := range nodes {
+		b.WriteString(strings.Repeat("  ", depth) + n.ChannelID + "\n")
+		dump(n.Children, depth+1, b)
+	}
+}
+
+func TestBuildCatalogTree(t *testing.T) {
+	const dev = "34020000001180000001"
+	channels := []*Channel{
+		{ChannelID: "3402", Name: "市"},
+		{ChannelID: "34020000002150000001", Name: "业务分组"},
+		{ChannelID: "34020000002160000001", Name: "虚拟组织", BusinessGroupID: "34020000002150000001"},
+		{ChannelID: "34020000002160000002", Name: "下级组织", ParentID: "34020000002150000001/34020000002160000001"},
+		{ChannelID: "34020000001320000001", Name: "组织内摄像机", ParentID: "34020000002160000002"},
+		{ChannelID: "34020000001320000002", Name: "区划内摄像机", ParentID: dev, CivilCode: "340201"},
+		{ChannelID: "34020000001320000003", Name: "直属摄像机", ParentID: dev},
+		// 父子关系成环
+		{ChannelID: "34020000001310000001", ParentID: "34020000001310000002"},
+		{ChannelID: "34020000001310000002", ParentID: "34020000001310000001"},
+	}
+	var b strings.Builder
+	dump(BuildCatalogTree(dev, channels), 0, &b)
+	expect := `34
+  3402
+    340201
+      34020000001320000002
+34020000002150000001
+  34020000002160000001
+    34020000002160000002
+      34020000001320000001
+34020000001310000001
+34020000001310000002
+34020000001320000003
+`
+	if b.String() != expect {
+		t.Fatalf("unexpected tree\n%s", b.String())
+	}
+}
@@ -10,7 +10,9 @@ import (
 	"github.com/ixugo/goweb/pkg/orm"
 	"github.com/ixugo/goweb/pkg/web"
 	"github.com/jinzhu/copier"
+	"wvp/internal/core/audit"
 	"wvp/internal/core/bz"
+	"wvp/internal/core/region"
 )
 
 // ChannelStorer Instantiation interface
@@ -22,6 +24,8 @@ type ChannelStorer interface {
 	Del(context.Context, *Channel, ...orm.QueryOption) error
 
 	BatchEdit(context.Context, string, any, ...orm.QueryOption) error // 批量更新一个字段
+	// Upsert 按 (device_id, channel_id) 批量写入，已存在的通道仅更新 columns 字段
+	Upsert(ctx context.Context, channels []*Channel, columns ...string) error
 }
 
 // FindChannel Paginated search
@@ -44,18 +48,51 @@ func (c *Core) FindChannel(ctx context.Context, in *FindChannelInput) ([]*Channe
 		query.Where("did=?", in.DID)
 	}
 
+	if in.Removed == "true" {
+		query.Where("removed_at IS NOT NULL")
+	} else {
+		query.Where("removed_at IS NULL")
+	}
+
 	if in.IsOnline == "true" || in.IsOnline == "false" {
 		isOnline, _ := strconv.ParseBool(in.IsOnline)
 		query.Where("is_online = ?", isOnline)
 	}
+	if in.Region != "" {
+		if region.Level(in.Region) == 0 {
+			return nil, 0, web.ErrBadRequest.Msg("行政区划编码应为 2/4/6/8 位数字")
+		}
+		query.Where("channel_id LIKE ?", in.Region+"%")
+	}
+	if in.GroupID != "" {
+		query.Where("id IN (SELECT resource_id FROM group_members WHERE resource_type=? AND group_id IN ?)", bz.ResourceChannel, in.GroupIDs)
+	}
+	if in.Scope.Limited {
+		query.Where("id IN ? OR device_id IN (SELECT device_id FROM devices WHERE id IN ?)", in.Scope.IDs, in.Scope.ParentIDs)
+	}
 
 	total, err := c.store.Channel().Find(ctx, &items, in, query.Encode()...)
 	if err != nil {
 		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
 	}
+	for _, item := range items {
+		item.RegionCode, item.RegionName = c.regionOf(item.ChannelID)
+	}
 	return items, total, nil
 }
 
+// FindChannelByIDs 按 id 批量查询通道，不包含已移除的通道
+func (c *Core) FindChannelByIDs(ctx context.Context, ids []string) ([]*Channel, error) {
+	items := make([]*Channel, 0, len(ids))
+	if len(ids) == 0 {
+		return items, nil
+	}
+	if _, err := c.store.Channel().Find(ctx, &items, web.NewPagerFilterMaxSize(), orm.Where("id IN ? AND removed_at IS NULL", ids)); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, nil
+}
+
 // GetChannel Query a single object
 func (c *Core) GetChannel(ctx context.Context, id string) (*Channel, error) {
 	var out Channel
@@ -95,6 +132,7 @@ func (c *Core) EditChannel(ctx context.Context, in *EditChannelInput, id string)
 
 // DelChannel Delete object
 func (c *Core) DelChannel(ctx context.Context, id string) (*Channel, error) {
+	audit.Record(ctx, audit.ActionChannelDelete, id, nil)
 	var out Channel
 	if err := c.store.Channel().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
 		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
@@ -1,16 +1,43 @@
 // Code generated by gowebx, DO AVOID EDIT.
 package gb28181
 
+import "github.com/ixugo/goweb/pkg/orm"
+
 // Channel domain model
 type Channel struct {
 	ID        string    `gorm:"primaryKey" json:"id"`
 	DID       string    `gorm:"column:did;index;notNull;default:'';comment:父级 ID" json:"did"`
-	DeviceID  string    `gorm:"column:device_id;index;notNull;default:'';comment:国标编码" json:"device_id"`   // 国标编码
-	ChannelID string    `gorm:"column:channel_id;index;notNull;default:'';comment:国标编码" json:"channel_id"` // 国标编码
-	Name      string    `gorm:"column:name;notNull;default:'';comment:通道名称" json:"name"`                   // 通道名称
-	PTZType   int       `gorm:"column:ptztype;notNull;default:0;comment:云台类型" json:"ptztype"`              // 云台类型
-	IsOnline  bool      `gorm:"column:is_online;notNull;default:FALSE;comment:是否在线" json:"is_online"`      // 是否在线
+	DeviceID  string    `gorm:"column:device_id;index;uniqueIndex:idx_channels_device_channel;notNull;default:'';comment:国标编码" json:"device_id"`   // 国标编码
+	ChannelID string    `gorm:"column:channel_id;index;uniqueIndex:idx_channels_device_channel;notNull;default:'';comment:国标编码" json:"channel_id"` // 国标编码
+	Name      string    `gorm:"column:name;notNull;default:'';comment:通道名称" json:"name"`                                                           // 通道名称
+	PTZType   int       `gorm:"column:ptztype;notNull;default:0;comment:云台类型" json:"ptztype"`                                                      // 云台类型
+	IsOnline  bool      `gorm:"column:is_online;notNull;default:FALSE;comment:是否在线" json:"is_online"`                                              // 是否在线
 	Ext       DeviceExt `gorm:"column:ext;notNull;type:JSON" json:"ext" `
+
+	// 设备目录上报的属性 GB/T28181 A.2.6.4
+	ParentID        string      `gorm:"column:parent_id;index;notNull;default:'';comment:父节点 ID" json:"parent_id"`               // 父节点 ID
+	CivilCode       string      `gorm:"column:civil_code;index;notNull;default:'';comment:行政区域" json:"civil_code"`               // 行政区域
+	BusinessGroupID string      `gorm:"column:business_group_id;notNull;default:'';comment:虚拟组织所属业务分组" json:"business_group_id"` // 虚拟组织所属业务分组
+	Owner           string      `gorm:"column:owner;notNull;default:'';comment:设备归属" json:"owner"`                               // 设备归属
+	Address         string      `gorm:"column:address;notNull;default:'';comment:安装地址" json:"address"`                           // 安装地址
+	Block           string      `gorm:"column:block;notNull;default:'';comment:警区" json:"block"`                                 // 警区
+	Parental        int         `gorm:"column:parental;notNull;default:0;comment:是否有子设备" json:"parental"`                        // 是否有子设备 1:有 0:没有
+	SafetyWay       int         `gorm:"column:safety_way;notNull;default:0;comment:信令安全模式" json:"safety_way"`                    // 信令安全模式
+	RegisterWay     int         `gorm:"column:register_way;notNull;default:0;comment:注册方式" json:"register_way"`                  // 注册方式
+	Secrecy         int         `gorm:"column:secrecy;notNull;default:0;comment:保密属性" json:"secrecy"`                            // 保密属性 0:不涉密 1:涉密
+	CertNum         string      `gorm:"column:cert_num;notNull;default:'';comment:证书序列号" json:"cert_num"`                        // 证书序列号
+	Certifiable     int         `gorm:"column:certifiable;notNull;default:0;comment:证书有效标识" json:"certifiable"`                  // 证书有效标识
+	ErrCode         int         `gorm:"column:err_code;notNull;default:0;comment:无效原因码" json:"err_code"`                         // 无效原因码
+	EndTime         string      `gorm:"column:end_time;notNull;default:'';comment:证书终止有效期" json:"end_time"`                      // 证书终止有效期
+	IPAddress       string      `gorm:"column:ip_address;notNull;default:'';comment:设备 IP" json:"ip_address"`                    // 设备 IP
+	Port            int         `gorm:"column:port;notNull;default:0;comment:设备端口" json:"port"`                                  // 设备端口
+	Longitude       float64     `gorm:"column:longitude;notNull;default:0;comment:经度" json:"longitude"`                          // 经度
+	Latitude        float64     `gorm:"column:latitude;notNull;default:0;comment:纬度" json:"latitude"`                            // 纬度
+	RemovedAt       *orm.Time   `gorm:"column:removed_at;comment:从设备目录移除的时间" json:"removed_at"`                                  // 从设备目录移除的时间，为空表示正常
+	Info            ChannelInfo `gorm:"column:info;notNull;type:JSON;default:'{}';comment:扩展信息" json:"info"`                     // 扩展信息
+
+	RegionCode string `gorm:"-" json:"region_code"` // 按国标编码前缀匹配的行政区划
+	RegionName string `gorm:"-" json:"region_name"` // 行政区划完整名称
 }
 
 // TableName database table name
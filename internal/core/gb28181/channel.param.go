@@ -1,7 +1,10 @@
 // Code generated by gowebx, DO AVOID EDIT.
 package gb28181
 
-import "github.com/ixugo/goweb/pkg/web"
+import (
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/bz"
+)
 
 type FindChannelInput struct {
 	web.PagerFilter
@@ -10,7 +13,12 @@ type FindChannelInput struct {
 	Key      string `form:"key"`       // 名称/国标编码 模糊搜索，id 精确搜索
 	// Name     string    `form:"name"`      // 通道名称
 	// PTZType  int       `form:"ptztype"`   // 云台类型
-	IsOnline string `form:"is_online"` // 是否在线
+	IsOnline string   `form:"is_online"`  // 是否在线
+	Removed  string   `form:"removed"`    // true:仅查询已从设备目录移除的通道，默认不包含已移除的通道
+	GroupID  string   `form:"group_id"`   // 分组 id，包含下级分组的通道
+	Region   string   `form:"region"`     // 行政区划编码，包含下级行政区划
+	GroupIDs []string `form:"-" json:"-"` // 分组及其下级分组 id，由 GroupID 展开
+	Scope    bz.Scope `form:"-" json:"-"` // 数据权限，ParentIDs 为授权的设备 id
 }
 
 type EditChannelInput struct {
@@ -2,6 +2,7 @@
 package gb28181
 
 import (
+	"wvp/internal/core/region"
 	"wvp/internal/core/uniqueid"
 )
 
@@ -9,15 +10,29 @@ import (
 type Storer interface {
 	Device() DeviceStorer
 	Channel() ChannelStorer
+	Pending() PendingDeviceStorer
 }
 
 // Core business domain
 type Core struct {
 	store    Storer
 	uniqueID uniqueid.Core
+	regions  *region.Registry
 }
 
 // NewCore create business domain
-func NewCore(store Storer, uni uniqueid.Core) Core {
-	return Core{store: store, uniqueID: uni}
+func NewCore(store Storer, uni uniqueid.Core, regions *region.Registry) Core {
+	return Core{store: store, uniqueID: uni, regions: regions}
+}
+
+// regionOf 国标编码所属的行政区划，编码未登记时名称为空
+func (c Core) regionOf(id string) (code, name string) {
+	if c.regions == nil {
+		return "", ""
+	}
+	code, err := c.regions.Match(id)
+	if err != nil {
+		return region.Code(id), ""
+	}
+	return code, c.regions.FullName(code)
 }
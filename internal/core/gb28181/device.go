@@ -9,7 +9,9 @@ import (
 	"github.com/ixugo/goweb/pkg/web"
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
+	"wvp/internal/core/audit"
 	"wvp/internal/core/bz"
+	"wvp/internal/core/region"
 )
 
 // DeviceStorer Instantiation interface
@@ -27,16 +29,29 @@ type DeviceStorer interface {
 func (c Core) FindDevice(ctx context.Context, in *FindDeviceInput) ([]*Device, int64, error) {
 	items := make([]*Device, 0)
 
-	query := orm.NewQuery(3)
+	query := orm.NewQuery(4)
 	query.OrderBy("created_at DESC")
 	if in.Key != "" {
 		query.Where("name LIKE ? OR device_id like ? OR id=?", "%"+in.Key+"%", "%"+in.Key+"%", in.Key)
 	}
+	if in.Region != "" {
+		if region.Level(in.Region) == 0 {
+			return nil, 0, web.ErrBadRequest.Msg("行政区划编码应为 2/4/6/8 位数字")
+		}
+		query.Where("device_id LIKE ?", in.Region+"%")
+	}
+	if in.Scope.Limited {
+		query.Where("id IN ?", in.Scope.IDs)
+	}
 
 	total, err := c.store.Device().Find(ctx, &items, in, query.Encode()...)
 	if err != nil {
 		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
 	}
+	for _, item := range items {
+		item.SetDeadline()
+		item.RegionCode, item.RegionName = c.regionOf(item.DeviceID)
+	}
 	return items, total, nil
 }
 
@@ -49,6 +64,8 @@ func (c Core) GetDevice(ctx context.Context, id string) (*Device, error) {
 		}
 		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
 	}
+	out.SetDeadline()
+	out.RegionCode, out.RegionName = c.regionOf(out.DeviceID)
 	return &out, nil
 }
 
@@ -86,6 +103,13 @@ func (c Core) AddDevice(ctx context.Context, in *AddDeviceInput) (*Device, error
 
 // EditDevice Update object information
 func (c Core) EditDevice(ctx context.Context, in *EditDeviceInput, id string) (*Device, error) {
+	audit.Record(ctx, audit.ActionDeviceEdit, id, in)
+	if err := IPList(in.AllowIPs).Check(); err != nil {
+		return nil, web.ErrBadRequest.Msg(err.Error())
+	}
+	if in.Keepalives < 0 || in.KeepaliveCount < 0 {
+		return nil, web.ErrBadRequest.Msg("心跳间隔与超时次数不能小于 0")
+	}
 	var out Device
 	if err := c.store.Device().Edit(ctx, &out, func(b *Device) {
 		if err := copier.Copy(b, in); err != nil {
@@ -99,6 +123,7 @@ func (c Core) EditDevice(ctx context.Context, in *EditDeviceInput, id string) (*
 
 // DelDevice Delete object
 func (c Core) DelDevice(ctx context.Context, id string) (*Device, error) {
+	audit.Record(ctx, audit.ActionDeviceDelete, id, nil)
 	var dev Device
 	if err := c.store.Device().Del(ctx, &dev, orm.Where("id=?", id)); err != nil {
 		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
@@ -3,11 +3,14 @@ package gb28181
 
 import (
 	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/bz"
 )
 
 type FindDeviceInput struct {
 	web.PagerFilter
-	Key string `form:"key"`
+	Key    string   `form:"key"`
+	Region string   `form:"region"`     // 行政区划编码，包含下级行政区划
+	Scope  bz.Scope `form:"-" json:"-"` // 数据权限
 	// DeviceID string `form:"device_id"` // 20 位国标编号
 	// Name     string `form:"name"`      // 设备名称
 	// ID       string `form:"id"`
@@ -30,6 +33,12 @@ type EditDeviceInput struct {
 	Password   string `json:"password"`    // 注册密码
 	StreamMode int    `json:"stream_mode"` // 数据传输模式
 
+	AllowIPs []string `json:"allow_ips"` // 允许注册的来源 IP/CIDR，为空不限制
+
+	// 设备不支持配置查询时手动配置，0 使用默认值，设备上报后以上报为准
+	Keepalives     int `json:"keepalives"`      // 心跳间隔(秒)
+	KeepaliveCount int `json:"keepalive_count"` // 心跳超时次数
+
 	// IP           string    `json:"ip"`
 	// Port         int       `json:"port"`
 	// IsOnline     bool      `json:"is_online"`
@@ -46,6 +55,8 @@ type AddDeviceInput struct {
 	Name     string `json:"name"`      // 设备名称
 	Password string `json:"password"`  // 注册密码
 
+	AllowIPs []string `json:"allow_ips"` // 允许注册的来源 IP/CIDR，为空不限制
+
 	// Trasnport    string    `json:"trasnport"`   // 传输协议(TCP/UDP)
 	// StreamMode   string    `json:"stream_mode"` // 数据传输模式(UDP/TCP_PASSIVE,TCP_ACTIVE)
 	// IP           string    `json:"ip"`
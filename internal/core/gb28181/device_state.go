@@ -0,0 +1,29 @@
+package gb28181
+
+import (
+	"context"
+	"time"
+)
+
+// DeviceState 设备运行状态，集群部署时各实例通过共享存储同步
+type DeviceState struct {
+	DeviceID       string    `json:"device_id"`
+	Node           string    `json:"node"` // 持有设备信令连接的实例，空串表示设备离线
+	IsOnline       bool      `json:"is_online"`
+	KeepaliveAt    time.Time `json:"keepalive_at"`
+	RegisteredAt   time.Time `json:"registered_at"`
+	Expires        int       `json:"expires"`
+	Keepalives     int       `json:"keepalives"`
+	KeepaliveCount int       `json:"keepalive_count"`
+	Address        string    `json:"address"`
+	Transport      string    `json:"transport"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// DeviceStateStorer 设备运行状态存储
+type DeviceStateStorer interface {
+	Save(context.Context, *DeviceState) error
+	List(context.Context) ([]*DeviceState, error)
+	Get(ctx context.Context, deviceID string) (*DeviceState, error) // 不存在时返回 nil
+	Del(ctx context.Context, deviceID string) error
+}
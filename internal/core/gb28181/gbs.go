@@ -2,10 +2,16 @@ package gb28181
 
 import (
 	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"reflect"
+	"time"
 
 	"github.com/ixugo/goweb/pkg/orm"
 	"github.com/ixugo/goweb/pkg/web"
 	"wvp/internal/core/bz"
+	"wvp/internal/core/event"
 	"wvp/internal/core/uniqueid"
 )
 
@@ -14,12 +20,14 @@ type GB28181 struct {
 	// channelStore ChannelStorer
 	store Storer
 	uni   uniqueid.Core
+	bus   *event.Bus
 }
 
-func NewGB28181(store Storer, uni uniqueid.Core) GB28181 {
+func NewGB28181(store Storer, uni uniqueid.Core, bus *event.Bus) GB28181 {
 	return GB28181{
 		store: store,
 		uni:   uni,
+		bus:   bus,
 	}
 }
 
@@ -27,21 +35,119 @@ func (g GB28181) Store() Storer {
 	return g.store
 }
 
-func (g GB28181) GetDeviceByDeviceID(deviceID string) (*Device, error) {
+// 设备准入策略
+const (
+	AdmissionOpen      = "open"      // 未知设备自动添加
+	AdmissionAllowlist = "allowlist" // 仅允许已添加的设备
+	AdmissionPending   = "pending"   // 未知设备记录到待审批列表，审批后准入
+)
+
+var (
+	ErrAdmissionDenied  = errors.New("device admission denied")
+	ErrAdmissionPending = errors.New("device admission pending approval")
+	ErrAdmissionUnknown = errors.New("device not added")
+)
+
+const (
+	maxPendingDevices    = 1000        // 待审批设备数量上限，超出后不再记录新设备
+	pendingWriteInterval = time.Minute // 同一设备待审批记录的最小更新间隔
+)
+
+// AdmitDeviceInput 设备注册准入
+type AdmitDeviceInput struct {
+	Policy    string // 准入策略
+	DeviceID  string
+	Transport string
+	Address   string // 来源地址 ip:port
+}
+
+// AdmitDevice 注册鉴权前的准入检查，已存在的设备校验来源 IP 白名单
+// 未知设备在 allowlist 策略下拒绝，其它策略返回 ErrAdmissionUnknown，鉴权通过后再调用 AdmitUnknownDevice
+func (g GB28181) AdmitDevice(in *AdmitDeviceInput) (*Device, error) {
 	ctx := context.TODO()
 	var d Device
-	if err := g.store.Device().Get(ctx, &d, orm.Where("device_id=?", deviceID)); err != nil {
-		if !orm.IsErrRecordNotFound(err) {
-			return nil, err
+	err := g.store.Device().Get(ctx, &d, orm.Where("device_id=?", in.DeviceID))
+	if err == nil {
+		ip, _, _ := net.SplitHostPort(in.Address)
+		if !d.AllowIPs.Allow(ip) {
+			return nil, ErrAdmissionDenied
 		}
-		d.init(g.uni.UniqueID(bz.IDPrefixGB), deviceID)
-		if err := g.store.Device().Add(ctx, &d); err != nil {
+		return &d, nil
+	}
+	if !orm.IsErrRecordNotFound(err) {
+		return nil, err
+	}
+	if in.Policy == AdmissionAllowlist {
+		return nil, ErrAdmissionDenied
+	}
+	return nil, ErrAdmissionUnknown
+}
+
+// AdmitUnknownDevice 未知设备鉴权通过后按准入策略处理，pending 记录到待审批列表，open 自动添加
+func (g GB28181) AdmitUnknownDevice(in *AdmitDeviceInput) (*Device, error) {
+	ctx := context.TODO()
+	switch in.Policy {
+	case AdmissionAllowlist:
+		return nil, ErrAdmissionDenied
+	case AdmissionPending:
+		if err := g.savePending(ctx, in); err != nil {
 			return nil, err
 		}
+		return nil, ErrAdmissionPending
+	}
+	var d Device
+	d.init(g.uni.UniqueID(bz.IDPrefixGB), in.DeviceID)
+	if err := g.store.Device().Add(ctx, &d); err != nil {
+		return nil, err
 	}
 	return &d, nil
 }
 
+// savePending 记录待审批设备，首次记录时发布事件
+// 同一设备在 pendingWriteInterval 内重复注册不更新，记录数达到上限后不再添加新设备
+func (g GB28181) savePending(ctx context.Context, in *AdmitDeviceInput) error {
+	var p PendingDevice
+	err := g.store.Pending().Get(ctx, &p, orm.Where("device_id=?", in.DeviceID))
+	if err == nil {
+		if time.Since(p.UpdatedAt.Time) < pendingWriteInterval {
+			return nil
+		}
+		return g.store.Pending().Edit(ctx, &p, func(p *PendingDevice) {
+			p.Transport = in.Transport
+			p.Address = in.Address
+			p.Attempts++
+		}, orm.Where("device_id=?", in.DeviceID))
+	}
+	if !orm.IsErrRecordNotFound(err) {
+		return err
+	}
+	total, err := g.store.Pending().Find(ctx, &[]*PendingDevice{}, web.PagerFilter{Page: 1, Size: 1})
+	if err != nil {
+		return err
+	}
+	if total >= maxPendingDevices {
+		slog.Warn("待审批设备数量已达上限，忽略新设备", "device_id", in.DeviceID, "address", in.Address, "limit", maxPendingDevices)
+		return nil
+	}
+	p = PendingDevice{
+		ID:        g.uni.UniqueID(bz.IDPrefixGBPending),
+		DeviceID:  in.DeviceID,
+		Transport: in.Transport,
+		Address:   in.Address,
+		Attempts:  1,
+	}
+	if err := g.store.Pending().Add(ctx, &p); err != nil {
+		return err
+	}
+	g.bus.Publish(event.TypeDevicePending, event.Device{
+		ID:        p.ID,
+		DeviceID:  p.DeviceID,
+		Address:   p.Address,
+		Transport: p.Transport,
+	})
+	return nil
+}
+
 func (g GB28181) Logout(deviceID string, changeFn func(*Device)) error {
 	var d Device
 	if err := g.store.Device().Edit(context.TODO(), &d, func(d *Device) {
@@ -64,30 +170,181 @@ func (g GB28181) Edit(deviceID string, changeFn func(*Device)) error {
 	return nil
 }
 
-func (g GB28181) SaveChannels(channels []*Channel) error {
-	if len(channels) <= 0 {
-		return nil
+// GetDevice 按国标编码查询设备
+func (g GB28181) GetDevice(deviceID string) (*Device, error) {
+	var d Device
+	if err := g.store.Device().Get(context.TODO(), &d, orm.Where("device_id=?", deviceID)); err != nil {
+		return nil, err
 	}
+	return &d, nil
+}
 
-	var dev Device
-	g.store.Device().Edit(context.TODO(), &dev, func(d *Device) {
-		d.Channels = len(channels)
-	}, orm.Where("device_id=?", channels[0].DeviceID))
+// SaveChannels 按设备上报的目录核对通道，新增、更新变化的通道
+// full 表示目录完整，此时设备未上报的通道标记为已移除，不完整的目录不做移除
+func (g GB28181) SaveChannels(deviceID string, channels []*Channel, full bool) (*CatalogDiff, error) {
+	cs, err := g.NewCatalogSync(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := cs.Save(channels); err != nil {
+		return nil, err
+	}
+	return cs.Finish(full)
+}
+
+// CatalogSync 分批核对设备目录，目录接收过程中每收到一批通道调用一次 Save，接收结束后调用 Finish
+// 仅在内存中保留数据库已有的通道用于比对，不保留设备上报的全部通道
+type CatalogSync struct {
+	g      GB28181
+	dev    Device
+	stored map[string]*Channel
+	seen   map[string]struct{}
+	diff   CatalogDiff
+}
+
+// NewCatalogSync 加载设备已有的通道，开始一次目录核对
+func (g GB28181) NewCatalogSync(deviceID string) (*CatalogSync, error) {
+	ctx := context.TODO()
+	s := CatalogSync{
+		g:    g,
+		seen: make(map[string]struct{}, 8),
+		diff: CatalogDiff{
+			DeviceID: deviceID,
+			Added:    make([]string, 0, 2),
+			Updated:  make([]string, 0, 2),
+			Removed:  make([]string, 0, 2),
+		},
+	}
+	if err := g.store.Device().Get(ctx, &s.dev, orm.Where("device_id=?", deviceID)); err != nil {
+		return nil, err
+	}
+	stored := make([]*Channel, 0, 8)
+	if _, err := g.store.Channel().Find(ctx, &stored, web.NewPagerFilterMaxSize(), orm.Where("device_id=?", deviceID)); err != nil {
+		return nil, err
+	}
+	s.stored = make(map[string]*Channel, len(stored))
+	for _, ch := range stored {
+		s.stored[ch.ChannelID] = ch
+	}
+	return &s, nil
+}
 
+// Save 比对一批通道，新增与变化的通道批量写入，新增通道的 ID 批量预分配
+func (s *CatalogSync) Save(channels []*Channel) error {
+	items := make([]*Channel, 0, len(channels))
+	added := make([]*Channel, 0, len(channels))
+	var restored, updated []*Channel
 	for _, channel := range channels {
-		var ch Channel
-		if err := g.store.Channel().Edit(context.TODO(), &ch, func(c *Channel) {
-			c.IsOnline = channel.IsOnline
-			ch.DID = dev.ID
-		}, orm.Where("device_id = ? AND channel_id = ?", channel.DeviceID, channel.ChannelID)); err != nil {
-			channel.ID = g.uni.UniqueID(bz.IDPrefixGBChannel)
-			channel.DID = dev.ID
-			g.store.Channel().Add(context.TODO(), channel)
+		if _, ok := s.seen[channel.ChannelID]; ok {
+			continue
+		}
+		s.seen[channel.ChannelID] = struct{}{}
+		s.diff.Total++
+		channel.DeviceID = s.dev.DeviceID
+		channel.DID = s.dev.ID
+
+		old, ok := s.stored[channel.ChannelID]
+		if !ok {
+			added = append(added, channel)
+			continue
+		}
+		next := *old
+		next.SetCatalog(channel)
+		next.DID = s.dev.ID
+		next.RemovedAt = nil
+		if reflect.DeepEqual(*old, next) {
+			s.diff.Unchanged++
+			continue
+		}
+		items = append(items, &next)
+		// 重新上报的已移除通道视为新增
+		if old.RemovedAt != nil {
+			restored = append(restored, &next)
+		} else {
+			updated = append(updated, &next)
+		}
+	}
+
+	ids := s.g.uni.UniqueIDs(bz.IDPrefixGBChannel, len(added))
+	for i, ch := range added {
+		ch.ID = ids[i]
+	}
+	items = append(items, added...)
+	if err := s.g.store.Channel().Upsert(context.TODO(), items, catalogColumns...); err != nil {
+		return err
+	}
+
+	for _, ch := range append(added, restored...) {
+		s.diff.Added = append(s.diff.Added, ch.ChannelID)
+		s.g.publishChannelStatus(&s.dev, ch)
+	}
+	for _, ch := range updated {
+		s.diff.Updated = append(s.diff.Updated, ch.ChannelID)
+		if s.stored[ch.ChannelID].IsOnline != ch.IsOnline {
+			s.g.publishChannelStatus(&s.dev, ch)
 		}
 	}
 	return nil
 }
 
+// Finish 结束目录核对，full 表示目录完整，此时设备未上报的通道标记为已移除
+func (s *CatalogSync) Finish(full bool) (*CatalogDiff, error) {
+	ctx := context.TODO()
+	s.diff.Partial = !full
+	if full {
+		ids := make([]string, 0, 2)
+		for _, ch := range s.stored {
+			if _, ok := s.seen[ch.ChannelID]; ok || ch.RemovedAt != nil {
+				continue
+			}
+			ids = append(ids, ch.ID)
+			s.diff.Removed = append(s.diff.Removed, ch.ChannelID)
+		}
+		if len(ids) > 0 {
+			if err := s.g.store.Channel().BatchEdit(ctx, "removed_at", orm.Now(), orm.Where("id IN ?", ids)); err != nil {
+				return nil, err
+			}
+			if err := s.g.store.Channel().BatchEdit(ctx, "is_online", false, orm.Where("id IN ?", ids)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 有效通道数
+	count := len(s.diff.Added) - len(s.diff.Removed)
+	for _, ch := range s.stored {
+		if ch.RemovedAt == nil {
+			count++
+		}
+	}
+	if err := s.g.store.Device().Edit(ctx, &s.dev, func(d *Device) {
+		d.Channels = count
+	}, orm.Where("id=?", s.dev.ID)); err != nil {
+		slog.Error("更新设备通道数失败", "err", err, "device_id", s.dev.DeviceID)
+	}
+
+	s.g.bus.Publish(event.TypeCatalogUpdate, event.Catalog{
+		DID:      s.dev.ID,
+		DeviceID: s.dev.DeviceID,
+		Total:    count,
+		Added:    len(s.diff.Added),
+		Updated:  len(s.diff.Updated),
+		Removed:  len(s.diff.Removed),
+	})
+	return &s.diff, nil
+}
+
+func (g GB28181) publishChannelStatus(dev *Device, ch *Channel) {
+	g.bus.Publish(event.TypeChannelStatus, event.Channel{
+		ID:        ch.ID,
+		DID:       dev.ID,
+		DeviceID:  dev.DeviceID,
+		ChannelID: ch.ChannelID,
+		Name:      ch.Name,
+		IsOnline:  ch.IsOnline,
+	})
+}
+
 // FindDevices 获取所有设备
 func (g GB28181) FindDevices(ctx context.Context) ([]*Device, error) {
 	var devices []*Device
@@ -4,6 +4,10 @@ package gb28181
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
 
 	"github.com/ixugo/goweb/pkg/orm"
 )
@@ -24,3 +28,119 @@ func (i *DeviceExt) Scan(input interface{}) error {
 func (i DeviceExt) Value() (driver.Value, error) {
 	return json.Marshal(i)
 }
+
+// ChannelInfo 设备目录 Info 扩展信息
+type ChannelInfo struct {
+	PTZType             int    `json:"ptz_type"`               // 摄像机结构类型 1:球机 2:半球 3:固定枪机 4:遥控枪机
+	PositionType        int    `json:"position_type"`          // 摄像机位置类型
+	RoomType            int    `json:"room_type"`              // 室外/室内 1:室外 2:室内
+	UseType             int    `json:"use_type"`               // 用途属性 1:治安 2:交通 3:重点
+	SupplyLightType     int    `json:"supply_light_type"`      // 补光属性
+	DirectionType       int    `json:"direction_type"`         // 监视方位
+	Resolution          string `json:"resolution"`             // 支持的分辨率
+	DownloadSpeed       string `json:"download_speed"`         // 下载倍速
+	SVCSpaceSupportMode int    `json:"svc_space_support_mode"` // 空域编码能力
+	SVCTimeSupportMode  int    `json:"svc_time_support_mode"`  // 时域编码能力
+}
+
+// Scan implements orm.Scaner.
+func (i *ChannelInfo) Scan(input interface{}) error {
+	return orm.JsonUnmarshal(input, i)
+}
+
+// Value implements driver.Valuer.
+func (i ChannelInfo) Value() (driver.Value, error) {
+	return json.Marshal(i)
+}
+
+// IPList 来源 IP/CIDR 白名单，以 json 格式存储，为空不限制
+type IPList []string
+
+// Scan implements orm.Scaner.
+func (l *IPList) Scan(input interface{}) error {
+	return orm.JsonUnmarshal(input, l)
+}
+
+// Value implements driver.Valuer.
+func (l IPList) Value() (driver.Value, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(l)
+}
+
+// Check 校验每一项为 IP 或 CIDR
+func (l IPList) Check() error {
+	for _, v := range l {
+		if strings.Contains(v, "/") {
+			if _, _, err := net.ParseCIDR(v); err != nil {
+				return fmt.Errorf("无效的网段 %s", v)
+			}
+			continue
+		}
+		if net.ParseIP(v) == nil {
+			return fmt.Errorf("无效的 IP %s", v)
+		}
+	}
+	return nil
+}
+
+// Allow 来源 IP 是否在白名单内，白名单为空时允许全部
+func (l IPList) Allow(ip string) bool {
+	if len(l) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, v := range l {
+		if strings.Contains(v, "/") {
+			if _, ipnet, err := net.ParseCIDR(v); err == nil && ipnet.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(v); other != nil && other.Equal(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// 心跳周期与超时次数的默认值，设备未上报且未配置时使用
+const (
+	DefaultKeepaliveInterval = 60 // 秒
+	DefaultKeepaliveCount    = 3
+)
+
+// registerGrace 注册过期的宽限时间，避免设备刷新注册的网络延迟导致离线
+const registerGrace = 10 * time.Second
+
+// KeepaliveDeadline 超过 心跳周期*超时次数 未收到心跳视为离线
+func KeepaliveDeadline(keepaliveAt time.Time, interval, count int) time.Time {
+	if interval <= 0 {
+		interval = DefaultKeepaliveInterval
+	}
+	if count <= 0 {
+		count = DefaultKeepaliveCount
+	}
+	return keepaliveAt.Add(time.Duration(interval*count) * time.Second)
+}
+
+// RegisterDeadline 注册有效期内未刷新视为离线，expires 小于等于 0 时返回零值
+func RegisterDeadline(registeredAt time.Time, expires int) time.Time {
+	if expires <= 0 {
+		return time.Time{}
+	}
+	return registeredAt.Add(time.Duration(expires)*time.Second + registerGrace)
+}
+
+// OnlineDeadline 心跳与注册截止时间中较早者
+func OnlineDeadline(keepaliveAt, registeredAt time.Time, interval, count, expires int) time.Time {
+	deadline := KeepaliveDeadline(keepaliveAt, interval, count)
+	if t := RegisterDeadline(registeredAt, expires); !t.IsZero() && t.Before(deadline) {
+		return t
+	}
+	return deadline
+}
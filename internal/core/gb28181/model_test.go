@@ -0,0 +1,21 @@
+package gb28181
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnlineDeadline(t *testing.T) {
+	now := time.Now()
+	// 未配置心跳参数时使用默认 60 秒 * 3 次
+	if got := OnlineDeadline(now, now, 0, 0, 0); !got.Equal(now.Add(3 * time.Minute)) {
+		t.Fatalf("expect default keepalive deadline, got %s", got.Sub(now))
+	}
+	if got := OnlineDeadline(now, now, 10, 2, 3600); !got.Equal(now.Add(20 * time.Second)) {
+		t.Fatalf("expect keepalive deadline, got %s", got.Sub(now))
+	}
+	// 注册有效期早于心跳截止时间
+	if got := OnlineDeadline(now, now, 60, 3, 60); !got.Equal(now.Add(60*time.Second + registerGrace)) {
+		t.Fatalf("expect register deadline, got %s", got.Sub(now))
+	}
+}
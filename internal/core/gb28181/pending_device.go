@@ -0,0 +1,70 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package gb28181
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/audit"
+)
+
+// PendingDeviceStorer Instantiation interface
+type PendingDeviceStorer interface {
+	Find(context.Context, *[]*PendingDevice, orm.Pager, ...orm.QueryOption) (int64, error)
+	Get(context.Context, *PendingDevice, ...orm.QueryOption) error
+	Add(context.Context, *PendingDevice) error
+	Edit(context.Context, *PendingDevice, func(*PendingDevice), ...orm.QueryOption) error
+	Del(context.Context, *PendingDevice, ...orm.QueryOption) error
+}
+
+// FindPendingDevice Paginated search
+func (c Core) FindPendingDevice(ctx context.Context, in *FindPendingDeviceInput) ([]*PendingDevice, int64, error) {
+	items := make([]*PendingDevice, 0)
+
+	query := orm.NewQuery(2)
+	query.OrderBy("updated_at DESC")
+	if in.Key != "" {
+		query.Where("device_id LIKE ?", "%"+in.Key+"%")
+	}
+	total, err := c.store.Pending().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// ApprovePendingDevice 审批通过，添加为设备，设备下次注册时准入
+func (c Core) ApprovePendingDevice(ctx context.Context, in *ApprovePendingDeviceInput, id string) (*Device, error) {
+	audit.Record(ctx, audit.ActionDeviceApprove, id, in)
+	var pending PendingDevice
+	if err := c.store.Pending().Get(ctx, &pending, orm.Where("id=?", id)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrNotFound.Withf(`Get err[%s]`, err.Error())
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	dev, err := c.AddDevice(ctx, &AddDeviceInput{
+		DeviceID: pending.DeviceID,
+		Name:     in.Name,
+		Password: in.Password,
+		AllowIPs: in.AllowIPs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.store.Pending().Del(ctx, &pending, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return dev, nil
+}
+
+// DelPendingDevice 拒绝并删除待审批记录，设备再次注册时重新记录
+func (c Core) DelPendingDevice(ctx context.Context, id string) (*PendingDevice, error) {
+	audit.Record(ctx, audit.ActionDeviceReject, id, nil)
+	var out PendingDevice
+	if err := c.store.Pending().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return &out, nil
+}
@@ -0,0 +1,20 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package gb28181
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// PendingDevice 待审批设备，准入策略为 pending 时未知设备的注册记录
+type PendingDevice struct {
+	ID        string   `gorm:"primaryKey" json:"id"`
+	DeviceID  string   `gorm:"column:device_id;notNull;uniqueIndex;default:'';comment:20 位国标编号" json:"device_id"`                 // 20 位国标编号
+	Transport string   `gorm:"column:transport;notNull;default:'';comment:传输协议" json:"transport"`                                 // 传输协议
+	Address   string   `gorm:"column:address;notNull;default:'';comment:最近一次注册的来源地址" json:"address"`                              // 最近一次注册的来源地址
+	Attempts  int      `gorm:"column:attempts;notNull;default:0;comment:注册次数" json:"attempts"`                                    // 注册次数
+	CreatedAt orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"` // 创建时间
+	UpdatedAt orm.Time `gorm:"column:updated_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"` // 更新时间，即最近一次注册时间
+}
+
+// TableName database table name
+func (*PendingDevice) TableName() string {
+	return "pending_devices"
+}
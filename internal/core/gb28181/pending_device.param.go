@@ -0,0 +1,15 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package gb28181
+
+import "github.com/ixugo/goweb/pkg/web"
+
+type FindPendingDeviceInput struct {
+	web.PagerFilter
+	Key string `form:"key"`
+}
+
+type ApprovePendingDeviceInput struct {
+	Name     string   `json:"name"`      // 设备名称
+	Password string   `json:"password"`  // 注册密码
+	AllowIPs []string `json:"allow_ips"` // 允许注册的来源 IP/CIDR，为空不限制
+}
@@ -0,0 +1,15 @@
+package gb28181
+
+import "context"
+
+// PlaySessionStorer 点播会话存储，支持内存、数据库与 redis
+type PlaySessionStorer interface {
+	List(context.Context) ([]*PlaySession, error)
+	Save(context.Context, *PlaySession) error // 按 ID 新增或覆盖
+	Del(ctx context.Context, id string) error
+}
+
+// PlaySessionID 点播会话 ID
+func PlaySessionID(deviceID, channelID string) string {
+	return "play:" + deviceID + ":" + channelID
+}
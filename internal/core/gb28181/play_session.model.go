@@ -0,0 +1,24 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package gb28181
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// PlaySession 国标点播会话，持久化用于程序重启后恢复或关闭设备推流
+type PlaySession struct {
+	ID            string   `gorm:"primaryKey" json:"id"`                                                                              // play:设备编号:通道编号
+	DeviceID      string   `gorm:"column:device_id;notNull;default:'';comment:20 位国标编号" json:"device_id"`                             // 20 位国标编号
+	ChannelID     string   `gorm:"column:channel_id;notNull;default:'';comment:通道国标编号" json:"channel_id"`                             // 通道国标编号
+	StreamID      string   `gorm:"column:stream_id;notNull;default:'';comment:流 ID" json:"stream_id"`                                 // 流 ID
+	SSRC          string   `gorm:"column:ssrc;notNull;default:'';comment:10 位 ssrc" json:"ssrc"`                                      // 10 位 ssrc
+	MediaServerID string   `gorm:"column:media_server_id;notNull;default:'';comment:接收流的媒体服务器 ID" json:"media_server_id"`             // 接收流的媒体服务器 ID
+	RTPPort       int      `gorm:"column:rtp_port;notNull;default:0;comment:rtp 接收端口" json:"rtp_port"`                                // rtp 接收端口
+	CallID        string   `gorm:"column:call_id;notNull;default:'';comment:会话 Call-ID" json:"call_id"`                               // 会话 Call-ID
+	Dialog        string   `gorm:"column:dialog;type:text;comment:对话状态" json:"dialog"`                                                // 对话状态 json，用于恢复对话发送 BYE
+	Node          string   `gorm:"column:node;notNull;default:'';comment:集群实例" json:"node"`                                           // 建立会话的集群实例
+	CreatedAt     orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"` // 创建时间
+}
+
+// TableName database table name
+func (*PlaySession) TableName() string {
+	return "play_sessions"
+}
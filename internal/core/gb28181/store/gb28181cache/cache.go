@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"strings"
+	"time"
 
 	"github.com/ixugo/goweb/pkg/conc"
 	"github.com/ixugo/goweb/pkg/orm"
@@ -23,6 +23,10 @@ type Cache struct {
 	gb28181.Storer
 
 	devices *conc.Map[string, *gbs.Device]
+
+	// 集群部署时共享的设备状态，node 为本实例标识
+	states gb28181.DeviceStateStorer
+	node   string
 }
 
 func (c *Cache) Device() gb28181.DeviceStorer {
@@ -40,6 +44,12 @@ func NewCache(store gb28181.Storer) *Cache {
 	}
 }
 
+// SetShared 集群部署时设备状态写入共享存储，用于其它实例同步状态与转发请求
+func (c *Cache) SetShared(states gb28181.DeviceStateStorer, node string) {
+	c.states = states
+	c.node = node
+}
+
 // LoadDeviceToMemory implements gbs.MemoryStorer.
 func (c *Cache) LoadDeviceToMemory(conn sip.Connection) {
 	devices := make([]*gb28181.Device, 0, 100)
@@ -49,16 +59,11 @@ func (c *Cache) LoadDeviceToMemory(conn sip.Connection) {
 	}
 
 	for _, d := range devices {
-		if strings.ToLower(d.Trasnport) == "tcp" {
-			// 通知相关设备/通道离线
-			continue
-		}
-
 		dev := gbs.NewDevice(conn, d)
 		if dev != nil {
 			slog.Debug("load device to memory", "device_id", d.DeviceID, "to", dev.To())
 			channels := make([]*gb28181.Channel, 0, 8)
-			_, err := c.Storer.Channel().Find(context.TODO(), &channels, web.NewPagerFilterMaxSize(), orm.Where("device_id=?", d.DeviceID))
+			_, err := c.Storer.Channel().Find(context.TODO(), &channels, web.NewPagerFilterMaxSize(), orm.Where("device_id=? AND removed_at IS NULL", d.DeviceID))
 			if err != nil {
 				panic(err)
 			}
@@ -88,9 +93,12 @@ func (c *Cache) Change(deviceID string, changeFn func(*gb28181.Device), changeFn
 	dev2.LastKeepaliveAt = dev.KeepaliveAt.Time
 	dev2.LastRegisterAt = dev.RegisteredAt.Time
 	dev2.Expires = dev.Expires
+	dev2.Keepalives = dev.Keepalives
+	dev2.KeepaliveCount = dev.KeepaliveCount
 	dev2.Password = dev.Password
 	dev2.Address = dev.Address
 	changeFn2(dev2)
+	c.saveState(deviceID, dev.Trasnport, dev2)
 	if !dev2.IsOnline {
 		if err := c.Storer.Channel().BatchEdit(context.TODO(), "is_online", false, orm.Where("did=?", dev.ID)); err != nil {
 			slog.Error("更新通道离线状态失败", "error", err)
@@ -99,6 +107,74 @@ func (c *Cache) Change(deviceID string, changeFn func(*gb28181.Device), changeFn
 	return nil
 }
 
+func (c *Cache) saveState(deviceID, transport string, dev *gbs.Device) {
+	if c.states == nil {
+		return
+	}
+	s := gb28181.DeviceState{
+		DeviceID:       deviceID,
+		Node:           dev.Node,
+		IsOnline:       dev.IsOnline,
+		KeepaliveAt:    dev.LastKeepaliveAt,
+		RegisteredAt:   dev.LastRegisterAt,
+		Expires:        dev.Expires,
+		Keepalives:     dev.Keepalives,
+		KeepaliveCount: dev.KeepaliveCount,
+		Address:        dev.Address,
+		Transport:      transport,
+		UpdatedAt:      time.Now(),
+	}
+	if err := c.states.Save(context.TODO(), &s); err != nil {
+		slog.Error("保存设备共享状态失败", "err", err, "device_id", deviceID)
+	}
+}
+
+// Sync implements gbs.MemoryStorer.
+// 由其它实例持有或已离线的设备，以共享存储中的状态为准
+func (c *Cache) Sync(ctx context.Context) error {
+	if c.states == nil {
+		return nil
+	}
+	states, err := c.states.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range states {
+		if s.Node == c.node {
+			continue
+		}
+		dev, ok := c.devices.Load(s.DeviceID)
+		if !ok {
+			continue
+		}
+		dev.Node = s.Node
+		dev.IsOnline = s.IsOnline
+		dev.LastKeepaliveAt = s.KeepaliveAt
+		dev.LastRegisterAt = s.RegisteredAt
+		dev.Expires = s.Expires
+		dev.Keepalives = s.Keepalives
+		dev.KeepaliveCount = s.KeepaliveCount
+		dev.Address = s.Address
+	}
+	return nil
+}
+
+// Owner implements gbs.MemoryStorer.
+func (c *Cache) Owner(deviceID string) string {
+	if c.states != nil {
+		s, err := c.states.Get(context.TODO(), deviceID)
+		if err != nil {
+			slog.Error("查询设备共享状态失败", "err", err, "device_id", deviceID)
+		} else if s != nil {
+			return s.Node
+		}
+	}
+	if dev, ok := c.devices.Load(deviceID); ok {
+		return dev.Node
+	}
+	return ""
+}
+
 // GetChannel implements gbs.MemoryStorer.
 func (c *Cache) GetChannel(deviceID string, channelID string) (*gbs.Channel, bool) {
 	dev, ok := c.devices.Load(deviceID)
@@ -28,6 +28,11 @@ func (c *Channel) BatchEdit(ctx context.Context, field string, value any, opts .
 	return c.Storer.Channel().BatchEdit(ctx, field, value, opts...)
 }
 
+// Upsert implements gb28181.ChannelStorer.
+func (c *Channel) Upsert(ctx context.Context, chs []*gb28181.Channel, columns ...string) error {
+	return c.Storer.Channel().Upsert(ctx, chs, columns...)
+}
+
 // Del implements gb28181.ChannelStorer.
 func (c *Channel) Del(ctx context.Context, ch *gb28181.Channel, opts ...orm.QueryOption) error {
 	return c.Storer.Channel().Del(ctx, ch, opts...)
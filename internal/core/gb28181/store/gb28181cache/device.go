@@ -43,6 +43,11 @@ func (d *Device) Del(ctx context.Context, dev *gb28181.Device, opts ...orm.Query
 	}
 
 	d.devices.Delete(dev.DeviceID)
+	if d.states != nil {
+		if err := d.states.Del(ctx, dev.DeviceID); err != nil {
+			slog.Error("删除设备共享状态失败", "err", err, "device_id", dev.DeviceID)
+		}
+	}
 	return nil
 }
 
@@ -55,6 +60,8 @@ func (d *Device) Edit(ctx context.Context, dev *gb28181.Device, changeFn func(*g
 	if !ok {
 		panic("edit device not found")
 	}
+	dev2.Keepalives = dev.Keepalives
+	dev2.KeepaliveCount = dev.KeepaliveCount
 	// 密码修改，设备需要重新注册
 	if dev2.Password != dev.Password && dev.Password != "" {
 		slog.Info("修改密码，设备离线")
@@ -0,0 +1,51 @@
+package gb28181cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"wvp/internal/core/gb28181"
+)
+
+var _ gb28181.PlaySessionStorer = &PlaySession{}
+
+// PlaySession 内存点播会话存储，程序重启后丢失
+type PlaySession struct {
+	mu    sync.RWMutex
+	items map[string]gb28181.PlaySession
+}
+
+func NewPlaySession() *PlaySession {
+	return &PlaySession{items: make(map[string]gb28181.PlaySession)}
+}
+
+// List implements gb28181.PlaySessionStorer.
+func (p *PlaySession) List(context.Context) ([]*gb28181.PlaySession, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	out := make([]*gb28181.PlaySession, 0, len(p.items))
+	for _, v := range p.items {
+		out = append(out, &v)
+	}
+	slices.SortFunc(out, func(a, b *gb28181.PlaySession) int {
+		return a.CreatedAt.Compare(b.CreatedAt.Time)
+	})
+	return out, nil
+}
+
+// Save implements gb28181.PlaySessionStorer.
+func (p *PlaySession) Save(_ context.Context, s *gb28181.PlaySession) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items[s.ID] = *s
+	return nil
+}
+
+// Del implements gb28181.PlaySessionStorer.
+func (p *PlaySession) Del(_ context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.items, id)
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ixugo/goweb/pkg/orm"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"wvp/internal/core/gb28181"
 )
 
@@ -26,6 +27,19 @@ func (d Channel) BatchEdit(ctx context.Context, column string, value any, args .
 	return db.UpdateColumn(column, value).Error
 }
 
+// Upsert implements gb28181.ChannelStorer.
+func (d Channel) Upsert(ctx context.Context, models []*gb28181.Channel, columns ...string) error {
+	if len(models) == 0 {
+		return nil
+	}
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "device_id"}, {Name: "channel_id"}},
+			DoUpdates: clause.AssignmentColumns(columns),
+		}).CreateInBatches(models, 200).Error
+	})
+}
+
 // NewChannel instance object
 func NewChannel(db *gorm.DB) Channel {
 	return Channel{db: db}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ixugo/goweb/pkg/orm"
 	"wvp/internal/core/gb28181"
 )
@@ -24,3 +25,25 @@ func TestChannelGet(t *testing.T) {
 		t.Fatal("ExpectationsWereMet err:", err)
 	}
 }
+
+func TestChannelUpsert(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	channelDB := NewChannel(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "channels" (.+) ON CONFLICT \("device_id","channel_id"\) DO UPDATE SET "is_online"="excluded"."is_online"`).
+		WillReturnRows(sqlmock.NewRows([]string{"info"}).AddRow("{}"))
+	mock.ExpectCommit()
+	err = channelDB.Upsert(context.Background(), []*gb28181.Channel{
+		{ID: "ch1", DeviceID: "34020000001320000001", ChannelID: "34020000001310000001", IsOnline: true},
+	}, "is_online")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
@@ -28,16 +28,35 @@ func (d DB) Channel() gb28181.ChannelStorer {
 	return Channel(d)
 }
 
+// Pending Get business instance
+func (d DB) Pending() gb28181.PendingDeviceStorer {
+	return PendingDevice(d)
+}
+
 // AutoMigrate sync database
 func (d DB) AutoMigrate(ok bool) DB {
 	if !ok {
 		return d
 	}
+	if err := d.dedupChannels(); err != nil {
+		panic(err)
+	}
 	if err := d.db.AutoMigrate(
 		new(gb28181.Device),
 		new(gb28181.Channel),
+		new(gb28181.PendingDevice),
+		new(gb28181.PlaySession),
 	); err != nil {
 		panic(err)
 	}
 	return d
 }
+
+// dedupChannels 创建 (device_id, channel_id) 唯一索引前，删除历史数据中重复的通道
+func (d DB) dedupChannels() error {
+	m := d.db.Migrator()
+	if !m.HasTable(new(gb28181.Channel)) || m.HasIndex(new(gb28181.Channel), "idx_channels_device_channel") {
+		return nil
+	}
+	return d.db.Exec(`DELETE FROM channels WHERE id NOT IN (SELECT id FROM (SELECT MIN(id) AS id FROM channels GROUP BY device_id, channel_id) t)`).Error
+}
@@ -0,0 +1,45 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package gb28181db
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/gb28181"
+)
+
+var _ gb28181.PendingDeviceStorer = PendingDevice{}
+
+// PendingDevice Related business namespaces
+type PendingDevice DB
+
+// NewPendingDevice instance object
+func NewPendingDevice(db *gorm.DB) PendingDevice {
+	return PendingDevice{db: db}
+}
+
+// Find implements gb28181.PendingDeviceStorer.
+func (d PendingDevice) Find(ctx context.Context, bs *[]*gb28181.PendingDevice, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Get implements gb28181.PendingDeviceStorer.
+func (d PendingDevice) Get(ctx context.Context, model *gb28181.PendingDevice, opts ...orm.QueryOption) error {
+	return orm.FirstWithContext(ctx, d.db, model, opts...)
+}
+
+// Add implements gb28181.PendingDeviceStorer.
+func (d PendingDevice) Add(ctx context.Context, model *gb28181.PendingDevice) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Edit implements gb28181.PendingDeviceStorer.
+func (d PendingDevice) Edit(ctx context.Context, model *gb28181.PendingDevice, changeFn func(*gb28181.PendingDevice), opts ...orm.QueryOption) error {
+	return orm.UpdateWithContext(ctx, d.db, model, changeFn, opts...)
+}
+
+// Del implements gb28181.PendingDeviceStorer.
+func (d PendingDevice) Del(ctx context.Context, model *gb28181.PendingDevice, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
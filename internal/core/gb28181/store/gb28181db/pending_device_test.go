@@ -0,0 +1,31 @@
+package gb28181db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ixugo/goweb/pkg/orm"
+	"wvp/internal/core/gb28181"
+)
+
+func TestPendingDeviceGet(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingDB := NewPendingDevice(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "pending_devices" WHERE device_id=\$1 (.+) LIMIT \$2`).WithArgs("34020000001320000001", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "device_id", "address", "attempts"}).AddRow("gp1", "34020000001320000001", "192.168.1.2:5060", 3))
+	var out gb28181.PendingDevice
+	if err := pendingDB.Get(context.Background(), &out, orm.Where("device_id=?", "34020000001320000001")); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != "gp1" || out.Attempts != 3 {
+		t.Fatalf("unexpected pending device %+v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
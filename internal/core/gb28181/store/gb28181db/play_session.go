@@ -0,0 +1,36 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package gb28181db
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"wvp/internal/core/gb28181"
+)
+
+var _ gb28181.PlaySessionStorer = PlaySession{}
+
+// PlaySession Related business namespaces
+type PlaySession DB
+
+// NewPlaySession instance object
+func NewPlaySession(db *gorm.DB) PlaySession {
+	return PlaySession{db: db}
+}
+
+// List implements gb28181.PlaySessionStorer.
+func (d PlaySession) List(ctx context.Context) ([]*gb28181.PlaySession, error) {
+	items := make([]*gb28181.PlaySession, 0, 8)
+	err := d.db.WithContext(ctx).Order("created_at").Find(&items).Error
+	return items, err
+}
+
+// Save implements gb28181.PlaySessionStorer.
+func (d PlaySession) Save(ctx context.Context, model *gb28181.PlaySession) error {
+	return d.db.WithContext(ctx).Save(model).Error
+}
+
+// Del implements gb28181.PlaySessionStorer.
+func (d PlaySession) Del(ctx context.Context, id string) error {
+	return d.db.WithContext(ctx).Where("id=?", id).Delete(new(gb28181.PlaySession)).Error
+}
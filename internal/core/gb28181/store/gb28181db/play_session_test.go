@@ -0,0 +1,48 @@
+package gb28181db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPlaySessionList(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionDB := NewPlaySession(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "play_sessions" ORDER BY created_at`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "device_id", "channel_id", "ssrc", "rtp_port"}).
+			AddRow("play:34020000001320000001:34020000001310000001", "34020000001320000001", "34020000001310000001", "0200000001", 30000))
+	items, err := sessionDB.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].SSRC != "0200000001" || items[0].RTPPort != 30000 {
+		t.Fatalf("unexpected sessions %+v", items)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
+
+func TestPlaySessionDel(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionDB := NewPlaySession(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "play_sessions" WHERE id=\$1`).WithArgs("play:1:2").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := sessionDB.Del(context.Background(), "play:1:2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
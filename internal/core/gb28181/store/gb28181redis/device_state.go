@@ -0,0 +1,77 @@
+package gb28181redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"wvp/internal/core/gb28181"
+	"wvp/pkg/redis"
+)
+
+var _ gb28181.DeviceStateStorer = DeviceState{}
+
+// DeviceState redis 设备状态存储，全部设备保存在 {prefix}device_states 哈希表中
+type DeviceState struct {
+	cli    *redis.Client
+	prefix string
+}
+
+// NewDeviceState prefix 为键前缀，多个服务共用 redis 时用于区分
+func NewDeviceState(cli *redis.Client, prefix string) DeviceState {
+	return DeviceState{cli: cli, prefix: prefix}
+}
+
+func (d DeviceState) key() string {
+	return d.prefix + "device_states"
+}
+
+// Save implements gb28181.DeviceStateStorer.
+func (d DeviceState) Save(ctx context.Context, s *gb28181.DeviceState) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = d.cli.Do(ctx, "HSET", d.key(), s.DeviceID, string(b))
+	return err
+}
+
+// List implements gb28181.DeviceStateStorer.
+func (d DeviceState) List(ctx context.Context) ([]*gb28181.DeviceState, error) {
+	values, err := redis.Strings(d.cli.Do(ctx, "HGETALL", d.key()))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*gb28181.DeviceState, 0, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		var s gb28181.DeviceState
+		if err := json.Unmarshal([]byte(values[i+1]), &s); err != nil {
+			return nil, fmt.Errorf("decode device state %s: %w", values[i], err)
+		}
+		out = append(out, &s)
+	}
+	return out, nil
+}
+
+// Get implements gb28181.DeviceStateStorer.
+func (d DeviceState) Get(ctx context.Context, deviceID string) (*gb28181.DeviceState, error) {
+	v, err := redis.String(d.cli.Do(ctx, "HGET", d.key(), deviceID))
+	if errors.Is(err, redis.ErrNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s gb28181.DeviceState
+	if err := json.Unmarshal([]byte(v), &s); err != nil {
+		return nil, fmt.Errorf("decode device state %s: %w", deviceID, err)
+	}
+	return &s, nil
+}
+
+// Del implements gb28181.DeviceStateStorer.
+func (d DeviceState) Del(ctx context.Context, deviceID string) error {
+	_, err := d.cli.Do(ctx, "HDEL", d.key(), deviceID)
+	return err
+}
@@ -0,0 +1,97 @@
+package gb28181redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"wvp/internal/core/gb28181"
+	"wvp/pkg/redis"
+)
+
+var _ gb28181.PlaySessionStorer = PlaySession{}
+
+// PlaySession redis 点播会话存储
+// 会话以 json 保存在 {prefix}play_session:{id}，{prefix}play_sessions 集合记录全部会话 id
+type PlaySession struct {
+	cli    *redis.Client
+	prefix string
+}
+
+// NewPlaySession prefix 为键前缀，多个服务共用 redis 时用于区分
+func NewPlaySession(cli *redis.Client, prefix string) PlaySession {
+	return PlaySession{cli: cli, prefix: prefix}
+}
+
+func (p PlaySession) key(id string) string {
+	return p.prefix + "play_session:" + id
+}
+
+func (p PlaySession) index() string {
+	return p.prefix + "play_sessions"
+}
+
+// List implements gb28181.PlaySessionStorer.
+func (p PlaySession) List(ctx context.Context) ([]*gb28181.PlaySession, error) {
+	ids, err := redis.Strings(p.cli.Do(ctx, "SMEMBERS", p.index()))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*gb28181.PlaySession, 0, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	args := make([]string, 0, len(ids)+1)
+	args = append(args, "MGET")
+	for _, id := range ids {
+		args = append(args, p.key(id))
+	}
+	values, err := redis.Strings(p.cli.Do(ctx, args...))
+	if err != nil {
+		return nil, err
+	}
+	stale := make([]string, 0, 2)
+	for i, v := range values {
+		if v == "" {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var s gb28181.PlaySession
+		if err := json.Unmarshal([]byte(v), &s); err != nil {
+			return nil, fmt.Errorf("decode play session %s: %w", ids[i], err)
+		}
+		out = append(out, &s)
+	}
+	// 会话已删除但未从集合移除
+	if len(stale) > 0 {
+		_, _ = p.cli.Do(ctx, append([]string{"SREM", p.index()}, stale...)...)
+	}
+	slices.SortFunc(out, func(a, b *gb28181.PlaySession) int {
+		return a.CreatedAt.Compare(b.CreatedAt.Time)
+	})
+	return out, nil
+}
+
+// Save implements gb28181.PlaySessionStorer.
+func (p PlaySession) Save(ctx context.Context, s *gb28181.PlaySession) error {
+	b, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if _, err := p.cli.Do(ctx, "SET", p.key(s.ID), string(b)); err != nil {
+		return err
+	}
+	_, err = p.cli.Do(ctx, "SADD", p.index(), s.ID)
+	return err
+}
+
+// Del implements gb28181.PlaySessionStorer.
+func (p PlaySession) Del(ctx context.Context, id string) error {
+	if _, err := p.cli.Do(ctx, "DEL", p.key(id)); err != nil {
+		return err
+	}
+	_, err := p.cli.Do(ctx, "SREM", p.index(), id)
+	return err
+}
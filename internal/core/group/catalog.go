@@ -0,0 +1,80 @@
+package group
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/bz"
+)
+
+// CatalogNode 分组在国标目录中的节点 GB/T28181 附录 O
+type CatalogNode struct {
+	ChannelID       string // 国标编码
+	Name            string
+	ParentID        string // 上级节点国标编码，业务分组为空
+	BusinessGroupID string // 所属业务分组国标编码
+}
+
+// Catalog 分组导出为国标目录，根节点为业务分组，下级为虚拟组织
+// parents 为通道 id 对应的上级节点，通道属于多个分组时取排序靠前的分组
+// 推流与拉流代理没有国标编码，不导出
+func (c Core) Catalog(ctx context.Context) (nodes []CatalogNode, parents map[string]CatalogNode, err error) {
+	groups, err := c.findAll(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	members := make([]*Member, 0, 8)
+	if _, err := c.store.Member().Find(ctx, &members, web.NewPagerFilterMaxSize(),
+		orm.Where("resource_type=?", bz.ResourceChannel), orm.OrderBy("id ASC"),
+	); err != nil {
+		return nil, nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	nodes, parents = BuildCatalog(groups, members)
+	return nodes, parents, nil
+}
+
+// BuildCatalog 按分组树生成国标目录节点
+func BuildCatalog(groups []*Group, members []*Member) ([]CatalogNode, map[string]CatalogNode) {
+	nodes := make([]CatalogNode, 0, len(groups))
+	byID := make(map[string]CatalogNode, len(groups))
+	var walk func(items []*Node, parent, business string)
+	walk = func(items []*Node, parent, business string) {
+		for _, v := range items {
+			node := CatalogNode{ChannelID: v.ChannelID, Name: v.Name, ParentID: parent, BusinessGroupID: business}
+			if parent == "" {
+				node.BusinessGroupID = v.ChannelID
+			}
+			nodes = append(nodes, node)
+			byID[v.ID] = node
+			walk(v.Children, node.ChannelID, node.BusinessGroupID)
+		}
+	}
+	walk(BuildTree(groups), "", "")
+
+	parents := make(map[string]CatalogNode, len(members))
+	for _, m := range members {
+		g, ok := byID[m.GroupID]
+		if !ok {
+			continue
+		}
+		if p, ok := parents[m.ResourceID]; ok && !before(nodes, g.ChannelID, p.ParentID) {
+			continue
+		}
+		parents[m.ResourceID] = CatalogNode{ParentID: g.ChannelID, BusinessGroupID: g.BusinessGroupID}
+	}
+	return nodes, parents
+}
+
+// before 目录顺序中 a 是否排在 b 之前
+func before(nodes []CatalogNode, a, b string) bool {
+	for _, v := range nodes {
+		switch v.ChannelID {
+		case a:
+			return true
+		case b:
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,26 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package group
+
+import "wvp/internal/core/uniqueid"
+
+// Storer data persistence
+type Storer interface {
+	Group() GroupStorer
+	Member() MemberStorer
+}
+
+// Core business domain
+type Core struct {
+	store    Storer
+	uniqueID uniqueid.Core
+	domain   string // 国标编码前 10 位中心编码，用于生成分组国标编码
+}
+
+// NewCore create business domain
+func NewCore(store Storer, uni uniqueid.Core, domain string) Core {
+	return Core{
+		store:    store,
+		uniqueID: uni,
+		domain:   domain,
+	}
+}
@@ -0,0 +1,397 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package group
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/audit"
+	"wvp/internal/core/bz"
+)
+
+// resourceTypes 支持分组的资源类型
+var resourceTypes = []string{bz.ResourceChannel, bz.ResourcePush, bz.ResourceProxy}
+
+// 分组国标编码第 11~13 位 GB/T28181 附录 D
+const (
+	TypeBusinessGroup = "215" // 业务分组
+	TypeVirtualOrg    = "216" // 虚拟组织
+)
+
+// GroupStorer Instantiation interface
+type GroupStorer interface {
+	Find(context.Context, *[]*Group, orm.Pager, ...orm.QueryOption) (int64, error)
+	Get(context.Context, *Group, ...orm.QueryOption) error
+	Add(context.Context, *Group) error
+	Edit(context.Context, *Group, func(*Group), ...orm.QueryOption) error
+	Del(context.Context, *Group, ...orm.QueryOption) error
+}
+
+// MemberStorer Instantiation interface
+type MemberStorer interface {
+	Find(context.Context, *[]*Member, orm.Pager, ...orm.QueryOption) (int64, error)
+	Add(context.Context, ...*Member) error
+	Del(context.Context, *Member, ...orm.QueryOption) error
+}
+
+// Node 分组树节点
+type Node struct {
+	Group
+	Children []*Node `json:"children"`
+}
+
+// BuildTree 按 ParentID 组织分组树，同级按 Sort 升序，上级不存在的分组挂在根节点
+func BuildTree(groups []*Group) []*Node {
+	nodes := make(map[string]*Node, len(groups))
+	for _, g := range groups {
+		nodes[g.ID] = &Node{Group: *g, Children: make([]*Node, 0)}
+	}
+	roots := make([]*Node, 0, 8)
+	for _, g := range groups {
+		node := nodes[g.ID]
+		if p, ok := nodes[g.ParentID]; ok && g.ParentID != g.ID {
+			p.Children = append(p.Children, node)
+			continue
+		}
+		roots = append(roots, node)
+	}
+	sortNodes(roots)
+	return roots
+}
+
+func sortNodes(items []*Node) {
+	slices.SortFunc(items, func(a, b *Node) int {
+		if n := cmp.Compare(a.Sort, b.Sort); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	for _, v := range items {
+		sortNodes(v.Children)
+	}
+}
+
+// Subtree 分组及其所有下级分组的 id
+func Subtree(groups []*Group, id string) []string {
+	children := make(map[string][]string, len(groups))
+	for _, g := range groups {
+		children[g.ParentID] = append(children[g.ParentID], g.ID)
+	}
+	out := make([]string, 0, 8)
+	seen := make(map[string]struct{}, len(groups))
+	queue := []string{id}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[v]; ok {
+			continue
+		}
+		seen[v] = struct{}{}
+		out = append(out, v)
+		queue = append(queue, children[v]...)
+	}
+	return out
+}
+
+func (c Core) findAll(ctx context.Context) ([]*Group, error) {
+	items := make([]*Group, 0, 8)
+	if _, err := c.store.Group().Find(ctx, &items, web.NewPagerFilterMaxSize(), orm.OrderBy("sort ASC")); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, nil
+}
+
+// FindGroupTree 查询分组树
+func (c Core) FindGroupTree(ctx context.Context) ([]*Node, error) {
+	items, err := c.findAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return BuildTree(items), nil
+}
+
+// GetGroup Query a single object
+func (c Core) GetGroup(ctx context.Context, id string) (*Group, error) {
+	var out Group
+	if err := c.store.Group().Get(ctx, &out, orm.Where("id=?", id)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrNotFound.Withf(`Get err[%s]`, err.Error())
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// SubtreeIDs 分组及其所有下级分组的 id，用于按分组树过滤资源
+func (c Core) SubtreeIDs(ctx context.Context, id string) ([]string, error) {
+	if _, err := c.GetGroup(ctx, id); err != nil {
+		return nil, err
+	}
+	items, err := c.findAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Subtree(items, id), nil
+}
+
+// AddGroup Insert into database，新分组排在同级末尾
+func (c Core) AddGroup(ctx context.Context, in *AddGroupInput) (*Group, error) {
+	typ := TypeBusinessGroup
+	if in.ParentID != "" {
+		if _, err := c.GetGroup(ctx, in.ParentID); err != nil {
+			return nil, err
+		}
+		typ = TypeVirtualOrg
+	}
+	siblings := make([]*Group, 0, 8)
+	if _, err := c.store.Group().Find(ctx, &siblings, web.NewPagerFilterMaxSize(), orm.Where("parent_id=?", in.ParentID)); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	out := Group{
+		ID:        c.uniqueID.UniqueID(bz.IDPrefixGroup),
+		ParentID:  in.ParentID,
+		Name:      in.Name,
+		Remark:    in.Remark,
+		ChannelID: in.ChannelID,
+	}
+	for _, v := range siblings {
+		out.Sort = max(out.Sort, v.Sort+1)
+	}
+
+	// 自动生成的国标编码重复时重新生成
+	for range 5 {
+		if in.ChannelID == "" {
+			out.ChannelID = c.channelID(typ)
+		}
+		err := c.store.Group().Add(ctx, &out)
+		if err == nil {
+			return &out, nil
+		}
+		if !orm.IsDuplicatedKey(err) {
+			return nil, web.ErrDB.Withf(`Add err[%s]`, err.Error())
+		}
+		if in.ChannelID != "" {
+			return nil, web.ErrDB.Msg("国标编码重复，请更换")
+		}
+	}
+	return nil, web.ErrDB.Msg("生成国标编码失败，请重试")
+}
+
+// channelID 生成分组国标编码，中心编码 10 位 + 类型 3 位 + 序号 7 位
+func (c Core) channelID(typ string) string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(10_000_000))
+	return fmt.Sprintf("%.10s%s%07d", c.domain+"0000000000", typ, n.Int64())
+}
+
+// retype 分组层级变化后调整国标编码中的类型
+func retype(channelID, typ string) string {
+	if len(channelID) != 20 {
+		return channelID
+	}
+	if t := channelID[10:13]; t != TypeBusinessGroup && t != TypeVirtualOrg {
+		return channelID
+	}
+	return channelID[:10] + typ + channelID[13:]
+}
+
+// EditGroup Update object information
+func (c Core) EditGroup(ctx context.Context, in *EditGroupInput, id string) (*Group, error) {
+	var out Group
+	if err := c.store.Group().Edit(ctx, &out, func(b *Group) {
+		b.Name = in.Name
+		b.Remark = in.Remark
+	}, orm.Where("id=?", id)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrNotFound.Withf(`Edit err[%s]`, err.Error())
+		}
+		return nil, web.ErrDB.Withf(`Edit err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// MoveGroup 移动分组到新的上级，并调整在同级中的顺序
+func (c Core) MoveGroup(ctx context.Context, in *MoveGroupInput, id string) (*Group, error) {
+	audit.Record(ctx, audit.ActionGroupMove, id, in)
+	items, err := c.findAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	idx := slices.IndexFunc(items, func(g *Group) bool { return g.ID == id })
+	if idx < 0 {
+		return nil, web.ErrNotFound.Msg("分组不存在")
+	}
+	if in.ParentID != "" {
+		if !slices.ContainsFunc(items, func(g *Group) bool { return g.ID == in.ParentID }) {
+			return nil, web.ErrNotFound.Msg("上级分组不存在")
+		}
+		if slices.Contains(Subtree(items, id), in.ParentID) {
+			return nil, web.ErrBadRequest.Msg("不能移动到自身或下级分组")
+		}
+	}
+
+	siblings := make([]*Group, 0, 8)
+	for _, g := range items {
+		if g.ParentID == in.ParentID && g.ID != id {
+			siblings = append(siblings, g)
+		}
+	}
+	slices.SortStableFunc(siblings, func(a, b *Group) int { return cmp.Compare(a.Sort, b.Sort) })
+	pos := in.Index
+	if pos < 0 || pos > len(siblings) {
+		pos = len(siblings)
+	}
+	siblings = slices.Insert(siblings, pos, items[idx])
+
+	typ := TypeBusinessGroup
+	if in.ParentID != "" {
+		typ = TypeVirtualOrg
+	}
+	var out Group
+	for i, g := range siblings {
+		if g.ID != id && g.Sort == i {
+			continue
+		}
+		var v Group
+		if err := c.store.Group().Edit(ctx, &v, func(b *Group) {
+			b.Sort = i
+			if b.ID == id {
+				b.ParentID = in.ParentID
+				b.ChannelID = retype(b.ChannelID, typ)
+			}
+		}, orm.Where("id=?", g.ID)); err != nil {
+			return nil, web.ErrDB.Withf(`Edit err[%s]`, err.Error())
+		}
+		if g.ID == id {
+			out = v
+		}
+	}
+	return &out, nil
+}
+
+// DelGroup Delete object，同时删除所有下级分组与分组成员
+func (c Core) DelGroup(ctx context.Context, id string) (*Group, error) {
+	audit.Record(ctx, audit.ActionGroupDelete, id, nil)
+	ids, err := c.SubtreeIDs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var out Group
+	if err := c.store.Group().Del(ctx, &out, orm.Where("id IN ?", ids)); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	if err := c.store.Member().Del(ctx, new(Member), orm.Where("group_id IN ?", ids)); err != nil {
+		slog.Error("删除分组成员失败", "group_id", id, "err", err)
+	}
+	return &out, nil
+}
+
+// FindMember 查询分组成员
+func (c Core) FindMember(ctx context.Context, in *FindMemberInput, groupID string) ([]*Member, int64, error) {
+	items := make([]*Member, 0)
+
+	query := orm.NewQuery(2)
+	query.Where("group_id=?", groupID)
+	if in.ResourceType != "" {
+		query.Where("resource_type=?", in.ResourceType)
+	}
+	if cond, args := scopeCond(in.Scopes); cond != "" {
+		query.Where(cond, args...)
+	}
+
+	total, err := c.store.Member().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// scopeCond 按数据权限过滤成员，授权设备后可访问设备下的通道
+func scopeCond(scopes map[string]bz.Scope) (string, []any) {
+	if !slices.ContainsFunc(resourceTypes, func(t string) bool { return scopes[t].Limited }) {
+		return "", nil
+	}
+	conds := make([]string, 0, len(resourceTypes)+1)
+	args := make([]any, 0, 2*len(resourceTypes)+2)
+	for _, t := range resourceTypes {
+		scope := scopes[t]
+		if !scope.Limited {
+			conds = append(conds, "resource_type=?")
+			args = append(args, t)
+			continue
+		}
+		conds = append(conds, "(resource_type=? AND resource_id IN ?)")
+		args = append(args, t, scope.IDs)
+		if t == bz.ResourceChannel && len(scope.ParentIDs) > 0 {
+			conds = append(conds, "(resource_type=? AND resource_id IN (SELECT id FROM channels WHERE device_id IN (SELECT device_id FROM devices WHERE id IN ?)))")
+			args = append(args, t, scope.ParentIDs)
+		}
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// AddMembers 批量添加分组成员，已存在的成员忽略，同一资源可属于多个分组
+func (c Core) AddMembers(ctx context.Context, in *AddMembersInput, groupID string) ([]*Member, error) {
+	if !slices.Contains(resourceTypes, in.ResourceType) {
+		return nil, web.ErrBadRequest.Msg("不支持的资源类型")
+	}
+	if _, err := c.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	exists := make([]*Member, 0, 8)
+	if _, err := c.store.Member().Find(ctx, &exists, web.NewPagerFilterMaxSize(),
+		orm.Where("group_id=? AND resource_type=? AND resource_id IN ?", groupID, in.ResourceType, in.ResourceIDs),
+	); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+
+	items := make([]*Member, 0, len(in.ResourceIDs))
+	for _, id := range in.ResourceIDs {
+		if id == "" || slices.ContainsFunc(exists, func(m *Member) bool { return m.ResourceID == id }) ||
+			slices.ContainsFunc(items, func(m *Member) bool { return m.ResourceID == id }) {
+			continue
+		}
+		items = append(items, &Member{
+			GroupID:      groupID,
+			ResourceType: in.ResourceType,
+			ResourceID:   id,
+		})
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+	if err := c.store.Member().Add(ctx, items...); err != nil {
+		return nil, web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return items, nil
+}
+
+// DelMembers 批量移除分组成员
+func (c Core) DelMembers(ctx context.Context, in *DelMembersInput, groupID string) error {
+	if err := c.store.Member().Del(ctx, new(Member),
+		orm.Where("group_id=? AND resource_type=? AND resource_id IN ?", groupID, in.ResourceType, in.ResourceIDs),
+	); err != nil {
+		return web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return nil
+}
+
+// MoveMembers 将成员从当前分组移动到目标分组
+func (c Core) MoveMembers(ctx context.Context, in *MoveMembersInput, groupID string) ([]*Member, error) {
+	if in.TargetID == groupID {
+		return nil, web.ErrBadRequest.Msg("目标分组与当前分组相同")
+	}
+	items, err := c.AddMembers(ctx, &AddMembersInput{ResourceType: in.ResourceType, ResourceIDs: in.ResourceIDs}, in.TargetID)
+	if err != nil {
+		return nil, err
+	}
+	return items, c.DelMembers(ctx, &DelMembersInput{ResourceType: in.ResourceType, ResourceIDs: in.ResourceIDs}, groupID)
+}
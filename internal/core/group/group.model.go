@@ -0,0 +1,35 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package group
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// Group domain model
+type Group struct {
+	ID        string   `gorm:"primaryKey" json:"id"`
+	CreatedAt orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"` // 创建时间
+	UpdatedAt orm.Time `gorm:"column:updated_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"` // 更新时间
+	ParentID  string   `gorm:"column:parent_id;index;notNull;default:'';comment:上级分组 id" json:"parent_id"`                        // 上级分组 id，为空表示根节点
+	Name      string   `gorm:"column:name;notNull;default:'';comment:分组名称" json:"name"`                                           // 分组名称
+	Sort      int      `gorm:"column:sort;notNull;default:0;comment:同级排序" json:"sort"`                                            // 同级排序，升序
+	ChannelID string   `gorm:"column:channel_id;uniqueIndex;notNull;default:'';comment:国标编码" json:"channel_id"`                   // 国标编码，根节点为业务分组，其余为虚拟组织
+	Remark    string   `gorm:"column:remark;notNull;default:'';comment:备注" json:"remark"`                                         // 备注
+}
+
+// TableName database table name
+func (*Group) TableName() string {
+	return "groups"
+}
+
+// Member domain model
+type Member struct {
+	ID           int      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt    orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`                         // 创建时间
+	GroupID      string   `gorm:"column:group_id;uniqueIndex:idx_group_members_group_resource;notNull;default:'';comment:分组 id" json:"group_id"`             // 分组 id
+	ResourceType string   `gorm:"column:resource_type;uniqueIndex:idx_group_members_group_resource;notNull;default:'';comment:资源类型" json:"resource_type"`    // 资源类型
+	ResourceID   string   `gorm:"column:resource_id;uniqueIndex:idx_group_members_group_resource;index;notNull;default:'';comment:资源 id" json:"resource_id"` // 资源 id
+}
+
+// TableName database table name
+func (*Member) TableName() string {
+	return "group_members"
+}
@@ -0,0 +1,46 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package group
+
+import (
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/bz"
+)
+
+type AddGroupInput struct {
+	ParentID  string `json:"parent_id"`               // 上级分组 id，为空表示根节点
+	Name      string `json:"name" binding:"required"` // 分组名称
+	ChannelID string `json:"channel_id"`              // 国标编码，为空时自动生成
+	Remark    string `json:"remark"`                  // 备注
+}
+
+type EditGroupInput struct {
+	Name   string `json:"name" binding:"required"` // 分组名称
+	Remark string `json:"remark"`                  // 备注
+}
+
+type MoveGroupInput struct {
+	ParentID string `json:"parent_id"` // 目标上级分组 id，为空表示根节点
+	Index    int    `json:"index"`     // 在同级中的位置，从 0 开始，小于 0 或超出范围时放在末尾
+}
+
+type FindMemberInput struct {
+	web.PagerFilter
+	ResourceType string              `form:"resource_type"` // 资源类型
+	Scopes       map[string]bz.Scope `form:"-" json:"-"`    // 数据权限，按资源类型区分
+}
+
+type AddMembersInput struct {
+	ResourceType string   `json:"resource_type" binding:"required"` // 资源类型 channel/push/proxy
+	ResourceIDs  []string `json:"resource_ids" binding:"required"`  // 资源 id
+}
+
+type DelMembersInput struct {
+	ResourceType string   `json:"resource_type" binding:"required"` // 资源类型 channel/push/proxy
+	ResourceIDs  []string `json:"resource_ids" binding:"required"`  // 资源 id
+}
+
+type MoveMembersInput struct {
+	TargetID     string   `json:"target_id" binding:"required"`     // 目标分组 id
+	ResourceType string   `json:"resource_type" binding:"required"` // 资源类型 channel/push/proxy
+	ResourceIDs  []string `json:"resource_ids" binding:"required"`  // 资源 id
+}
@@ -0,0 +1,79 @@
+package group
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"wvp/internal/core/bz"
+)
+
+func TestBuildTree(t *testing.T) {
+	groups := []*Group{
+		{ID: "b", Name: "B 栋", Sort: 1, ChannelID: "34020000002150000002"},
+		{ID: "a", Name: "A 栋", Sort: 0, ChannelID: "34020000002150000001"},
+		{ID: "a2", ParentID: "a", Name: "2F", Sort: 1, ChannelID: "34020000002160000002"},
+		{ID: "a1", ParentID: "a", Name: "1F", Sort: 0, ChannelID: "34020000002160000001"},
+		{ID: "x", ParentID: "missing", Name: "孤立", Sort: 2, ChannelID: "34020000002160000009"},
+	}
+	tree := BuildTree(groups)
+	if len(tree) != 3 || tree[0].ID != "a" || tree[1].ID != "b" || tree[2].ID != "x" {
+		t.Fatalf("unexpected roots %+v", tree)
+	}
+	if c := tree[0].Children; len(c) != 2 || c[0].ID != "a1" || c[1].ID != "a2" {
+		t.Fatalf("unexpected children %+v", c)
+	}
+
+	ids := Subtree(groups, "a")
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"a", "a1", "a2"}) {
+		t.Fatalf("unexpected subtree %v", ids)
+	}
+
+	nodes, parents := BuildCatalog(groups, []*Member{
+		{GroupID: "a2", ResourceType: "channel", ResourceID: "ch1"},
+		{GroupID: "a1", ResourceType: "channel", ResourceID: "ch1"},
+		{GroupID: "b", ResourceType: "channel", ResourceID: "ch2"},
+		{GroupID: "deleted", ResourceType: "channel", ResourceID: "ch3"},
+	})
+	if len(nodes) != 5 {
+		t.Fatalf("unexpected nodes %+v", nodes)
+	}
+	if n := nodes[1]; n.ChannelID != "34020000002160000001" || n.ParentID != "34020000002150000001" || n.BusinessGroupID != "34020000002150000001" {
+		t.Fatalf("unexpected virtual org %+v", n)
+	}
+	if p := parents["ch1"]; p.ParentID != "34020000002160000001" || p.BusinessGroupID != "34020000002150000001" {
+		t.Fatalf("unexpected channel parent %+v", p)
+	}
+	if p := parents["ch2"]; p.ParentID != "34020000002150000002" || p.BusinessGroupID != "34020000002150000002" {
+		t.Fatalf("unexpected channel parent %+v", p)
+	}
+	if _, ok := parents["ch3"]; ok {
+		t.Fatal("member of deleted group should be ignored")
+	}
+}
+
+func TestRetype(t *testing.T) {
+	if v := retype("34020000002150000001", TypeVirtualOrg); v != "34020000002160000001" {
+		t.Fatalf("unexpected %s", v)
+	}
+	if v := retype("34020000001320000001", TypeVirtualOrg); v != "34020000001320000001" {
+		t.Fatalf("custom code should not change, got %s", v)
+	}
+}
+
+func TestScopeCond(t *testing.T) {
+	if cond, _ := scopeCond(map[string]bz.Scope{bz.ResourceChannel: {}}); cond != "" {
+		t.Fatalf("unlimited scope should not filter, got %q", cond)
+	}
+	cond, args := scopeCond(map[string]bz.Scope{
+		bz.ResourceChannel: {Limited: true, IDs: []string{"ch1"}, ParentIDs: []string{"dev1"}},
+		bz.ResourcePush:    {Limited: true},
+	})
+	if n := strings.Count(cond, " OR "); n != 3 {
+		t.Fatalf("unexpected cond %q", cond)
+	}
+	if len(args) != 7 || args[len(args)-1] != bz.ResourceProxy {
+		t.Fatalf("unexpected args %v", args)
+	}
+}
@@ -0,0 +1,43 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package groupdb
+
+import (
+	"gorm.io/gorm"
+	"wvp/internal/core/group"
+)
+
+var _ group.Storer = DB{}
+
+// DB Related business namespaces
+type DB struct {
+	db *gorm.DB
+}
+
+// NewDB instance object
+func NewDB(db *gorm.DB) DB {
+	return DB{db: db}
+}
+
+// Group Get business instance
+func (d DB) Group() group.GroupStorer {
+	return Group(d)
+}
+
+// Member Get business instance
+func (d DB) Member() group.MemberStorer {
+	return Member(d)
+}
+
+// AutoMigrate sync database
+func (d DB) AutoMigrate(ok bool) DB {
+	if !ok {
+		return d
+	}
+	if err := d.db.AutoMigrate(
+		new(group.Group),
+		new(group.Member),
+	); err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -0,0 +1,71 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package groupdb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/group"
+)
+
+var (
+	_ group.GroupStorer  = Group{}
+	_ group.MemberStorer = Member{}
+)
+
+// Group Related business namespaces
+type Group DB
+
+// NewGroup instance object
+func NewGroup(db *gorm.DB) Group {
+	return Group{db: db}
+}
+
+// Find implements group.GroupStorer.
+func (d Group) Find(ctx context.Context, bs *[]*group.Group, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Get implements group.GroupStorer.
+func (d Group) Get(ctx context.Context, model *group.Group, opts ...orm.QueryOption) error {
+	return orm.FirstWithContext(ctx, d.db, model, opts...)
+}
+
+// Add implements group.GroupStorer.
+func (d Group) Add(ctx context.Context, model *group.Group) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Edit implements group.GroupStorer.
+func (d Group) Edit(ctx context.Context, model *group.Group, changeFn func(*group.Group), opts ...orm.QueryOption) error {
+	return orm.UpdateWithContext(ctx, d.db, model, changeFn, opts...)
+}
+
+// Del implements group.GroupStorer.
+func (d Group) Del(ctx context.Context, model *group.Group, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
+
+// Member Related business namespaces
+type Member DB
+
+// NewMember instance object
+func NewMember(db *gorm.DB) Member {
+	return Member{db: db}
+}
+
+// Find implements group.MemberStorer.
+func (d Member) Find(ctx context.Context, bs *[]*group.Member, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Add implements group.MemberStorer.
+func (d Member) Add(ctx context.Context, models ...*group.Member) error {
+	return d.db.WithContext(ctx).Create(models).Error
+}
+
+// Del implements group.MemberStorer.
+func (d Member) Del(ctx context.Context, model *group.Member, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
@@ -0,0 +1,31 @@
+package groupdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ixugo/goweb/pkg/orm"
+	"wvp/internal/core/group"
+)
+
+func TestGroupGet(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userDB := NewGroup(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "groups" WHERE id=\$1 (.+) LIMIT \$2`).WithArgs("jack", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow("jack", "1F"))
+	var out group.Group
+	if err := userDB.Get(context.Background(), &out, orm.Where("id=?", "jack")); err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "1F" {
+		t.Fatalf("unexpected name %q", out.Name)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
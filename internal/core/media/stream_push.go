@@ -10,6 +10,7 @@ import (
 	"github.com/ixugo/goweb/pkg/orm"
 	"github.com/ixugo/goweb/pkg/web"
 	"github.com/jinzhu/copier"
+	"wvp/internal/core/audit"
 	"wvp/internal/core/bz"
 )
 
@@ -33,6 +34,9 @@ func (c Core) FindStreamPush(ctx context.Context, in *FindStreamPushInput) ([]*S
 	if in.Key != "" {
 		args = append(args, orm.Where("id=? OR app LIKE ? OR stream LIKE ?", in.Key, "%"+in.Key+"%", "%"+in.Key+"%"))
 	}
+	if in.Scope.Limited {
+		args = append(args, orm.Where("id IN ?", in.Scope.IDs))
+	}
 
 	total, err := c.store.StreamPush().Find(ctx, &items, in, args...)
 	if err != nil {
@@ -54,6 +58,19 @@ func (c Core) GetStreamPush(ctx context.Context, id string) (*StreamPush, error)
 	return &out, nil
 }
 
+// PlayStreamPush 播放推流，仅推流中可播放，并记录审计结果
+func (c Core) PlayStreamPush(ctx context.Context, id string) (*StreamPush, error) {
+	out, err := c.GetStreamPush(ctx, id)
+	if err == nil && out.Status != StatusPushing {
+		err = web.ErrNotFound.Msg("未推流")
+	}
+	audit.RecordResult(ctx, audit.ActionPlay, id, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c Core) GetStreamPushByAppStream(ctx context.Context, app, stream string) (*StreamPush, error) {
 	var out StreamPush
 	if err := c.store.StreamPush().Get(ctx, &out, orm.Where("app=? AND stream=?", app, stream)); err != nil {
@@ -100,6 +117,7 @@ func (c Core) EditStreamPush(ctx context.Context, in *EditStreamPushInput, id st
 
 // DelStreamPush Delete object
 func (c *Core) DelStreamPush(ctx context.Context, id string) (*StreamPush, error) {
+	audit.Record(ctx, audit.ActionStreamPushDelete, id, nil)
 	// 检查数据库
 	// 如果是推流中，需要先让 sms 停止推流
 	// TODO: 待实现国标相关，删除国标相关数据
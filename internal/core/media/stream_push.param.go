@@ -3,6 +3,7 @@ package media
 
 import (
 	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/bz"
 )
 
 type FindStreamPushInput struct {
@@ -13,8 +14,9 @@ type FindStreamPushInput struct {
 	// Stream        string    `form:"stream"`          // 流 ID
 	// MediaServerID string    `form:"media_server_id"` // 媒体服务器 ID
 	// ServerID      string    `form:"server_id"`       // 服务器 ID
-	Status string `form:"status"` // 推流状态(PUSHING)
-	Key    string `form:"key"`
+	Status string   `form:"status"` // 推流状态(PUSHING)
+	Key    string   `form:"key"`
+	Scope  bz.Scope `form:"-" json:"-"` // 数据权限
 }
 
 type EditStreamPushInput struct {
@@ -0,0 +1,21 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notify
+
+import "wvp/internal/core/uniqueid"
+
+// Storer data persistence
+type Storer interface {
+	Subscription() SubscriptionStorer
+	Delivery() DeliveryStorer
+}
+
+// Core business domain
+type Core struct {
+	store    Storer
+	uniqueID uniqueid.Core
+}
+
+// NewCore create business domain
+func NewCore(store Storer, uni uniqueid.Core) Core {
+	return Core{store: store, uniqueID: uni}
+}
@@ -0,0 +1,58 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notify
+
+import (
+	"context"
+	"time"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+)
+
+// DeliveryStorer Instantiation interface
+type DeliveryStorer interface {
+	Find(context.Context, *[]*Delivery, orm.Pager, ...orm.QueryOption) (int64, error)
+	Add(context.Context, *Delivery) error
+	Del(context.Context, *Delivery, ...orm.QueryOption) error
+}
+
+// FindDelivery Paginated search
+func (c Core) FindDelivery(ctx context.Context, in *FindDeliveryInput) ([]*Delivery, int64, error) {
+	items := make([]*Delivery, 0)
+
+	query := orm.NewQuery(4)
+	query.OrderBy("id DESC")
+	query.Where("subscription_id=?", in.SubscriptionID)
+	if in.EventID != "" {
+		query.Where("event_id=?", in.EventID)
+	}
+	if in.EventType != "" {
+		query.Where("event_type=?", in.EventType)
+	}
+	if in.Success != nil {
+		query.Where("success=?", *in.Success)
+	}
+
+	total, err := c.store.Delivery().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// AddDelivery 记录投递结果
+func (c Core) AddDelivery(ctx context.Context, in *Delivery) error {
+	in.CreatedAt = orm.Now()
+	if err := c.store.Delivery().Add(ctx, in); err != nil {
+		return web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return nil
+}
+
+// DelExpiredDelivery 删除指定时间之前的投递记录
+func (c Core) DelExpiredDelivery(ctx context.Context, before time.Time) error {
+	if err := c.store.Delivery().Del(ctx, new(Delivery), orm.Where("created_at<?", before)); err != nil {
+		return web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notify
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// Delivery domain model，每次投递尝试记录一条
+type Delivery struct {
+	ID             int64    `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt      orm.Time `gorm:"column:created_at;index;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:投递时间" json:"created_at"` // 投递时间
+	SubscriptionID string   `gorm:"column:subscription_id;index;notNull;default:'';comment:订阅 id" json:"subscription_id"`                    // 订阅 id
+	EventID        string   `gorm:"column:event_id;index;notNull;default:'';comment:事件 id" json:"event_id"`                                  // 事件 id
+	EventType      string   `gorm:"column:event_type;notNull;default:'';comment:事件类型" json:"event_type"`                                     // 事件类型
+	URL            string   `gorm:"column:url;notNull;default:'';comment:回调地址" json:"url"`                                                   // 回调地址
+	Payload        string   `gorm:"column:payload;notNull;default:'';comment:请求内容" json:"payload"`                                           // 请求内容
+	Attempt        int      `gorm:"column:attempt;notNull;default:0;comment:第几次尝试" json:"attempt"`                                           // 第几次尝试
+	StatusCode     int      `gorm:"column:status_code;notNull;default:0;comment:响应状态码" json:"status_code"`                                   // 响应状态码
+	Response       string   `gorm:"column:response;notNull;default:'';comment:响应内容" json:"response"`                                         // 响应内容，超长截断
+	Error          string   `gorm:"column:error;notNull;default:'';comment:错误信息" json:"error"`                                               // 错误信息
+	Success        bool     `gorm:"column:success;notNull;default:FALSE;comment:是否成功" json:"success"`                                        // 是否成功
+	DurationMs     int64    `gorm:"column:duration_ms;notNull;default:0;comment:耗时(毫秒)" json:"duration_ms"`                                  // 耗时(毫秒)
+}
+
+// TableName database table name
+func (*Delivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,12 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notify
+
+import "github.com/ixugo/goweb/pkg/web"
+
+type FindDeliveryInput struct {
+	web.PagerFilter
+	SubscriptionID string `form:"-"`
+	EventID        string `form:"event_id"`   // 事件 id
+	EventType      string `form:"event_type"` // 事件类型
+	Success        *bool  `form:"success"`    // 是否成功
+}
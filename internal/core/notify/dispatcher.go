@@ -0,0 +1,244 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"wvp/internal/core/event"
+)
+
+// 回调请求头
+const (
+	HeaderSubscription = "X-Webhook-Id"        // 订阅 id
+	HeaderEvent        = "X-Webhook-Event"     // 事件类型
+	HeaderDelivery     = "X-Webhook-Delivery"  // 事件 id，重试时不变，可用于去重
+	HeaderTimestamp    = "X-Webhook-Timestamp" // 签名时间戳，unix 秒
+	HeaderSignature    = "X-Webhook-Signature" // sha256=hex(hmac_sha256(secret, timestamp + "." + body))
+)
+
+const (
+	backoffBase   = 2 * time.Second
+	backoffMax    = 5 * time.Minute
+	queueSize     = 1024
+	maxRespLength = 1024
+	// reloadInterval 兜底刷新订阅的间隔，增删改订阅时会立即刷新
+	reloadInterval = time.Minute
+	cleanInterval  = time.Hour
+)
+
+// DispatcherConfig 投递配置
+type DispatcherConfig struct {
+	Workers     int           // 并发投递数
+	MaxAttempts int           // 最大尝试次数，包含首次投递
+	Timeout     time.Duration // 单次请求超时
+	Retention   time.Duration // 投递记录保留时长，0 表示永久保留
+}
+
+type job struct {
+	sub     *Subscription
+	event   event.Event
+	body    []byte
+	attempt int
+}
+
+// Dispatcher 将事件投递到订阅地址，失败后按指数退避重试
+type Dispatcher struct {
+	core   Core
+	cfg    DispatcherConfig
+	client *http.Client
+	queue  chan *job
+
+	mu   sync.RWMutex
+	subs []*Subscription
+}
+
+// NewDispatcher 创建投递器，需调用 Start 启动
+func NewDispatcher(core Core, cfg DispatcherConfig) *Dispatcher {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return &Dispatcher{
+		core:   core,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		queue:  make(chan *job, queueSize),
+	}
+}
+
+// Start 启动投递协程
+func (d *Dispatcher) Start(ctx context.Context) {
+	if err := d.Reload(ctx); err != nil {
+		slog.Error("加载事件订阅失败", "err", err)
+	}
+	for range d.cfg.Workers {
+		go d.work(ctx)
+	}
+	go d.tick(ctx)
+}
+
+// Reload 重新加载启用的订阅
+func (d *Dispatcher) Reload(ctx context.Context) error {
+	subs, err := d.core.FindEnabledSubscription(ctx)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.subs = subs
+	d.mu.Unlock()
+	return nil
+}
+
+// Handle 处理事件总线的事件，不阻塞
+func (d *Dispatcher) Handle(e event.Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, sub := range d.subs {
+		if sub.Match(e.Type) {
+			d.Send(sub, e)
+		}
+	}
+}
+
+// Send 投递事件到指定订阅
+func (d *Dispatcher) Send(sub *Subscription, e event.Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		slog.Error("事件序列化失败", "type", e.Type, "err", err)
+		return
+	}
+	d.enqueue(&job{sub: sub, event: e, body: body, attempt: 1})
+}
+
+func (d *Dispatcher) enqueue(j *job) {
+	select {
+	case d.queue <- j:
+	default:
+		slog.Warn("事件投递队列已满，丢弃事件", "subscription_id", j.sub.ID, "event_id", j.event.ID, "type", j.event.Type)
+	}
+}
+
+func (d *Dispatcher) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case j := <-d.queue:
+			if ok := d.deliver(ctx, j); !ok && j.attempt < d.cfg.MaxAttempts {
+				next := *j
+				next.attempt++
+				time.AfterFunc(backoff(j.attempt), func() { d.enqueue(&next) })
+			}
+		}
+	}
+}
+
+// deliver 执行一次投递并记录结果
+func (d *Dispatcher) deliver(ctx context.Context, j *job) bool {
+	log := Delivery{
+		SubscriptionID: j.sub.ID,
+		EventID:        j.event.ID,
+		EventType:      j.event.Type,
+		URL:            j.sub.URL,
+		Payload:        string(j.body),
+		Attempt:        j.attempt,
+	}
+	start := time.Now()
+	code, resp, err := d.post(ctx, j)
+	log.DurationMs = time.Since(start).Milliseconds()
+	log.StatusCode = code
+	log.Response = resp
+	if err != nil {
+		log.Error = err.Error()
+	}
+	log.Success = err == nil && code >= 200 && code < 300
+
+	if err := d.core.AddDelivery(context.Background(), &log); err != nil {
+		slog.Error("记录事件投递失败", "err", err)
+	}
+	if !log.Success {
+		slog.Warn("事件投递失败", "subscription_id", j.sub.ID, "event_id", j.event.ID, "attempt", j.attempt, "code", code, "err", err)
+	}
+	return log.Success
+}
+
+func (d *Dispatcher) post(ctx context.Context, j *job) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.sub.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, "", err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "gowvp-webhook")
+	req.Header.Set(HeaderSubscription, j.sub.ID)
+	req.Header.Set(HeaderEvent, j.event.Type)
+	req.Header.Set(HeaderDelivery, j.event.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(j.sub.Secret, ts, j.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, maxRespLength))
+	return resp.StatusCode, string(b), nil
+}
+
+// tick 定时刷新订阅并清理过期投递记录
+func (d *Dispatcher) tick(ctx context.Context) {
+	reload := time.NewTicker(reloadInterval)
+	defer reload.Stop()
+	clean := time.NewTicker(cleanInterval)
+	defer clean.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload.C:
+			if err := d.Reload(ctx); err != nil {
+				slog.Error("加载事件订阅失败", "err", err)
+			}
+		case <-clean.C:
+			if d.cfg.Retention <= 0 {
+				continue
+			}
+			if err := d.core.DelExpiredDelivery(ctx, time.Now().Add(-d.cfg.Retention)); err != nil {
+				slog.Error("清理事件投递记录失败", "err", err)
+			}
+		}
+	}
+}
+
+// Sign 计算回调签名，接收方使用相同方式校验
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff 第 attempt 次失败后的等待时长
+func backoff(attempt int) time.Duration {
+	d := backoffBase << (attempt - 1)
+	if d <= 0 || d > backoffMax {
+		return backoffMax
+	}
+	return d
+}
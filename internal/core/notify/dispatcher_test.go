@@ -0,0 +1,49 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		1:  2 * time.Second,
+		2:  4 * time.Second,
+		5:  32 * time.Second,
+		20: backoffMax,
+		80: backoffMax,
+	}
+	for attempt, expect := range cases {
+		if got := backoff(attempt); got != expect {
+			t.Fatalf("attempt[%d] expect[%s] got[%s]", attempt, expect, got)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	a := Sign("secret", 1700000000, []byte(`{"id":"1"}`))
+	if a != Sign("secret", 1700000000, []byte(`{"id":"1"}`)) {
+		t.Fatal("sign should be stable")
+	}
+	if a == Sign("secret", 1700000001, []byte(`{"id":"1"}`)) {
+		t.Fatal("timestamp should be signed")
+	}
+	if len(a) != 64 {
+		t.Fatalf("expect hex sha256, got[%s]", a)
+	}
+}
+
+func TestSubscriptionMatch(t *testing.T) {
+	s := Subscription{Enabled: true}
+	if !s.Match("alarm") {
+		t.Fatal("empty events should match all")
+	}
+	s.Events = Strings{"device.online"}
+	if s.Match("alarm") || !s.Match("device.online") {
+		t.Fatal("unexpected match")
+	}
+	s.Enabled = false
+	if s.Match("device.online") {
+		t.Fatal("disabled subscription should not match")
+	}
+}
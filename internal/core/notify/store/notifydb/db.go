@@ -0,0 +1,43 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notifydb
+
+import (
+	"gorm.io/gorm"
+	"wvp/internal/core/notify"
+)
+
+var _ notify.Storer = DB{}
+
+// DB Related business namespaces
+type DB struct {
+	db *gorm.DB
+}
+
+// NewDB instance object
+func NewDB(db *gorm.DB) DB {
+	return DB{db: db}
+}
+
+// Subscription Get business instance
+func (d DB) Subscription() notify.SubscriptionStorer {
+	return Subscription(d)
+}
+
+// Delivery Get business instance
+func (d DB) Delivery() notify.DeliveryStorer {
+	return Delivery(d)
+}
+
+// AutoMigrate sync database
+func (d DB) AutoMigrate(ok bool) DB {
+	if !ok {
+		return d
+	}
+	if err := d.db.AutoMigrate(
+		new(notify.Subscription),
+		new(notify.Delivery),
+	); err != nil {
+		panic(err)
+	}
+	return d
+}
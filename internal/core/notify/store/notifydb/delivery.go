@@ -0,0 +1,35 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notifydb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/notify"
+)
+
+var _ notify.DeliveryStorer = Delivery{}
+
+// Delivery Related business namespaces
+type Delivery DB
+
+// NewDelivery instance object
+func NewDelivery(db *gorm.DB) Delivery {
+	return Delivery{db: db}
+}
+
+// Find implements notify.DeliveryStorer.
+func (d Delivery) Find(ctx context.Context, bs *[]*notify.Delivery, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Add implements notify.DeliveryStorer.
+func (d Delivery) Add(ctx context.Context, model *notify.Delivery) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Del implements notify.DeliveryStorer.
+func (d Delivery) Del(ctx context.Context, model *notify.Delivery, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
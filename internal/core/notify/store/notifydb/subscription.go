@@ -0,0 +1,45 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notifydb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/notify"
+)
+
+var _ notify.SubscriptionStorer = Subscription{}
+
+// Subscription Related business namespaces
+type Subscription DB
+
+// NewSubscription instance object
+func NewSubscription(db *gorm.DB) Subscription {
+	return Subscription{db: db}
+}
+
+// Find implements notify.SubscriptionStorer.
+func (d Subscription) Find(ctx context.Context, bs *[]*notify.Subscription, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Get implements notify.SubscriptionStorer.
+func (d Subscription) Get(ctx context.Context, model *notify.Subscription, opts ...orm.QueryOption) error {
+	return orm.FirstWithContext(ctx, d.db, model, opts...)
+}
+
+// Add implements notify.SubscriptionStorer.
+func (d Subscription) Add(ctx context.Context, model *notify.Subscription) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Edit implements notify.SubscriptionStorer.
+func (d Subscription) Edit(ctx context.Context, model *notify.Subscription, changeFn func(*notify.Subscription), opts ...orm.QueryOption) error {
+	return orm.UpdateWithContext(ctx, d.db, model, changeFn, opts...)
+}
+
+// Del implements notify.SubscriptionStorer.
+func (d Subscription) Del(ctx context.Context, model *notify.Subscription, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
@@ -0,0 +1,31 @@
+package notifydb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ixugo/goweb/pkg/orm"
+	"wvp/internal/core/notify"
+)
+
+func TestSubscriptionGet(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	subDB := NewSubscription(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "webhook_subscriptions" WHERE id=\$1 (.+) LIMIT \$2`).WithArgs("wh1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "events", "enabled"}).AddRow("wh1", "http://127.0.0.1/hook", `["alarm"]`, true))
+	var out notify.Subscription
+	if err := subDB.Get(context.Background(), &out, orm.Where("id=?", "wh1")); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Match("alarm") || out.Match("device.online") {
+		t.Fatalf("unexpected events %v", out.Events)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
@@ -0,0 +1,112 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"slices"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"github.com/jinzhu/copier"
+	"wvp/internal/core/bz"
+	"wvp/internal/core/event"
+)
+
+// SubscriptionStorer Instantiation interface
+type SubscriptionStorer interface {
+	Find(context.Context, *[]*Subscription, orm.Pager, ...orm.QueryOption) (int64, error)
+	Get(context.Context, *Subscription, ...orm.QueryOption) error
+	Add(context.Context, *Subscription) error
+	Edit(context.Context, *Subscription, func(*Subscription), ...orm.QueryOption) error
+	Del(context.Context, *Subscription, ...orm.QueryOption) error
+}
+
+// FindSubscription Paginated search
+func (c Core) FindSubscription(ctx context.Context, in *FindSubscriptionInput) ([]*Subscription, int64, error) {
+	items := make([]*Subscription, 0)
+	total, err := c.store.Subscription().Find(ctx, &items, in, orm.OrderBy("created_at DESC"))
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// FindEnabledSubscription 查询全部启用的订阅
+func (c Core) FindEnabledSubscription(ctx context.Context) ([]*Subscription, error) {
+	items := make([]*Subscription, 0, 8)
+	if _, err := c.store.Subscription().Find(ctx, &items, web.NewPagerFilterMaxSize(), orm.Where("enabled=?", true)); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, nil
+}
+
+// GetSubscription Query a single object
+func (c Core) GetSubscription(ctx context.Context, id string) (*Subscription, error) {
+	var out Subscription
+	if err := c.store.Subscription().Get(ctx, &out, orm.Where("id=?", id)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrNotFound.Withf(`Get err[%s]`, err.Error())
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// AddSubscription Insert into database
+func (c Core) AddSubscription(ctx context.Context, in *AddSubscriptionInput) (*AddSubscriptionOutput, error) {
+	if err := checkSubscription(in.URL, in.Events); err != nil {
+		return nil, err
+	}
+	var out Subscription
+	if err := copier.Copy(&out, in); err != nil {
+		slog.Error("Copy", "err", err)
+	}
+	if out.Secret == "" {
+		out.Secret = orm.GenerateRandomString(32)
+	}
+	out.ID = c.uniqueID.UniqueID(bz.IDPrefixWebhook)
+	if err := c.store.Subscription().Add(ctx, &out); err != nil {
+		return nil, web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return &AddSubscriptionOutput{Subscription: &out, Secret: out.Secret}, nil
+}
+
+// EditSubscription Update object information
+func (c Core) EditSubscription(ctx context.Context, in *EditSubscriptionInput, id string) (*Subscription, error) {
+	if err := checkSubscription(in.URL, in.Events); err != nil {
+		return nil, err
+	}
+	var out Subscription
+	if err := c.store.Subscription().Edit(ctx, &out, func(b *Subscription) {
+		if err := copier.Copy(b, in); err != nil {
+			slog.Error("Copy", "err", err)
+		}
+	}, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Edit err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// DelSubscription Delete object
+func (c Core) DelSubscription(ctx context.Context, id string) (*Subscription, error) {
+	var out Subscription
+	if err := c.store.Subscription().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+func checkSubscription(rawURL string, events []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return web.ErrBadRequest.Msg("回调地址格式错误，仅支持 http/https")
+	}
+	for _, v := range events {
+		if !slices.Contains(event.Types, v) {
+			return web.ErrBadRequest.Msg("不支持的事件类型: " + v)
+		}
+	}
+	return nil
+}
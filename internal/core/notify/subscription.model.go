@@ -0,0 +1,30 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notify
+
+import (
+	"slices"
+
+	"github.com/ixugo/goweb/pkg/orm"
+)
+
+// Subscription domain model
+type Subscription struct {
+	ID        string   `gorm:"primaryKey" json:"id"`
+	CreatedAt orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"` // 创建时间
+	UpdatedAt orm.Time `gorm:"column:updated_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"` // 更新时间
+	Name      string   `gorm:"column:name;notNull;default:'';comment:名称" json:"name"`                                             // 名称
+	URL       string   `gorm:"column:url;notNull;default:'';comment:回调地址" json:"url"`                                             // 回调地址
+	Events    Strings  `gorm:"column:events;notNull;type:JSON;comment:订阅的事件类型，为空订阅全部" json:"events"`                              // 订阅的事件类型
+	Secret    string   `gorm:"column:secret;notNull;default:'';comment:签名秘钥" json:"-"`                                            // 签名秘钥
+	Enabled   bool     `gorm:"column:enabled;notNull;default:FALSE;comment:是否启用" json:"enabled"`                                  // 是否启用
+}
+
+// TableName database table name
+func (*Subscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Match 是否订阅了该事件
+func (s *Subscription) Match(typ string) bool {
+	return s.Enabled && (len(s.Events) == 0 || slices.Contains(s.Events, typ))
+}
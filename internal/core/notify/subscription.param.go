@@ -0,0 +1,28 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package notify
+
+import "github.com/ixugo/goweb/pkg/web"
+
+type FindSubscriptionInput struct {
+	web.PagerFilter
+}
+
+type EditSubscriptionInput struct {
+	Name    string   `json:"name"`                   // 名称
+	URL     string   `json:"url" binding:"required"` // 回调地址
+	Events  []string `json:"events"`                 // 订阅的事件类型，为空订阅全部
+	Enabled bool     `json:"enabled"`                // 是否启用
+}
+
+type AddSubscriptionInput struct {
+	Name    string   `json:"name"`                   // 名称
+	URL     string   `json:"url" binding:"required"` // 回调地址
+	Events  []string `json:"events"`                 // 订阅的事件类型，为空订阅全部
+	Secret  string   `json:"secret"`                 // 签名秘钥，为空时随机生成
+	Enabled bool     `json:"enabled"`                // 是否启用
+}
+
+type AddSubscriptionOutput struct {
+	*Subscription
+	Secret string `json:"secret"` // 签名秘钥，仅创建时返回
+}
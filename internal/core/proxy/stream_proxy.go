@@ -8,6 +8,7 @@ import (
 	"github.com/ixugo/goweb/pkg/orm"
 	"github.com/ixugo/goweb/pkg/web"
 	"github.com/jinzhu/copier"
+	"wvp/internal/core/audit"
 	"wvp/internal/core/bz"
 )
 
@@ -22,8 +23,11 @@ type StreamProxyStorer interface {
 
 // FindStreamProxy Paginated search
 func (c *Core) FindStreamProxy(ctx context.Context, in *FindStreamProxyInput) ([]*StreamProxy, int64, error) {
-	query := orm.NewQuery(1)
+	query := orm.NewQuery(2)
 	query.OrderBy("created_at desc")
+	if in.Scope.Limited {
+		query.Where("id IN ?", in.Scope.IDs)
+	}
 
 	items := make([]*StreamProxy, 0)
 	total, err := c.store.StreamProxy().Find(ctx, &items, in, query.Encode()...)
@@ -77,6 +81,24 @@ func (c *Core) EditStreamProxy(ctx context.Context, in *EditStreamProxyInput, id
 	return &out, nil
 }
 
+// PlayStreamProxy 播放拉流代理，pull 通知媒体服务器开始拉流并返回流的 key，并记录审计结果
+func (c *Core) PlayStreamProxy(ctx context.Context, id string, pull func(*StreamProxy) (string, error)) (*StreamProxy, error) {
+	out, err := c.GetStreamProxy(ctx, id)
+	if err == nil {
+		var key string
+		if key, err = pull(out); err == nil {
+			if _, err := c.EditStreamProxyKey(ctx, key, id); err != nil {
+				slog.Error("保存拉流代理 key 失败", "id", id, "err", err)
+			}
+		}
+	}
+	audit.RecordResult(ctx, audit.ActionPlay, id, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *Core) EditStreamProxyKey(ctx context.Context, streamKey, id string) (*StreamProxy, error) {
 	var out StreamProxy
 	if err := c.store.StreamProxy().Edit(ctx, &out, func(b *StreamProxy) {
@@ -89,9 +111,36 @@ func (c *Core) EditStreamProxyKey(ctx context.Context, streamKey, id string) (*S
 
 // DelStreamProxy Delete object
 func (c *Core) DelStreamProxy(ctx context.Context, id string) (*StreamProxy, error) {
+	audit.Record(ctx, audit.ActionStreamProxyDelete, id, nil)
 	var out StreamProxy
 	if err := c.store.StreamProxy().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
 		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
 	}
 	return &out, nil
 }
+
+// SetPulling 更新拉流状态，状态未变化时返回 false
+func (c *Core) SetPulling(ctx context.Context, app, stream string, pulling bool) (*StreamProxy, bool, error) {
+	var out StreamProxy
+	var changed bool
+	if err := c.store.StreamProxy().Edit(ctx, &out, func(b *StreamProxy) {
+		changed = b.Pulling != pulling
+		b.Pulling = pulling
+	}, orm.Where("app=? AND stream=?", app, stream)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, false, web.ErrNotFound.Withf(`Edit err[%s]`, err.Error())
+		}
+		return nil, false, web.ErrDB.Withf(`Edit err[%s]`, err.Error())
+	}
+	return &out, changed, nil
+}
+
+// CountPulling 拉流中的代理数量
+func (c *Core) CountPulling(ctx context.Context) (int64, error) {
+	items := make([]*StreamProxy, 0, 1)
+	total, err := c.store.StreamProxy().Find(ctx, &items, web.PagerFilter{Page: 1, Size: 1}, orm.Where("pulling=?", true))
+	if err != nil {
+		return 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return total, nil
+}
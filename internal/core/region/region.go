@@ -0,0 +1,186 @@
+// Package region 行政区划，国标编码前 2~8 位为设备所属的行政区划编码
+package region
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//go:embed civil_code.csv
+var builtin []byte
+
+// 行政区划级别，按编码长度区分
+const (
+	LevelProvince = 1 // 省级，2 位
+	LevelCity     = 2 // 市级，4 位
+	LevelDistrict = 3 // 县级，6 位
+	LevelBasic    = 4 // 基层接入单位，8 位
+)
+
+// Region 行政区划
+type Region struct {
+	Code       string `json:"code"`        // 行政区划编码
+	Name       string `json:"name"`        // 名称
+	Level      int    `json:"level"`       // 级别
+	ParentCode string `json:"parent_code"` // 上级编码，省级为空
+	Leaf       bool   `json:"leaf"`        // 是否没有下级
+}
+
+// ErrUnknownCode 行政区划编码未登记
+var ErrUnknownCode = errors.New("civil code not registered")
+
+// Registry 行政区划数据，加载后只读
+type Registry struct {
+	items    map[string]*Region
+	children map[string][]*Region
+	missing  sync.Map // 已告警的未登记编码
+}
+
+// NewRegistry 加载数据文件，path 为空时使用内置数据，内置数据仅包含全部省级与部分市、县级
+func NewRegistry(path string) (*Registry, error) {
+	if path == "" {
+		return Load(bytes.NewReader(builtin))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+// Load 读取数据，每行 编码,名称，# 开头为注释
+// 上级按编码前缀确定，缺少上级时挂在最近一级存在的上级下
+func Load(r io.Reader) (*Registry, error) {
+	reg := Registry{
+		items:    make(map[string]*Region, 64),
+		children: make(map[string][]*Region, 64),
+	}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		code, name, ok := strings.Cut(line, ",")
+		code, name = strings.TrimSpace(code), strings.TrimSpace(name)
+		if !ok || Level(code) == 0 || name == "" {
+			return nil, fmt.Errorf("civil code line %d invalid: %q", n, line)
+		}
+		reg.items[code] = &Region{Code: code, Name: name, Level: Level(code)}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for code, v := range reg.items {
+		v.ParentCode = reg.match(code[:len(code)-2])
+		reg.children[v.ParentCode] = append(reg.children[v.ParentCode], v)
+	}
+	for _, v := range reg.items {
+		v.Leaf = len(reg.children[v.Code]) == 0
+	}
+	for _, items := range reg.children {
+		slices.SortFunc(items, func(a, b *Region) int { return strings.Compare(a.Code, b.Code) })
+	}
+	return &reg, nil
+}
+
+// Level 编码对应的级别，非法编码返回 0
+func Level(code string) int {
+	n := len(code)
+	if n == 0 || n > 8 || n%2 != 0 {
+		return 0
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return 0
+		}
+	}
+	return n / 2
+}
+
+// match 最长的已登记编码前缀
+func (r *Registry) match(code string) string {
+	for n := min(len(code), 8) &^ 1; n >= 2; n -= 2 {
+		if _, ok := r.items[code[:n]]; ok {
+			return code[:n]
+		}
+	}
+	return ""
+}
+
+// Get 查询行政区划
+func (r *Registry) Get(code string) (Region, bool) {
+	v, ok := r.items[code]
+	if !ok {
+		return Region{}, false
+	}
+	return *v, true
+}
+
+// Children 下级行政区划，code 为空时返回省级
+func (r *Registry) Children(code string) []Region {
+	items := r.children[code]
+	out := make([]Region, len(items))
+	for i, v := range items {
+		out[i] = *v
+	}
+	return out
+}
+
+// Path 从省级到该行政区划的完整路径
+func (r *Registry) Path(code string) []Region {
+	out := make([]Region, 0, 4)
+	for v, ok := r.items[code]; ok; v, ok = r.items[v.ParentCode] {
+		out = append(out, *v)
+	}
+	slices.Reverse(out)
+	return out
+}
+
+// Code 国标编码前 8 位表示的行政区划编码，去掉末尾补位的 00，非法编码返回空串
+func Code(id string) string {
+	code := id[:min(len(id), 8)&^1]
+	for len(code) > 2 && strings.HasSuffix(code, "00") {
+		code = code[:len(code)-2]
+	}
+	if Level(code) == 0 || code == "00" {
+		return ""
+	}
+	return code
+}
+
+// Match 国标编码所属的行政区划，编码未登记时返回 ErrUnknownCode，不回退到上级
+func (r *Registry) Match(id string) (string, error) {
+	code := Code(id)
+	if code == "" {
+		return "", fmt.Errorf("%w: invalid id %q", ErrUnknownCode, id)
+	}
+	if _, ok := r.items[code]; !ok {
+		if _, loaded := r.missing.LoadOrStore(code, struct{}{}); !loaded {
+			slog.Warn("行政区划编码未登记，请通过 Sip.CivilCodeFile 加载完整数据", "code", code, "id", id)
+		}
+		return "", fmt.Errorf("%w: %s", ErrUnknownCode, code)
+	}
+	return code, nil
+}
+
+// FullName 完整名称，各级以 / 分隔
+func (r *Registry) FullName(code string) string {
+	path := r.Path(code)
+	names := make([]string, len(path))
+	for i, v := range path {
+		names[i] = v.Name
+	}
+	return strings.Join(names, "/")
+}
@@ -0,0 +1,68 @@
+package region
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuiltin(t *testing.T) {
+	r, err := NewRegistry("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(r.Children("")); n != 34 {
+		t.Fatalf("expect 34 provinces, got %d", n)
+	}
+	if v, err := r.Match("34020000001320000001"); err != nil || v != "3402" {
+		t.Fatalf("unexpected match %q %v", v, err)
+	}
+	if v := r.FullName("340207"); v != "安徽省/芜湖市/鸠江区" {
+		t.Fatalf("unexpected name %q", v)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	r, err := Load(strings.NewReader("# test\n34,安徽省\n340207,鸠江区\n3402,芜湖市\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, ok := r.Get("340207")
+	if !ok || v.ParentCode != "3402" || v.Level != LevelDistrict || !v.Leaf {
+		t.Fatalf("unexpected region %+v", v)
+	}
+	if p, _ := r.Get("34"); p.Leaf {
+		t.Fatal("province should have children")
+	}
+	if items := r.Children("34"); len(items) != 1 || items[0].Code != "3402" {
+		t.Fatalf("unexpected children %+v", items)
+	}
+	if _, err := r.Match("99000000001320000001"); !errors.Is(err, ErrUnknownCode) {
+		t.Fatalf("expect unknown code, got %v", err)
+	}
+	// 未登记的县级编码不回退到上级
+	if _, err := r.Match("34020800001320000001"); !errors.Is(err, ErrUnknownCode) {
+		t.Fatalf("expect unknown code, got %v", err)
+	}
+
+	if _, err := Load(strings.NewReader("340,x\n")); err == nil {
+		t.Fatal("expect invalid code error")
+	}
+}
+
+func TestCode(t *testing.T) {
+	cases := map[string]string{
+		"34020000001320000001": "3402",
+		"34020701001320000001": "34020701",
+		"3402070000":           "340207",
+		"11000000001320000001": "11",
+		"00000000001320000001": "",
+		"3x020000001320000001": "",
+		"3":                    "",
+	}
+	for id, expect := range cases {
+		if v := Code(id); v != expect {
+			t.Errorf("Code(%q) expect %q, got %q", id, expect, v)
+		}
+	}
+}
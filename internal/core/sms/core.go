@@ -1,9 +1,15 @@
 // Code generated by gowebx, DO AVOID EDIT.
 package sms
 
+import (
+	"wvp/internal/core/cluster"
+	"wvp/internal/core/event"
+)
+
 // Storer data persistence
 type Storer interface {
 	MediaServer() MediaServerStorer
+	SSRC() SSRCStorer
 }
 
 // Core business domain
@@ -13,10 +19,10 @@ type Core struct {
 }
 
 // NewCore create business domain
-func NewCore(store Storer) Core {
+func NewCore(store Storer, bus *event.Bus, c *cluster.Cluster) Core {
 	return Core{
 		storer: store,
 
-		NodeManager: NewNodeManager(store),
+		NodeManager: NewNodeManager(store, bus, c),
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/ixugo/goweb/pkg/orm"
 	"github.com/ixugo/goweb/pkg/web"
 	"github.com/jinzhu/copier"
+	"wvp/internal/core/audit"
 )
 
 // MediaServerStorer Instantiation interface
@@ -69,6 +70,7 @@ func (c *Core) EditMediaServer(ctx context.Context, in *EditMediaServerInput, id
 
 // DelMediaServer Delete object
 func (c *Core) DelMediaServer(ctx context.Context, id string) (*MediaServer, error) {
+	audit.Record(ctx, audit.ActionMediaServerDelete, id, nil)
 	var out MediaServer
 	if err := c.storer.MediaServer().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
 		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
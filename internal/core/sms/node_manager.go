@@ -2,20 +2,31 @@ package sms
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/ixugo/goweb/pkg/conc"
 	"github.com/ixugo/goweb/pkg/orm"
 	"github.com/ixugo/goweb/pkg/web"
 	"wvp/internal/conf"
+	"wvp/internal/core/cluster"
+	"wvp/internal/core/event"
 	"wvp/pkg/zlm"
 )
 
 type WarpMediaServer struct {
 	IsOnline      bool
 	LastUpdatedAt time.Time
+
+	mu        sync.Mutex
+	streams   map[string]struct{} // 节点上已注册的流 app/stream
+	hookToken string              // 回调地址携带的令牌
 }
 
 type NodeManager struct {
@@ -24,13 +35,23 @@ type NodeManager struct {
 	zlm          zlm.Engine
 	cacheServers conc.Map[string, *WarpMediaServer]
 	quit         chan struct{}
+	bus          *event.Bus
+	ssrc         *SSRCManager
+	cluster      *cluster.Cluster
 }
 
-func NewNodeManager(storer Storer) *NodeManager {
+// NewNodeManager c 为空时按单实例部署处理
+func NewNodeManager(storer Storer, bus *event.Bus, c *cluster.Cluster) *NodeManager {
+	if c == nil {
+		c = cluster.New(cluster.Config{}, nil)
+	}
 	n := NodeManager{
-		storer: storer,
-		zlm:    zlm.NewEngine(),
-		quit:   make(chan struct{}, 1),
+		storer:  storer,
+		bus:     bus,
+		zlm:     zlm.NewEngine(),
+		quit:    make(chan struct{}, 1),
+		ssrc:    NewSSRCManager(storer.SSRC(), c.Enabled()),
+		cluster: c,
 	}
 	go n.tickCheck()
 	return &n
@@ -52,15 +73,25 @@ func (n *NodeManager) tickCheck() {
 			// TODO: 前期先固定 10 秒保活，后期优化
 			const KeepaliveInterval = 2 * 10 * time.Second
 			n.cacheServers.Range(func(serverID string, ms *WarpMediaServer) bool {
-				IsOffline := time.Since(ms.LastUpdatedAt) >= KeepaliveInterval
+				IsOffline := time.Since(n.lastKeepalive(serverID, ms)) >= KeepaliveInterval
 				if ms.IsOnline == IsOffline {
 					ms.IsOnline = !IsOffline
+					if !ms.IsOnline {
+						ms.mu.Lock()
+						clear(ms.streams)
+						ms.mu.Unlock()
+					}
+					// 集群部署时由主节点更新状态并发布事件
+					if !n.cluster.IsLeader() {
+						return true
+					}
 					var svr MediaServer
 					if err := n.storer.MediaServer().Edit(context.Background(), &svr, func(b *MediaServer) {
 						b.Status = ms.IsOnline
 					}, orm.Where("id=?", serverID)); err != nil {
 						slog.Error("Edit MediaServer err", "err", err)
 					}
+					n.bus.Publish(event.TypeMediaServer, event.MediaServer{ID: serverID, IsOnline: ms.IsOnline})
 				}
 				return true
 			})
@@ -112,8 +143,10 @@ func (n *NodeManager) Run(cfg *conf.Media, serverPort int) error {
 }
 
 func (n *NodeManager) connection(server *MediaServer, serverPort int) {
+	token := HookToken(server.Secret)
 	n.cacheServers.Store(server.ID, &WarpMediaServer{
 		LastUpdatedAt: time.Now(),
+		hookToken:     token,
 	})
 
 	url := fmt.Sprintf("http://%s:%d", server.IP, server.Ports.HTTP)
@@ -160,23 +193,26 @@ func (n *NodeManager) connection(server *MediaServer, serverPort int) {
 		log.Info("ZLM 服务节点配置设置")
 
 		hookPrefix := fmt.Sprintf("http://%s:%d/webhook", server.HookIP, serverPort)
+		hook := func(name string) *string {
+			return zlm.NewString(fmt.Sprintf("%s/%s?token=%s", hookPrefix, name, token))
+		}
 		req := zlm.SetServerConfigRequest{
 			RtcExternIP:          zlm.NewString(server.IP),
 			GeneralMediaServerID: zlm.NewString(server.ID),
 			HookEnable:           zlm.NewString("1"),
 			HookOnFlowReport:     zlm.NewString(""),
-			HookOnPlay:           zlm.NewString(fmt.Sprintf("%s/on_play", hookPrefix)),
+			HookOnPlay:           hook("on_play"),
 			// HookOnHTTPAccess:     zlm.NewString(""),
-			HookOnPublish:          zlm.NewString(fmt.Sprintf("%s/on_publish", hookPrefix)),
-			HookOnStreamNoneReader: zlm.NewString(fmt.Sprintf("%s/on_stream_none_reader", hookPrefix)),
+			HookOnPublish:          hook("on_publish"),
+			HookOnStreamNoneReader: hook("on_stream_none_reader"),
 			HookOnRecordTs:         zlm.NewString(""),
-			HookOnRtspAuth:         zlm.NewString(""),
-			HookOnRtspRealm:        zlm.NewString(""),
+			HookOnRtspAuth:         hook("on_rtsp_auth"),
+			HookOnRtspRealm:        hook("on_rtsp_realm"),
 			// HookOnServerStarted: ,
 			HookOnShellLogin:    zlm.NewString(""),
-			HookOnStreamChanged: zlm.NewString(fmt.Sprintf("%s/on_stream_changed", hookPrefix)),
+			HookOnStreamChanged: hook("on_stream_changed"),
 			// HookOnStreamNotFound: ,
-			HookOnServerKeepalive: zlm.NewString(fmt.Sprintf("%s/on_server_keepalive", hookPrefix)),
+			HookOnServerKeepalive: hook("on_server_keepalive"),
 			// HookOnSendRtpStopped: ,
 			// HookOnRtpServerTimeout: ,
 			// HookOnRecordMp4: ,
@@ -206,12 +242,92 @@ func (n *NodeManager) connection(server *MediaServer, serverPort int) {
 	}
 }
 
+// HookToken 媒体服务器回调令牌，由媒体服务器密钥派生，避免密钥出现在回调地址中
+func HookToken(secret string) string {
+	sum := sha256.Sum256([]byte("webhook:" + secret))
+	return hex.EncodeToString(sum[:16])
+}
+
+// VerifyHook 回调令牌是否属于已连接的媒体服务器
+func (n *NodeManager) VerifyHook(token string) bool {
+	var ok bool
+	n.cacheServers.Range(func(_ string, ms *WarpMediaServer) bool {
+		ok = token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(ms.hookToken)) == 1
+		return !ok
+	})
+	return ok
+}
+
 func (n *NodeManager) Keepalive(serverID string) {
 	value, ok := n.cacheServers.Load(serverID)
 	if !ok {
 		return
 	}
 	value.LastUpdatedAt = time.Now()
+
+	// 媒体服务器仅回调其中一个实例，集群部署时共享心跳时间
+	if n.cluster.Enabled() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		v := strconv.FormatInt(value.LastUpdatedAt.UnixMilli(), 10)
+		if err := n.cluster.Backend().Set(ctx, keepaliveKey(serverID), v, time.Minute); err != nil {
+			slog.Error("共享媒体服务器心跳失败", "err", err, "id", serverID)
+		}
+	}
+}
+
+func keepaliveKey(serverID string) string {
+	return "sms_keepalive:" + serverID
+}
+
+// lastKeepalive 本实例与集群共享的心跳时间中较新的一个
+func (n *NodeManager) lastKeepalive(serverID string, ms *WarpMediaServer) time.Time {
+	last := ms.LastUpdatedAt
+	if !n.cluster.Enabled() {
+		return last
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	v, err := n.cluster.Backend().Get(ctx, keepaliveKey(serverID))
+	if err != nil {
+		return last
+	}
+	if msec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if t := time.UnixMilli(msec); t.After(last) {
+			return t
+		}
+	}
+	return last
+}
+
+// StreamChanged 记录节点上流的注册/注销，用于统计节点流数量
+func (n *NodeManager) StreamChanged(serverID, app, stream string, regist bool) {
+	value, ok := n.cacheServers.Load(serverID)
+	if !ok {
+		return
+	}
+	value.mu.Lock()
+	defer value.mu.Unlock()
+	key := app + "/" + stream
+	if !regist {
+		delete(value.streams, key)
+		return
+	}
+	if value.streams == nil {
+		value.streams = make(map[string]struct{})
+	}
+	value.streams[key] = struct{}{}
+}
+
+// RangeServers 遍历已连接的媒体节点
+func (n *NodeManager) RangeServers(fn func(serverID string, isOnline bool, streams int)) {
+	n.cacheServers.Range(func(serverID string, ms *WarpMediaServer) bool {
+		ms.mu.Lock()
+		streams := len(ms.streams)
+		ms.mu.Unlock()
+		fn(serverID, ms.IsOnline, streams)
+		return true
+	})
 }
 
 // findMediaServer Paginated search
@@ -234,9 +350,49 @@ func (n *NodeManager) OpenRTPServer(server *MediaServer, in zlm.OpenRTPServerReq
 	return e.OpenRTPServer(in)
 }
 
+// AllocSSRC 为媒体服务器分配 ssrc，domain 为 SIP 域 ID
+func (n *NodeManager) AllocSSRC(server *MediaServer, domain string, playback bool, streamID string) (string, error) {
+	return n.ssrc.Alloc(context.Background(), server.ID, domain, playback, streamID)
+}
+
+// ReleaseSSRC 释放 ssrc
+func (n *NodeManager) ReleaseSSRC(server *MediaServer, ssrc string) {
+	n.ssrc.Release(context.Background(), server.ID, ssrc)
+}
+
+// ReleaseOrphanSSRCs 释放 before 之前分配且未被点播会话引用的 ssrc
+func (n *NodeManager) ReleaseOrphanSSRCs(ctx context.Context, before time.Time, inUse func(serverID, ssrc string) bool) (int, error) {
+	return n.ssrc.ReleaseOrphans(ctx, before, inUse)
+}
+
 // CloseRTPServer 关闭RTP服务器
-func (n *NodeManager) CloseRTPServer(in zlm.CloseRTPServerRequest) (*zlm.CloseRTPServerResponse, error) {
-	return n.zlm.CloseRTPServer(in)
+func (n *NodeManager) CloseRTPServer(server *MediaServer, in zlm.CloseRTPServerRequest) (*zlm.CloseRTPServerResponse, error) {
+	addr := fmt.Sprintf("http://%s:%d", server.IP, server.Ports.HTTP)
+	e := n.zlm.SetConfig(zlm.Config{
+		URL:    addr,
+		Secret: server.Secret,
+	})
+	return e.CloseRTPServer(in)
+}
+
+// GetRTPInfo 获取 rtp 推流信息
+func (n *NodeManager) GetRTPInfo(server *MediaServer, in zlm.GetRTPInfoRequest) (*zlm.GetRTPInfoResponse, error) {
+	addr := fmt.Sprintf("http://%s:%d", server.IP, server.Ports.HTTP)
+	e := n.zlm.SetConfig(zlm.Config{
+		URL:    addr,
+		Secret: server.Secret,
+	})
+	return e.GetRTPInfo(in)
+}
+
+// GetMediaList 获取流列表
+func (n *NodeManager) GetMediaList(server *MediaServer, in zlm.GetMediaListRequest) (*zlm.GetMediaListResponse, error) {
+	addr := fmt.Sprintf("http://%s:%d", server.IP, server.Ports.HTTP)
+	e := n.zlm.SetConfig(zlm.Config{
+		URL:    addr,
+		Secret: server.Secret,
+	})
+	return e.GetMediaList(in)
 }
 
 // AddStreamProxy 添加流代理
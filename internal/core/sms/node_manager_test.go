@@ -51,9 +51,14 @@ func (t *TestStorer) MediaServer() MediaServerStorer {
 	return &TestMediaServerStorer{}
 }
 
+// SSRC implements Storer.
+func (t *TestStorer) SSRC() SSRCStorer {
+	return &TestSSRCStorer{}
+}
+
 func TestKeepalvie(t *testing.T) {
 	var storer TestStorer
-	nm := NewNodeManager(&storer)
+	nm := NewNodeManager(&storer, nil, nil)
 	nm.cacheServers.Store("local", &WarpMediaServer{
 		LastUpdatedAt: time.Now(),
 	})
@@ -66,3 +71,16 @@ func TestKeepalvie(t *testing.T) {
 	// edit status: false
 	// edit status: true
 }
+
+func TestVerifyHook(t *testing.T) {
+	var nm NodeManager
+	nm.cacheServers.Store("local", &WarpMediaServer{hookToken: HookToken("secret")})
+	if !nm.VerifyHook(HookToken("secret")) {
+		t.Fatal("expect token of connected server to pass")
+	}
+	for _, token := range []string{"", "secret", HookToken("other")} {
+		if nm.VerifyHook(token) {
+			t.Fatalf("expect token %q to be rejected", token)
+		}
+	}
+}
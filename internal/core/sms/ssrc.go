@@ -0,0 +1,166 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+)
+
+// ssrcSeqMax ssrc 后 4 位流序号的取值个数
+const ssrcSeqMax = 10000
+
+// ErrSSRCExhausted 媒体服务器的 ssrc 已全部分配
+var ErrSSRCExhausted = errors.New("ssrc exhausted")
+
+// SSRCStorer Instantiation interface
+type SSRCStorer interface {
+	Find(context.Context, *[]*SSRC, orm.Pager, ...orm.QueryOption) (int64, error)
+	Add(context.Context, *SSRC) error
+	Del(context.Context, *SSRC, ...orm.QueryOption) error
+}
+
+// SSRCManager 按媒体服务器分配唯一的 ssrc
+// GB/T 28181 附录 G: 第 1 位 0 实时 1 历史，第 2-6 位取 SIP 域 ID 的第 4-8 位，第 7-10 位为流序号
+type SSRCManager struct {
+	storer SSRCStorer
+	shared bool // 集群部署时多个实例共用 ssrc 表
+
+	mu     sync.Mutex
+	loaded bool
+	used   map[string]map[string]struct{} // key=媒体服务器 ID
+	next   map[string]int                 // key=媒体服务器 ID + 前缀，下一个尝试的序号
+}
+
+// NewSSRCManager 已分配的 ssrc 在首次分配时从数据库加载，shared 为 true 时每次分配前重新加载
+func NewSSRCManager(storer SSRCStorer, shared bool) *SSRCManager {
+	return &SSRCManager{
+		storer: storer,
+		shared: shared,
+		used:   make(map[string]map[string]struct{}),
+		next:   make(map[string]int),
+	}
+}
+
+// Alloc 分配 ssrc，playback 为 true 时分配历史回放的 ssrc
+func (m *SSRCManager) Alloc(ctx context.Context, serverID, domain string, playback bool, streamID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.load(ctx, serverID); err != nil {
+		return "", err
+	}
+
+	prefix := "0"
+	if playback {
+		prefix = "1"
+	}
+	prefix += ssrcDomain(domain)
+
+	used := m.used[serverID]
+	if used == nil {
+		used = make(map[string]struct{})
+		m.used[serverID] = used
+	}
+	key := serverID + ":" + prefix
+	for i := range ssrcSeqMax {
+		seq := (m.next[key] + i) % ssrcSeqMax
+		ssrc := fmt.Sprintf("%s%04d", prefix, seq)
+		if _, ok := used[ssrc]; ok {
+			continue
+		}
+		err := m.storer.Add(ctx, &SSRC{MediaServerID: serverID, SSRC: ssrc, StreamID: streamID})
+		// 其它实例已分配该 ssrc
+		if orm.IsDuplicatedKey(err) {
+			used[ssrc] = struct{}{}
+			continue
+		}
+		if err != nil {
+			return "", web.ErrDB.Withf(`Add err[%s]`, err.Error())
+		}
+		used[ssrc] = struct{}{}
+		m.next[key] = seq + 1
+		return ssrc, nil
+	}
+	return "", ErrSSRCExhausted
+}
+
+// Release 释放 ssrc
+func (m *SSRCManager) Release(ctx context.Context, serverID, ssrc string) {
+	if ssrc == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.storer.Del(ctx, new(SSRC), orm.Where("media_server_id=? AND ssrc=?", serverID, ssrc)); err != nil {
+		slog.Error("释放 ssrc 失败", "err", err, "media_server_id", serverID, "ssrc", ssrc)
+	}
+	delete(m.used[serverID], ssrc)
+}
+
+// ReleaseOrphans 释放 before 之前分配且 inUse 返回 false 的 ssrc，返回释放的个数
+// 之后分配的 ssrc 可能正在建立会话，会话在收到 ACK 后才持久化
+func (m *SSRCManager) ReleaseOrphans(ctx context.Context, before time.Time, inUse func(serverID, ssrc string) bool) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	items := make([]*SSRC, 0, 8)
+	if _, err := m.storer.Find(ctx, &items, web.NewPagerFilterMaxSize(), orm.Where("created_at < ?", before)); err != nil {
+		return 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	var n int
+	for _, v := range items {
+		if inUse(v.MediaServerID, v.SSRC) {
+			continue
+		}
+		if err := m.storer.Del(ctx, new(SSRC), orm.Where("id=?", v.ID)); err != nil {
+			return n, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+		}
+		delete(m.used[v.MediaServerID], v.SSRC)
+		n++
+	}
+	return n, nil
+}
+
+// load 加载已分配的 ssrc，重启后仍在使用的流不会被重复分配
+// 集群部署时其它实例也会分配与释放 ssrc，每次仅加载该媒体服务器的 ssrc
+func (m *SSRCManager) load(ctx context.Context, serverID string) error {
+	if m.loaded && !m.shared {
+		return nil
+	}
+	opts := make([]orm.QueryOption, 0, 1)
+	if m.shared {
+		opts = append(opts, orm.Where("media_server_id=?", serverID))
+		m.used[serverID] = make(map[string]struct{})
+	}
+	items := make([]*SSRC, 0, 8)
+	if _, err := m.storer.Find(ctx, &items, web.NewPagerFilterMaxSize(), opts...); err != nil {
+		return web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	for _, v := range items {
+		if m.used[v.MediaServerID] == nil {
+			m.used[v.MediaServerID] = make(map[string]struct{})
+		}
+		m.used[v.MediaServerID][v.SSRC] = struct{}{}
+	}
+	m.loaded = true
+	return nil
+}
+
+// ssrcDomain 取 SIP 域 ID 的第 4-8 位，域 ID 过短或包含非数字时补 0
+func ssrcDomain(domain string) string {
+	if len(domain) < 8 {
+		domain = strings.Repeat("0", 8-len(domain)) + domain
+	}
+	out := []byte(domain[3:8])
+	for i, c := range out {
+		if c < '0' || c > '9' {
+			out[i] = '0'
+		}
+	}
+	return string(out)
+}
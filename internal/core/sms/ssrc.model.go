@@ -0,0 +1,18 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package sms
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// SSRC 媒体服务器已分配的 ssrc，持久化避免重启后重复分配
+type SSRC struct {
+	ID            int64    `gorm:"primaryKey" json:"id"`
+	MediaServerID string   `gorm:"column:media_server_id;notNull;default:'';uniqueIndex:idx_ssrcs_server_ssrc;comment:媒体服务器 ID" json:"media_server_id"` // 媒体服务器 ID
+	SSRC          string   `gorm:"column:ssrc;notNull;default:'';uniqueIndex:idx_ssrcs_server_ssrc;comment:10 位 ssrc" json:"ssrc"`                      // 10 位 ssrc
+	StreamID      string   `gorm:"column:stream_id;notNull;default:'';comment:流 ID" json:"stream_id"`                                                   // 流 ID
+	CreatedAt     orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`                   // 创建时间
+}
+
+// TableName database table name
+func (*SSRC) TableName() string {
+	return "ssrcs"
+}
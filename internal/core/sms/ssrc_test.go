@@ -0,0 +1,134 @@
+package sms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+)
+
+var _ SSRCStorer = (*TestSSRCStorer)(nil)
+
+// TestSSRCStorer 内存存储，Del 仅计数，taken 模拟其它实例已写入的 ssrc
+type TestSSRCStorer struct {
+	items []*SSRC
+	dels  int
+	taken map[string]bool
+}
+
+// Find implements SSRCStorer.
+func (t *TestSSRCStorer) Find(_ context.Context, out *[]*SSRC, _ orm.Pager, _ ...orm.QueryOption) (int64, error) {
+	*out = append(*out, t.items...)
+	return int64(len(t.items)), nil
+}
+
+// Add implements SSRCStorer.
+func (t *TestSSRCStorer) Add(_ context.Context, in *SSRC) error {
+	if t.taken[in.SSRC] {
+		return gorm.ErrDuplicatedKey
+	}
+	t.items = append(t.items, in)
+	return nil
+}
+
+// Del implements SSRCStorer.
+func (t *TestSSRCStorer) Del(context.Context, *SSRC, ...orm.QueryOption) error {
+	t.dels++
+	return nil
+}
+
+func TestSSRCManagerAlloc(t *testing.T) {
+	ctx := context.Background()
+	storer := TestSSRCStorer{items: []*SSRC{{MediaServerID: "local", SSRC: "0200000000"}}}
+	m := NewSSRCManager(&storer, false)
+
+	alloc := func(serverID, domain string, playback bool) string {
+		t.Helper()
+		ssrc, err := m.Alloc(ctx, serverID, domain, playback, "stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ssrc
+	}
+	// 重启前已分配的 ssrc 不会重复分配
+	if v := alloc("local", "3402000000", false); v != "0200000001" {
+		t.Fatalf("expect 0200000001, got %s", v)
+	}
+	if v := alloc("local", "3402000000", true); v != "1200000000" {
+		t.Fatalf("expect playback ssrc 1200000000, got %s", v)
+	}
+	// 不同媒体服务器独立分配
+	if v := alloc("zlm2", "3402000000", false); v != "0200000000" {
+		t.Fatalf("expect 0200000000, got %s", v)
+	}
+	// 域 ID 过短时不越界
+	if v := alloc("local", "12", false); v != "0000120000" {
+		t.Fatalf("expect 0000120000, got %s", v)
+	}
+
+	m.Release(ctx, "local", "0200000000")
+	if storer.dels != 1 {
+		t.Fatal("expect ssrc deleted")
+	}
+	if v := alloc("local", "3402000000", false); v != "0200000002" {
+		t.Fatalf("expect 0200000002, got %s", v)
+	}
+}
+
+func TestSSRCManagerExhausted(t *testing.T) {
+	m := NewSSRCManager(&TestSSRCStorer{}, false)
+	for range ssrcSeqMax {
+		if _, err := m.Alloc(context.Background(), "local", "3402000000", false, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Alloc(context.Background(), "local", "3402000000", false, ""); err != ErrSSRCExhausted {
+		t.Fatalf("expect exhausted, got %v", err)
+	}
+}
+
+func TestSSRCManagerShared(t *testing.T) {
+	ctx := context.Background()
+	storer := TestSSRCStorer{taken: map[string]bool{"0200000000": true}}
+	m := NewSSRCManager(&storer, true)
+
+	// 其它实例已分配的 ssrc 写入冲突时尝试下一个序号
+	if v, err := m.Alloc(ctx, "local", "3402000000", false, ""); err != nil || v != "0200000001" {
+		t.Fatalf("expect 0200000001, got %s %v", v, err)
+	}
+	// 其它实例释放后可再次分配
+	storer.taken = nil
+	storer.items = nil
+	m.next = make(map[string]int)
+	if v, err := m.Alloc(ctx, "local", "3402000000", false, ""); err != nil || v != "0200000000" {
+		t.Fatalf("expect 0200000000, got %s %v", v, err)
+	}
+}
+
+func TestSSRCManagerReleaseOrphans(t *testing.T) {
+	ctx := context.Background()
+	storer := TestSSRCStorer{items: []*SSRC{
+		{ID: 1, MediaServerID: "local", SSRC: "0200000000"},
+		{ID: 2, MediaServerID: "local", SSRC: "0200000001"},
+	}}
+	m := NewSSRCManager(&storer, false)
+	if _, err := m.Alloc(ctx, "local", "3402000000", false, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// 仅释放未被会话引用的 ssrc
+	n, err := m.ReleaseOrphans(ctx, time.Now(), func(serverID, ssrc string) bool {
+		return serverID == "local" && ssrc != "0200000001"
+	})
+	if err != nil || n != 1 || storer.dels != 1 {
+		t.Fatalf("expect 1 orphan released, got %d %v", n, err)
+	}
+	if _, ok := m.used["local"]["0200000001"]; ok {
+		t.Fatal("expect orphan removed from used")
+	}
+	if _, ok := m.used["local"]["0200000000"]; !ok {
+		t.Fatal("expect referenced ssrc kept")
+	}
+}
@@ -25,11 +25,17 @@ func (d DB) MediaServer() sms.MediaServerStorer {
 	return MediaServer(d)
 }
 
+// SSRC Get business instance
+func (d DB) SSRC() sms.SSRCStorer {
+	return SSRC(d)
+}
+
 // AutoMigrate sync database
 func (d DB) AutoMigrate(ok bool) DB {
 
 	if err := d.db.AutoMigrate(
 		new(sms.MediaServer),
+		new(sms.SSRC),
 		new(gb28181.Device),
 		new(gbs.Streams),
 	); err != nil {
@@ -0,0 +1,35 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package smsdb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/sms"
+)
+
+var _ sms.SSRCStorer = SSRC{}
+
+// SSRC Related business namespaces
+type SSRC DB
+
+// NewSSRC instance object
+func NewSSRC(db *gorm.DB) SSRC {
+	return SSRC{db: db}
+}
+
+// Find implements sms.SSRCStorer.
+func (d SSRC) Find(ctx context.Context, bs *[]*sms.SSRC, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Add implements sms.SSRCStorer.
+func (d SSRC) Add(ctx context.Context, model *sms.SSRC) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Del implements sms.SSRCStorer.
+func (d SSRC) Del(ctx context.Context, model *sms.SSRC, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
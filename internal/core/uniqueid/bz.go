@@ -49,6 +49,41 @@ func (m *IDManager) UniqueID(prefix string) string {
 	return "unknown"
 }
 
+// UniqueIDs 批量获取唯一 id，整批写入失败时重新生成，多次失败后逐个获取
+func (m *IDManager) UniqueIDs(prefix string, n int) []string {
+	if n <= 0 {
+		return nil
+	}
+	cost := hook.UseTiming(time.Second)
+	defer cost()
+
+	for i := range 3 {
+		items := make([]*UniqueID, 0, n)
+		ids := make([]string, 0, n)
+		seen := make(map[string]struct{}, n)
+		for len(ids) < n {
+			id := prefix + GenerateRandomString(m.length+i)
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+			items = append(items, &UniqueID{ID: id})
+		}
+		if err := m.store.BatchAdd(context.Background(), items); err != nil {
+			slog.Error("UniqueIDs", "err", err, "n", n)
+			continue
+		}
+		return ids
+	}
+
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = m.UniqueID(prefix)
+	}
+	return ids
+}
+
 // GenerateRandomString 生成随机字符串
 // 采用全小写+数字，有识别需求，可以删除 o/0，i/l 之一
 func GenerateRandomString(length int) string {
@@ -24,3 +24,8 @@ func NewCore(store Storer, length int) Core {
 func (c Core) UniqueID(prefix string) string {
 	return c.m.UniqueID(prefix)
 }
+
+// UniqueIDs 批量获取全局唯一 ID
+func (c Core) UniqueIDs(prefix string, n int) []string {
+	return c.m.UniqueIDs(prefix, n)
+}
@@ -34,6 +34,13 @@ func (d UniqueID) Add(ctx context.Context, model *uniqueid.UniqueID) error {
 	return d.db.WithContext(ctx).Create(model).Error
 }
 
+// BatchAdd implements uniqueid.UniqueIDStorer.
+func (d UniqueID) BatchAdd(ctx context.Context, models []*uniqueid.UniqueID) error {
+	return d.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(models, 500).Error
+	})
+}
+
 // Edit implements uniqueid.UniqueIDStorer.
 func (d UniqueID) Edit(ctx context.Context, model *uniqueid.UniqueID, changeFn func(*uniqueid.UniqueID), opts ...orm.QueryOption) error {
 	return orm.UpdateWithContext(ctx, d.db, model, changeFn, opts...)
@@ -12,6 +12,7 @@ type UniqueIDStorer interface {
 	Find(context.Context, *[]*UniqueID, orm.Pager, ...orm.QueryOption) (int64, error)
 	Get(context.Context, *UniqueID, ...orm.QueryOption) error
 	Add(context.Context, *UniqueID) error
+	BatchAdd(context.Context, []*UniqueID) error // 批量写入，任一 id 重复时整体失败
 	Edit(context.Context, *UniqueID, func(*UniqueID), ...orm.QueryOption) error
 	Del(context.Context, *UniqueID, ...orm.QueryOption) error
 }
@@ -0,0 +1,166 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/audit"
+	"wvp/internal/core/bz"
+)
+
+const (
+	apiKeyPrefix = "wvp_"
+	// apiKeyUsedInterval 最后使用时间的更新间隔，避免每次请求都写库
+	apiKeyUsedInterval = time.Minute
+)
+
+var apiKeyScopes = []string{ScopePlay, ScopePTZ, ScopeRead, ScopeAdmin}
+
+// APIKeyStorer Instantiation interface
+type APIKeyStorer interface {
+	Find(context.Context, *[]*APIKey, orm.Pager, ...orm.QueryOption) (int64, error)
+	Get(context.Context, *APIKey, ...orm.QueryOption) error
+	Add(context.Context, *APIKey) error
+	Edit(context.Context, *APIKey, func(*APIKey), ...orm.QueryOption) error
+	Del(context.Context, *APIKey, ...orm.QueryOption) error
+}
+
+// FindAPIKey Paginated search
+func (c Core) FindAPIKey(ctx context.Context, in *FindAPIKeyInput) ([]*APIKey, int64, error) {
+	items := make([]*APIKey, 0)
+
+	query := orm.NewQuery(2)
+	query.OrderBy("created_at DESC")
+	if in.UserID != "" {
+		query.Where("user_id=?", in.UserID)
+	}
+
+	total, err := c.store.APIKey().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// AddAPIKey 创建密钥，明文密钥仅在此返回
+func (c Core) AddAPIKey(ctx context.Context, in *AddAPIKeyInput, uid string) (*AddAPIKeyOutput, error) {
+	for _, scope := range in.Scopes {
+		if !slices.Contains(apiKeyScopes, scope) {
+			return nil, web.ErrBadRequest.Msg("不支持的权限范围: " + scope)
+		}
+	}
+	for _, ip := range in.AllowIPs {
+		if net.ParseIP(ip) == nil {
+			if _, _, err := net.ParseCIDR(ip); err != nil {
+				return nil, web.ErrBadRequest.Msg("IP 白名单格式错误: " + ip)
+			}
+		}
+	}
+
+	prefix, err := randomHex(4)
+	if err != nil {
+		return nil, web.ErrServer.Msg(err.Error())
+	}
+	secret, err := randomHex(16)
+	if err != nil {
+		return nil, web.ErrServer.Msg(err.Error())
+	}
+	prefix = apiKeyPrefix + prefix
+	key := prefix + "_" + secret
+
+	out := APIKey{
+		ID:         c.uniqueID.UniqueID(bz.IDPrefixAPIKey),
+		UserID:     uid,
+		Name:       in.Name,
+		Prefix:     prefix,
+		Hash:       hashAPIKey(key),
+		Scopes:     in.Scopes,
+		AllowIPs:   in.AllowIPs,
+		LastUsedAt: orm.Now(),
+	}
+	if err := c.store.APIKey().Add(ctx, &out); err != nil {
+		return nil, web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return &AddAPIKeyOutput{APIKey: &out, Key: key}, nil
+}
+
+// DelAPIKey Delete object，uid 为空时不限制所属用户
+func (c Core) DelAPIKey(ctx context.Context, id, uid string) (*APIKey, error) {
+	audit.Record(ctx, audit.ActionAPIKeyDelete, id, nil)
+	query := orm.NewQuery(2)
+	query.Where("id=?", id)
+	if uid != "" {
+		query.Where("user_id=?", uid)
+	}
+	var out APIKey
+	if err := c.store.APIKey().Del(ctx, &out, query.Encode()...); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// VerifyAPIKey 校验密钥与来源 IP，并记录最后使用信息
+func (c Core) VerifyAPIKey(ctx context.Context, key, ip string) (*APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, web.ErrBadRequest.Msg("API Key 无效")
+	}
+	var out APIKey
+	if err := c.store.APIKey().Get(ctx, &out, orm.Where("hash=?", hashAPIKey(key))); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrBadRequest.Msg("API Key 无效")
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	if !out.allowIP(ip) {
+		return nil, web.ErrBadRequest.Msg("API Key 不允许从该 IP 访问")
+	}
+
+	if time.Since(out.LastUsedAt.Time) >= apiKeyUsedInterval || out.LastUsedIP != ip {
+		if err := c.store.APIKey().Edit(ctx, new(APIKey), func(b *APIKey) {
+			b.LastUsedAt = orm.Now()
+			b.LastUsedIP = ip
+		}, orm.Where("id=?", out.ID)); err != nil {
+			slog.Error("记录 API Key 使用信息失败", "id", out.ID, "err", err)
+		}
+	}
+	return &out, nil
+}
+
+func (k *APIKey) allowIP(ip string) bool {
+	if len(k.AllowIPs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	for _, v := range k.AllowIPs {
+		if v == ip {
+			return true
+		}
+		if _, cidr, err := net.ParseCIDR(v); err == nil && addr != nil && cidr.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func hashAPIKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
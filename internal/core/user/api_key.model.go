@@ -0,0 +1,41 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// API Key 权限范围
+const (
+	ScopePlay  = "play"  // 播放
+	ScopePTZ   = "ptz"   // 云台控制
+	ScopeRead  = "read"  // 只读查询
+	ScopeAdmin = "admin" // 全部权限
+)
+
+// APIKey domain model
+type APIKey struct {
+	ID         string   `gorm:"primaryKey" json:"id"`
+	CreatedAt  orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`       // 创建时间
+	UserID     string   `gorm:"column:user_id;index;notNull;default:'';comment:所属用户 id" json:"user_id"`                                  // 所属用户 id，数据权限与该用户一致
+	Name       string   `gorm:"column:name;notNull;default:'';comment:名称" json:"name"`                                                   // 名称
+	Prefix     string   `gorm:"column:prefix;index;notNull;default:'';comment:明文前缀，用于识别" json:"prefix"`                                  // 明文前缀，用于识别
+	Hash       string   `gorm:"column:hash;uniqueIndex;notNull;default:'';comment:密钥哈希" json:"-"`                                        // 密钥哈希
+	Scopes     Strings  `gorm:"column:scopes;notNull;type:JSON;comment:权限范围" json:"scopes"`                                              // 权限范围
+	AllowIPs   Strings  `gorm:"column:allow_ips;notNull;type:JSON;comment:IP 白名单，支持 CIDR，为空不限制" json:"allow_ips"`                        // IP 白名单
+	LastUsedAt orm.Time `gorm:"column:last_used_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:最后使用时间" json:"last_used_at"` // 最后使用时间
+	LastUsedIP string   `gorm:"column:last_used_ip;notNull;default:'';comment:最后使用 IP" json:"last_used_ip"`                              // 最后使用 IP
+}
+
+// TableName database table name
+func (*APIKey) TableName() string {
+	return "api_keys"
+}
+
+// HasScope 是否拥有指定权限，admin 拥有全部权限
+func (k *APIKey) HasScope(scope string) bool {
+	for _, v := range k.Scopes {
+		if v == scope || v == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
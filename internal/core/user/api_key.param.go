@@ -0,0 +1,20 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import "github.com/ixugo/goweb/pkg/web"
+
+type FindAPIKeyInput struct {
+	web.PagerFilter
+	UserID string `form:"-"` // 所属用户，管理员可查看全部
+}
+
+type AddAPIKeyInput struct {
+	Name     string   `json:"name" binding:"required"`   // 名称
+	Scopes   []string `json:"scopes" binding:"required"` // 权限范围 play/ptz/read/admin
+	AllowIPs []string `json:"allow_ips"`                 // IP 白名单，支持 CIDR
+}
+
+type AddAPIKeyOutput struct {
+	*APIKey
+	Key string `json:"key"` // 明文密钥，仅创建时返回一次
+}
@@ -0,0 +1,26 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import "wvp/internal/core/uniqueid"
+
+// Storer data persistence
+type Storer interface {
+	User() UserStorer
+	Role() RoleStorer
+	Grant() GrantStorer
+	APIKey() APIKeyStorer
+}
+
+// Core business domain
+type Core struct {
+	store    Storer
+	uniqueID uniqueid.Core
+}
+
+// NewCore create business domain
+func NewCore(store Storer, uni uniqueid.Core) Core {
+	return Core{
+		store:    store,
+		uniqueID: uni,
+	}
+}
@@ -0,0 +1,25 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/ixugo/goweb/pkg/orm"
+)
+
+// Strings 字符串数组，以 json 格式存储
+type Strings []string
+
+// Scan implements orm.Scaner.
+func (i *Strings) Scan(input interface{}) error {
+	return orm.JsonUnmarshal(input, i)
+}
+
+// Value implements driver.Valuer.
+func (i Strings) Value() (driver.Value, error) {
+	if i == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(i)
+}
@@ -0,0 +1,248 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"github.com/jinzhu/copier"
+	"wvp/internal/core/audit"
+	"wvp/internal/core/bz"
+)
+
+// RoleIDAdmin 内置管理员角色
+const RoleIDAdmin = "admin"
+
+// resourceTypes 支持授权的资源类型
+var resourceTypes = []string{bz.ResourceDevice, bz.ResourceChannel, bz.ResourcePush, bz.ResourceProxy}
+
+// RoleStorer Instantiation interface
+type RoleStorer interface {
+	Find(context.Context, *[]*Role, orm.Pager, ...orm.QueryOption) (int64, error)
+	Get(context.Context, *Role, ...orm.QueryOption) error
+	Add(context.Context, *Role) error
+	Edit(context.Context, *Role, func(*Role), ...orm.QueryOption) error
+	Del(context.Context, *Role, ...orm.QueryOption) error
+}
+
+// GrantStorer Instantiation interface
+type GrantStorer interface {
+	Find(context.Context, *[]*Grant, orm.Pager, ...orm.QueryOption) (int64, error)
+	Add(context.Context, ...*Grant) error
+	Del(context.Context, *Grant, ...orm.QueryOption) error
+}
+
+// FindRole Paginated search
+func (c Core) FindRole(ctx context.Context, in *FindRoleInput) ([]*Role, int64, error) {
+	items := make([]*Role, 0)
+
+	query := orm.NewQuery(2)
+	query.OrderBy("created_at DESC")
+	if in.Key != "" {
+		query.Where("name LIKE ?", "%"+in.Key+"%")
+	}
+
+	total, err := c.store.Role().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// GetRole Query a single object
+func (c Core) GetRole(ctx context.Context, id string) (*Role, error) {
+	var out Role
+	if err := c.store.Role().Get(ctx, &out, orm.Where("id=?", id)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrNotFound.Withf(`Get err[%s]`, err.Error())
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// AddRole Insert into database
+func (c Core) AddRole(ctx context.Context, in *AddRoleInput) (*Role, error) {
+	var out Role
+	if err := copier.Copy(&out, in); err != nil {
+		slog.Error("Copy", "err", err)
+	}
+	out.ID = c.uniqueID.UniqueID(bz.IDPrefixRole)
+	if err := c.store.Role().Add(ctx, &out); err != nil {
+		if orm.IsDuplicatedKey(err) {
+			return nil, web.ErrDB.Msg("角色名称重复，请更换")
+		}
+		return nil, web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// EditRole Update object information
+func (c Core) EditRole(ctx context.Context, in *EditRoleInput, id string) (*Role, error) {
+	audit.Record(ctx, audit.ActionRoleEdit, id, in)
+	if id == RoleIDAdmin && !in.IsAdmin {
+		return nil, web.ErrBadRequest.Msg("内置管理员角色不能取消管理员权限")
+	}
+	var out Role
+	if err := c.store.Role().Edit(ctx, &out, func(b *Role) {
+		if err := copier.Copy(b, in); err != nil {
+			slog.Error("Copy", "err", err)
+		}
+	}, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Edit err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// DelRole Delete object，同时删除角色下的授权
+func (c Core) DelRole(ctx context.Context, id string) (*Role, error) {
+	audit.Record(ctx, audit.ActionRoleDelete, id, nil)
+	if id == RoleIDAdmin {
+		return nil, web.ErrBadRequest.Msg("内置管理员角色不能删除")
+	}
+	total, err := c.store.User().Count(ctx, orm.Where("role_id=?", id))
+	if err != nil {
+		return nil, web.ErrDB.Withf(`Count err[%s]`, err.Error())
+	}
+	if total > 0 {
+		return nil, web.ErrBadRequest.Msg("角色下存在用户，请先调整用户角色")
+	}
+	var out Role
+	if err := c.store.Role().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	if err := c.store.Grant().Del(ctx, new(Grant), orm.Where("role_id=?", id)); err != nil {
+		slog.Error("删除角色授权失败", "role_id", id, "err", err)
+	}
+	return &out, nil
+}
+
+// FindGrant 查询角色的授权
+func (c Core) FindGrant(ctx context.Context, in *FindGrantInput, roleID string) ([]*Grant, int64, error) {
+	items := make([]*Grant, 0)
+
+	query := orm.NewQuery(2)
+	query.Where("role_id=?", roleID)
+	if in.ResourceType != "" {
+		query.Where("resource_type=?", in.ResourceType)
+	}
+
+	total, err := c.store.Grant().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// AddGrants 批量授权，已存在的授权忽略
+func (c Core) AddGrants(ctx context.Context, in *AddGrantsInput, roleID string) ([]*Grant, error) {
+	if !slices.Contains(resourceTypes, in.ResourceType) {
+		return nil, web.ErrBadRequest.Msg("不支持的资源类型")
+	}
+	if _, err := c.GetRole(ctx, roleID); err != nil {
+		return nil, err
+	}
+
+	exists := make([]*Grant, 0, 8)
+	if _, err := c.store.Grant().Find(ctx, &exists, web.NewPagerFilterMaxSize(),
+		orm.Where("role_id=? AND resource_type=? AND resource_id IN ?", roleID, in.ResourceType, in.ResourceIDs),
+	); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+
+	items := make([]*Grant, 0, len(in.ResourceIDs))
+	for _, id := range in.ResourceIDs {
+		if id == "" || slices.ContainsFunc(exists, func(g *Grant) bool { return g.ResourceID == id }) {
+			continue
+		}
+		items = append(items, &Grant{
+			RoleID:       roleID,
+			ResourceType: in.ResourceType,
+			ResourceID:   id,
+		})
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+	if err := c.store.Grant().Add(ctx, items...); err != nil {
+		return nil, web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return items, nil
+}
+
+// DelGrant 取消授权
+func (c Core) DelGrant(ctx context.Context, roleID string, id int) (*Grant, error) {
+	audit.Record(ctx, audit.ActionGrantDelete, roleID, map[string]int{"grant_id": id})
+	var out Grant
+	if err := c.store.Grant().Del(ctx, &out, orm.Where("id=? AND role_id=?", id, roleID)); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// Permission 用户的数据权限
+type Permission struct {
+	UserID   string
+	Username string
+	RoleID   string
+	IsAdmin  bool
+	grants   map[string][]string
+}
+
+// NewPermission 非管理员的数据权限，grants 为资源类型对应的已授权资源 id
+func NewPermission(uid string, grants map[string][]string) *Permission {
+	return &Permission{UserID: uid, grants: grants}
+}
+
+// Scope 获取指定资源类型的数据权限范围，未登录时不可访问任何资源
+func (p *Permission) Scope(resourceType string) bz.Scope {
+	if p == nil {
+		return bz.Scope{Limited: true}
+	}
+	if p.IsAdmin {
+		return bz.Scope{}
+	}
+	scope := bz.Scope{Limited: true, IDs: p.grants[resourceType]}
+	// 授权设备后，可访问设备下的所有通道
+	if resourceType == bz.ResourceChannel {
+		scope.ParentIDs = p.grants[bz.ResourceDevice]
+	}
+	return scope
+}
+
+// GetPermission 获取用户的数据权限
+func (c Core) GetPermission(ctx context.Context, uid string) (*Permission, error) {
+	u, err := c.GetUser(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if !u.Enabled {
+		return nil, web.ErrBadRequest.Msg("用户已禁用")
+	}
+	p := Permission{UserID: u.ID, Username: u.Username, RoleID: u.RoleID, grants: make(map[string][]string)}
+	if u.RoleID == "" {
+		return &p, nil
+	}
+	var role Role
+	if err := c.store.Role().Get(ctx, &role, orm.Where("id=?", u.RoleID)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return &p, nil
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	if p.IsAdmin = role.IsAdmin; p.IsAdmin {
+		return &p, nil
+	}
+
+	grants := make([]*Grant, 0, 8)
+	if _, err := c.store.Grant().Find(ctx, &grants, web.NewPagerFilterMaxSize(), orm.Where("role_id=?", role.ID)); err != nil {
+		return nil, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	for _, g := range grants {
+		p.grants[g.ResourceType] = append(p.grants[g.ResourceType], g.ResourceID)
+	}
+	return &p, nil
+}
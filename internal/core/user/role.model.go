@@ -0,0 +1,33 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// Role domain model
+type Role struct {
+	ID        string   `gorm:"primaryKey" json:"id"`
+	CreatedAt orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"` // 创建时间
+	UpdatedAt orm.Time `gorm:"column:updated_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"` // 更新时间
+	Name      string   `gorm:"column:name;uniqueIndex;notNull;default:'';comment:角色名称" json:"name"`                               // 角色名称
+	Remark    string   `gorm:"column:remark;notNull;default:'';comment:备注" json:"remark"`                                         // 备注
+	IsAdmin   bool     `gorm:"column:is_admin;notNull;default:FALSE;comment:是否管理员，管理员不受数据权限限制" json:"is_admin"`                   // 是否管理员
+}
+
+// TableName database table name
+func (*Role) TableName() string {
+	return "roles"
+}
+
+// Grant domain model
+type Grant struct {
+	ID           int      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt    orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`                 // 创建时间
+	RoleID       string   `gorm:"column:role_id;uniqueIndex:idx_grants_role_resource;notNull;default:'';comment:角色 id" json:"role_id"`               // 角色 id
+	ResourceType string   `gorm:"column:resource_type;uniqueIndex:idx_grants_role_resource;notNull;default:'';comment:资源类型" json:"resource_type"`    // 资源类型
+	ResourceID   string   `gorm:"column:resource_id;uniqueIndex:idx_grants_role_resource;index;notNull;default:'';comment:资源 id" json:"resource_id"` // 资源 id
+}
+
+// TableName database table name
+func (*Grant) TableName() string {
+	return "grants"
+}
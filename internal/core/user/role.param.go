@@ -0,0 +1,31 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import "github.com/ixugo/goweb/pkg/web"
+
+type FindRoleInput struct {
+	web.PagerFilter
+	Key string `form:"key"` // 角色名称
+}
+
+type EditRoleInput struct {
+	Name    string `json:"name"`     // 角色名称
+	Remark  string `json:"remark"`   // 备注
+	IsAdmin bool   `json:"is_admin"` // 是否管理员
+}
+
+type AddRoleInput struct {
+	Name    string `json:"name" binding:"required"` // 角色名称
+	Remark  string `json:"remark"`                  // 备注
+	IsAdmin bool   `json:"is_admin"`                // 是否管理员
+}
+
+type FindGrantInput struct {
+	web.PagerFilter
+	ResourceType string `form:"resource_type"` // 资源类型
+}
+
+type AddGrantsInput struct {
+	ResourceType string   `json:"resource_type" binding:"required"` // 资源类型 device/channel/push/proxy
+	ResourceIDs  []string `json:"resource_ids" binding:"required"`  // 资源 id
+}
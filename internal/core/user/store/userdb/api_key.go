@@ -0,0 +1,45 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package userdb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/user"
+)
+
+var _ user.APIKeyStorer = APIKey{}
+
+// APIKey Related business namespaces
+type APIKey DB
+
+// NewAPIKey instance object
+func NewAPIKey(db *gorm.DB) APIKey {
+	return APIKey{db: db}
+}
+
+// Find implements user.APIKeyStorer.
+func (d APIKey) Find(ctx context.Context, bs *[]*user.APIKey, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Get implements user.APIKeyStorer.
+func (d APIKey) Get(ctx context.Context, model *user.APIKey, opts ...orm.QueryOption) error {
+	return orm.FirstWithContext(ctx, d.db, model, opts...)
+}
+
+// Add implements user.APIKeyStorer.
+func (d APIKey) Add(ctx context.Context, model *user.APIKey) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Edit implements user.APIKeyStorer.
+func (d APIKey) Edit(ctx context.Context, model *user.APIKey, changeFn func(*user.APIKey), opts ...orm.QueryOption) error {
+	return orm.UpdateWithContext(ctx, d.db, model, changeFn, opts...)
+}
+
+// Del implements user.APIKeyStorer.
+func (d APIKey) Del(ctx context.Context, model *user.APIKey, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
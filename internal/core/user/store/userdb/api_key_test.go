@@ -0,0 +1,31 @@
+package userdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ixugo/goweb/pkg/orm"
+	"wvp/internal/core/user"
+)
+
+func TestAPIKeyGet(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKeyDB := NewAPIKey(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "api_keys" WHERE hash=\$1 (.+) LIMIT \$2`).WithArgs("abc", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hash", "scopes"}).AddRow("ak1", "abc", `["play","read"]`))
+	var out user.APIKey
+	if err := apiKeyDB.Get(context.Background(), &out, orm.Where("hash=?", "abc")); err != nil {
+		t.Fatal(err)
+	}
+	if !out.HasScope(user.ScopePlay) || out.HasScope(user.ScopePTZ) {
+		t.Fatalf("unexpected scopes %v", out.Scopes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
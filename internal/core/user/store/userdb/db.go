@@ -0,0 +1,55 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package userdb
+
+import (
+	"gorm.io/gorm"
+	"wvp/internal/core/user"
+)
+
+var _ user.Storer = DB{}
+
+// DB Related business namespaces
+type DB struct {
+	db *gorm.DB
+}
+
+// NewDB instance object
+func NewDB(db *gorm.DB) DB {
+	return DB{db: db}
+}
+
+// User Get business instance
+func (d DB) User() user.UserStorer {
+	return User(d)
+}
+
+// Role Get business instance
+func (d DB) Role() user.RoleStorer {
+	return Role(d)
+}
+
+// Grant Get business instance
+func (d DB) Grant() user.GrantStorer {
+	return Grant(d)
+}
+
+// APIKey Get business instance
+func (d DB) APIKey() user.APIKeyStorer {
+	return APIKey(d)
+}
+
+// AutoMigrate sync database
+func (d DB) AutoMigrate(ok bool) DB {
+	if !ok {
+		return d
+	}
+	if err := d.db.AutoMigrate(
+		new(user.User),
+		new(user.Role),
+		new(user.Grant),
+		new(user.APIKey),
+	); err != nil {
+		panic(err)
+	}
+	return d
+}
@@ -0,0 +1,71 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package userdb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/user"
+)
+
+var (
+	_ user.RoleStorer  = Role{}
+	_ user.GrantStorer = Grant{}
+)
+
+// Role Related business namespaces
+type Role DB
+
+// NewRole instance object
+func NewRole(db *gorm.DB) Role {
+	return Role{db: db}
+}
+
+// Find implements user.RoleStorer.
+func (d Role) Find(ctx context.Context, bs *[]*user.Role, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Get implements user.RoleStorer.
+func (d Role) Get(ctx context.Context, model *user.Role, opts ...orm.QueryOption) error {
+	return orm.FirstWithContext(ctx, d.db, model, opts...)
+}
+
+// Add implements user.RoleStorer.
+func (d Role) Add(ctx context.Context, model *user.Role) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Edit implements user.RoleStorer.
+func (d Role) Edit(ctx context.Context, model *user.Role, changeFn func(*user.Role), opts ...orm.QueryOption) error {
+	return orm.UpdateWithContext(ctx, d.db, model, changeFn, opts...)
+}
+
+// Del implements user.RoleStorer.
+func (d Role) Del(ctx context.Context, model *user.Role, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
+
+// Grant Related business namespaces
+type Grant DB
+
+// NewGrant instance object
+func NewGrant(db *gorm.DB) Grant {
+	return Grant{db: db}
+}
+
+// Find implements user.GrantStorer.
+func (d Grant) Find(ctx context.Context, bs *[]*user.Grant, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Add implements user.GrantStorer.
+func (d Grant) Add(ctx context.Context, models ...*user.Grant) error {
+	return d.db.WithContext(ctx).Create(models).Error
+}
+
+// Del implements user.GrantStorer.
+func (d Grant) Del(ctx context.Context, model *user.Grant, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
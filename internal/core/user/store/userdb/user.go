@@ -0,0 +1,56 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package userdb
+
+import (
+	"context"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"gorm.io/gorm"
+	"wvp/internal/core/user"
+)
+
+var _ user.UserStorer = User{}
+
+// User Related business namespaces
+type User DB
+
+// NewUser instance object
+func NewUser(db *gorm.DB) User {
+	return User{db: db}
+}
+
+// Find implements user.UserStorer.
+func (d User) Find(ctx context.Context, bs *[]*user.User, page orm.Pager, opts ...orm.QueryOption) (int64, error) {
+	return orm.FindWithContext(ctx, d.db, bs, page, opts...)
+}
+
+// Get implements user.UserStorer.
+func (d User) Get(ctx context.Context, model *user.User, opts ...orm.QueryOption) error {
+	return orm.FirstWithContext(ctx, d.db, model, opts...)
+}
+
+// Add implements user.UserStorer.
+func (d User) Add(ctx context.Context, model *user.User) error {
+	return d.db.WithContext(ctx).Create(model).Error
+}
+
+// Edit implements user.UserStorer.
+func (d User) Edit(ctx context.Context, model *user.User, changeFn func(*user.User), opts ...orm.QueryOption) error {
+	return orm.UpdateWithContext(ctx, d.db, model, changeFn, opts...)
+}
+
+// Del implements user.UserStorer.
+func (d User) Del(ctx context.Context, model *user.User, opts ...orm.QueryOption) error {
+	return orm.DeleteWithContext(ctx, d.db, model, opts...)
+}
+
+// Count implements user.UserStorer.
+func (d User) Count(ctx context.Context, opts ...orm.QueryOption) (int64, error) {
+	db := d.db.WithContext(ctx).Model(new(user.User))
+	for _, opt := range opts {
+		db = opt(db)
+	}
+	var total int64
+	err := db.Count(&total).Error
+	return total, err
+}
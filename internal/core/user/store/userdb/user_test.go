@@ -0,0 +1,51 @@
+package userdb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ixugo/goweb/pkg/orm"
+	"wvp/internal/core/user"
+)
+
+func TestUserGet(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userDB := NewUser(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE username=\$1 (.+) LIMIT \$2`).WithArgs("jack", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow("us1", "jack"))
+	var out user.User
+	if err := userDB.Get(context.Background(), &out, orm.Where("username=?", "jack")); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != "us1" {
+		t.Fatalf("expect id[us1], got[%s]", out.ID)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
+
+func TestUserCount(t *testing.T) {
+	db, mock, err := generateMockDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	userDB := NewUser(db)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	total, err := userDB.Count(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("expect total[2], got[%d]", total)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal("ExpectationsWereMet err:", err)
+	}
+}
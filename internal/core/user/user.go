@@ -0,0 +1,218 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"github.com/jinzhu/copier"
+	"golang.org/x/crypto/bcrypt"
+	"wvp/internal/core/audit"
+	"wvp/internal/core/bz"
+)
+
+// minPasswordLen 密码最小长度
+const minPasswordLen = 6
+
+// UserStorer Instantiation interface
+type UserStorer interface {
+	Find(context.Context, *[]*User, orm.Pager, ...orm.QueryOption) (int64, error)
+	Get(context.Context, *User, ...orm.QueryOption) error
+	Add(context.Context, *User) error
+	Edit(context.Context, *User, func(*User), ...orm.QueryOption) error
+	Del(context.Context, *User, ...orm.QueryOption) error
+	Count(context.Context, ...orm.QueryOption) (int64, error)
+}
+
+// FindUser Paginated search
+func (c Core) FindUser(ctx context.Context, in *FindUserInput) ([]*User, int64, error) {
+	items := make([]*User, 0)
+
+	query := orm.NewQuery(2)
+	query.OrderBy("created_at DESC")
+	if in.Key != "" {
+		query.Where("username LIKE ? OR nickname LIKE ?", "%"+in.Key+"%", "%"+in.Key+"%")
+	}
+
+	total, err := c.store.User().Find(ctx, &items, in, query.Encode()...)
+	if err != nil {
+		return nil, 0, web.ErrDB.Withf(`Find err[%s]`, err.Error())
+	}
+	return items, total, nil
+}
+
+// GetUser Query a single object
+func (c Core) GetUser(ctx context.Context, id string) (*User, error) {
+	var out User
+	if err := c.store.User().Get(ctx, &out, orm.Where("id=?", id)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrNotFound.Withf(`Get err[%s]`, err.Error())
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// AddUser Insert into database
+func (c Core) AddUser(ctx context.Context, in *AddUserInput) (*User, error) {
+	var out User
+	if err := copier.Copy(&out, in); err != nil {
+		slog.Error("Copy", "err", err)
+	}
+	hash, err := hashPassword(in.Password)
+	if err != nil {
+		return nil, err
+	}
+	out.ID = c.uniqueID.UniqueID(bz.IDPrefixUser)
+	out.Password = hash
+	out.Enabled = true
+	out.LastLoginAt = orm.Now()
+	if err := c.store.User().Add(ctx, &out); err != nil {
+		if orm.IsDuplicatedKey(err) {
+			return nil, web.ErrDB.Msg("用户名重复，请更换")
+		}
+		return nil, web.ErrDB.Withf(`Add err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// EditUser Update object information
+func (c Core) EditUser(ctx context.Context, in *EditUserInput, id string) (*User, error) {
+	audit.Record(ctx, audit.ActionUserEdit, id, in)
+	var out User
+	if err := c.store.User().Edit(ctx, &out, func(b *User) {
+		if in.Nickname != nil {
+			b.Nickname = *in.Nickname
+		}
+		if in.RoleID != nil {
+			b.RoleID = *in.RoleID
+		}
+		if in.Enabled != nil {
+			b.Enabled = *in.Enabled
+		}
+	}, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Edit err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// DelUser Delete object
+func (c Core) DelUser(ctx context.Context, id string) (*User, error) {
+	audit.Record(ctx, audit.ActionUserDelete, id, nil)
+	var out User
+	if err := c.store.User().Del(ctx, &out, orm.Where("id=?", id)); err != nil {
+		return nil, web.ErrDB.Withf(`Del err[%s]`, err.Error())
+	}
+	return &out, nil
+}
+
+// Login 校验用户名密码，成功后记录登录时间
+func (c Core) Login(ctx context.Context, in *LoginInput) (*User, error) {
+	var out User
+	if err := c.store.User().Get(ctx, &out, orm.Where("username=?", in.Username)); err != nil {
+		if orm.IsErrRecordNotFound(err) {
+			return nil, web.ErrBadRequest.Msg("用户名或密码错误")
+		}
+		return nil, web.ErrDB.Withf(`Get err[%s]`, err.Error())
+	}
+	if bcrypt.CompareHashAndPassword([]byte(out.Password), []byte(in.Password)) != nil {
+		return nil, web.ErrBadRequest.Msg("用户名或密码错误")
+	}
+	if !out.Enabled {
+		return nil, web.ErrBadRequest.Msg("用户已禁用")
+	}
+	if err := c.store.User().Edit(ctx, &out, func(b *User) {
+		b.LastLoginAt = orm.Now()
+	}, orm.Where("id=?", out.ID)); err != nil {
+		slog.Error("记录登录时间失败", "err", err)
+	}
+	return &out, nil
+}
+
+// ChangePassword 修改密码，需校验原密码
+func (c Core) ChangePassword(ctx context.Context, in *ChangePasswordInput, id string) error {
+	u, err := c.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(in.OldPassword)) != nil {
+		return web.ErrBadRequest.Msg("原密码错误")
+	}
+	hash, err := hashPassword(in.NewPassword)
+	if err != nil {
+		return err
+	}
+	var out User
+	if err := c.store.User().Edit(ctx, &out, func(b *User) {
+		b.Password = hash
+	}, orm.Where("id=?", id)); err != nil {
+		return web.ErrDB.Withf(`Edit err[%s]`, err.Error())
+	}
+	return nil
+}
+
+// InitAdmin 不存在任何用户时，创建初始管理员账号
+// 返回值 true 表示本次创建了账号
+func (c Core) InitAdmin(ctx context.Context, username, password string) (bool, error) {
+	created, err := c.initAdminRole(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	total, err := c.store.User().Count(ctx)
+	if err != nil {
+		return false, err
+	}
+	if total > 0 {
+		// 管理员角色首次创建时，将其赋予配置的管理员账号，兼容已有用户的数据
+		if created {
+			var u User
+			if err := c.store.User().Edit(ctx, &u, func(b *User) {
+				b.RoleID = RoleIDAdmin
+			}, orm.Where("username=?", username)); err != nil && !orm.IsErrRecordNotFound(err) {
+				return false, err
+			}
+		}
+		return false, nil
+	}
+	if _, err := c.AddUser(ctx, &AddUserInput{
+		Username: username,
+		Password: password,
+		Nickname: "管理员",
+		RoleID:   RoleIDAdmin,
+	}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// initAdminRole 创建内置管理员角色，返回值 true 表示本次创建
+func (c Core) initAdminRole(ctx context.Context) (bool, error) {
+	var role Role
+	err := c.store.Role().Get(ctx, &role, orm.Where("id=?", RoleIDAdmin))
+	if err == nil {
+		return false, nil
+	}
+	if !orm.IsErrRecordNotFound(err) {
+		return false, err
+	}
+	role = Role{ID: RoleIDAdmin, Name: "管理员", Remark: "内置角色，可访问全部资源", IsAdmin: true}
+	if err := c.store.Role().Add(ctx, &role); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLen {
+		return "", web.ErrBadRequest.Msg(fmt.Sprintf("密码长度不能少于 %d 位", minPasswordLen))
+	}
+	b, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", web.ErrServer.Msg(err.Error())
+	}
+	return string(b), nil
+}
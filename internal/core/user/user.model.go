@@ -0,0 +1,22 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import "github.com/ixugo/goweb/pkg/orm"
+
+// User domain model
+type User struct {
+	ID          string   `gorm:"primaryKey" json:"id"`
+	CreatedAt   orm.Time `gorm:"column:created_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`         // 创建时间
+	UpdatedAt   orm.Time `gorm:"column:updated_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`         // 更新时间
+	Username    string   `gorm:"column:username;uniqueIndex;notNull;default:'';comment:用户名" json:"username"`                                // 用户名
+	Password    string   `gorm:"column:password;notNull;default:'';comment:密码哈希" json:"-"`                                                  // 密码哈希
+	Nickname    string   `gorm:"column:nickname;notNull;default:'';comment:昵称" json:"nickname"`                                             // 昵称
+	RoleID      string   `gorm:"column:role_id;index;notNull;default:'';comment:角色 id" json:"role_id"`                                      // 角色 id
+	Enabled     bool     `gorm:"column:enabled;notNull;default:TRUE;comment:是否启用" json:"enabled"`                                           // 是否启用
+	LastLoginAt orm.Time `gorm:"column:last_login_at;notNull;type:TIMESTAMP;default:CURRENT_TIMESTAMP;comment:最后登录时间" json:"last_login_at"` // 最后登录时间
+}
+
+// TableName database table name
+func (*User) TableName() string {
+	return "users"
+}
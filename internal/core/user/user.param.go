@@ -0,0 +1,33 @@
+// Code generated by gowebx, DO AVOID EDIT.
+package user
+
+import "github.com/ixugo/goweb/pkg/web"
+
+type FindUserInput struct {
+	web.PagerFilter
+	Key string `form:"key"` // 用户名/昵称
+}
+
+// EditUserInput 未传的字段不修改
+type EditUserInput struct {
+	Nickname *string `json:"nickname"` // 昵称
+	RoleID   *string `json:"role_id"`  // 角色 id
+	Enabled  *bool   `json:"enabled"`  // 是否启用
+}
+
+type AddUserInput struct {
+	Username string `json:"username" binding:"required"` // 用户名
+	Password string `json:"password" binding:"required"` // 密码
+	Nickname string `json:"nickname"`                    // 昵称
+	RoleID   string `json:"role_id"`                     // 角色 id
+}
+
+type LoginInput struct {
+	Username string `json:"username" binding:"required"` // 用户名
+	Password string `json:"password" binding:"required"` // 密码
+}
+
+type ChangePasswordInput struct {
+	OldPassword string `json:"old_password" binding:"required"` // 原密码
+	NewPassword string `json:"new_password" binding:"required"` // 新密码
+}
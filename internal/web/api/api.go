@@ -24,6 +24,7 @@ var startRuntime = time.Now()
 func setupRouter(r *gin.Engine, uc *Usecase) {
 	uc.GB28181API.uc = uc
 	uc.SMSAPI.uc = uc
+	uc.GroupAPI.uc = uc
 	go stat.LoadTop(system.Getwd(), func(m map[string]any) {
 		_ = m
 	})
@@ -39,6 +40,7 @@ func setupRouter(r *gin.Engine, uc *Usecase) {
 			// true:记录请求响应报文
 			return uc.Conf.Server.Debug
 		}),
+		auditMiddleware(uc.AuditAPI),
 	)
 	go web.CountGoroutines(10*time.Minute, 20)
 
@@ -59,19 +61,28 @@ func setupRouter(r *gin.Engine, uc *Usecase) {
 		ctx.Redirect(http.StatusPermanentRedirect, filepath.Join(staticPrefix, "index.html"))
 	})
 
-	auth := web.AuthMiddleware(uc.Conf.Server.HTTP.JwtSecret)
+	// webhook、健康检查与指标不鉴权，其余管理接口需要登录
+	auth := authMiddleware(uc.Conf.Server.HTTP.JwtSecret, uc.UserAPI.userCore.GetPermission, uc.UserAPI.userCore.VerifyAPIKey)
 	r.GET("/health", web.WarpH(uc.getHealth))
-	r.GET("/app/metrics/api", web.WarpH(uc.getMetricsAPI))
+	r.GET("/app/metrics/api", auth, web.WarpH(uc.getMetricsAPI))
 
 	registerVersionAPI(r, uc.Version, auth)
-	statapi.Register(r)
-	registerZLMWebhookAPI(r, uc.WebHookAPI)
-	// TODO: 待增加鉴权
-	registerMediaAPI(r, uc.MediaAPI)
-	registerGB28181(r, uc.GB28181API)
-	registerProxy(r, uc.ProxyAPI)
-	registerConfig(r, uc.ConfigAPI)
-	registerSms(r, uc.SMSAPI)
+	statapi.Register(r, auth)
+	registerMetrics(r, uc)
+	registerZLMWebhookAPI(r, uc.WebHookAPI, webhookMetrics())
+	registerUser(r, uc.UserAPI, auth)
+	registerAudit(r, uc.AuditAPI, auth)
+	registerNotify(r, uc.NotifyAPI, auth)
+	registerEvent(r, uc.EventAPI, auth)
+	registerMediaAPI(r, uc.MediaAPI, auth)
+	registerGB28181(r, uc.GB28181API, auth)
+	registerProxy(r, uc.ProxyAPI, auth)
+	registerGroup(r, uc.GroupAPI, auth)
+	registerRegion(r, uc.RegionAPI, auth)
+	registerConfig(r, uc.ConfigAPI, auth)
+	registerSms(r, uc.SMSAPI, auth)
+	registerSIP(r, uc.SipServer, auth)
+	registerCluster(r, uc.Cluster, uc.SipServer)
 }
 
 type playOutput struct {
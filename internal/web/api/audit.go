@@ -0,0 +1,176 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/web"
+	"gorm.io/gorm"
+	"wvp/internal/conf"
+	"wvp/internal/core/audit"
+	"wvp/internal/core/audit/store/auditdb"
+)
+
+const (
+	// auditExportMax 单次导出的最大条数
+	auditExportMax = 100000
+	// auditCleanInterval 过期审计日志的清理间隔
+	auditCleanInterval = time.Hour
+)
+
+type AuditAPI struct {
+	auditCore audit.Core
+	conf      *conf.Bootstrap
+}
+
+func NewAuditAPI(db *gorm.DB, cfg *conf.Bootstrap) AuditAPI {
+	core := audit.NewCore(auditdb.NewDB(db).AutoMigrate(true))
+	if retention := cfg.Server.Audit.Retention.Duration(); retention > 0 {
+		go cleanAuditLog(core, retention)
+	}
+	return AuditAPI{auditCore: core, conf: cfg}
+}
+
+func registerAudit(g gin.IRouter, api AuditAPI, handler ...gin.HandlerFunc) {
+	group := g.Group("/audit_logs", handler...)
+	group.Use(adminMiddleware)
+	group.GET("", web.WarpH(api.findAuditLog))
+	group.GET("/export", api.exportAuditLog)
+}
+
+// cleanAuditLog 定时删除超过保留时长的审计日志
+func cleanAuditLog(core audit.Core, retention time.Duration) {
+	ticker := time.NewTicker(auditCleanInterval)
+	defer ticker.Stop()
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		if err := core.DelExpired(ctx, time.Now().Add(-retention)); err != nil {
+			slog.Error("清理审计日志失败", "err", err)
+		}
+		cancel()
+		<-ticker.C
+	}
+}
+
+// auditMiddleware 记录登录用户的操作
+// 写操作默认记录，查询操作仅记录业务层通过 audit.Record 显式标记的请求
+func auditMiddleware(api AuditAPI) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !api.conf.Server.Audit.Enabled {
+			c.Next()
+			return
+		}
+		entry := new(audit.Entry)
+		c.Request = c.Request.WithContext(audit.NewContext(c.Request.Context(), entry))
+		c.Next()
+
+		uid := getUserID(c)
+		if uid == "" {
+			return
+		}
+		action, targetID, params := entry.Get()
+		if action == "" {
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				return
+			}
+			action = c.Request.Method + " " + c.FullPath()
+			targetID = c.Param("id")
+		}
+		code := c.Writer.Status()
+		result, reason := entry.Result()
+		if result == "" {
+			result = audit.ResultSuccess
+			if code >= http.StatusBadRequest {
+				result = audit.ResultFailure
+			}
+		}
+		log := audit.AuditLog{
+			UserID:   uid,
+			Username: c.GetString(ctxKeyUsername),
+			Action:   action,
+			TargetID: targetID,
+			Params:   params,
+			Method:   c.Request.Method,
+			Path:     c.Request.URL.Path,
+			IP:       c.ClientIP(),
+			Result:   result,
+			Reason:   reason,
+			Code:     code,
+		}
+		if ak := getAPIKey(c); ak != nil {
+			log.APIKeyID, log.APIKeyName = ak.ID, ak.Name
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := api.auditCore.AddAuditLog(ctx, &log); err != nil {
+				slog.Error("记录审计日志失败", "action", log.Action, "err", err)
+			}
+		}()
+	}
+}
+
+// >>> audit log >>>>>>>>>>>>>>>>>>>>
+
+func (a AuditAPI) findAuditLog(c *gin.Context, in *audit.FindAuditLogInput) (any, error) {
+	items, total, err := a.auditCore.FindAuditLog(c.Request.Context(), in)
+	return gin.H{"items": items, "total": total}, err
+}
+
+// exportAuditLog 按查询条件导出 csv
+func (a AuditAPI) exportAuditLog(c *gin.Context) {
+	var in audit.FindAuditLogInput
+	if err := c.ShouldBindQuery(&in); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"reason": "bad_request", "msg": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("audit_logs_%s.csv", time.Now().Format("20060102150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	// 写入 BOM，避免 excel 打开中文乱码
+	_, _ = c.Writer.WriteString("\xEF\xBB\xBF")
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"id", "created_at", "user_id", "username", "api_key_id", "api_key_name", "action", "target_id", "params", "method", "path", "ip", "result", "reason", "code"})
+
+	in.Size = 500
+	for in.Page = 1; (in.Page-1)*in.Size < auditExportMax; in.Page++ {
+		items, _, err := a.auditCore.FindAuditLog(c.Request.Context(), &in)
+		if err != nil {
+			slog.Error("导出审计日志失败", "err", err)
+			break
+		}
+		for _, v := range items {
+			_ = w.Write([]string{
+				strconv.FormatInt(v.ID, 10),
+				v.CreatedAt.Format(time.DateTime),
+				v.UserID,
+				v.Username,
+				v.APIKeyID,
+				v.APIKeyName,
+				v.Action,
+				v.TargetID,
+				v.Params,
+				v.Method,
+				v.Path,
+				v.IP,
+				v.Result,
+				v.Reason,
+				strconv.Itoa(v.Code),
+			})
+		}
+		w.Flush()
+		if len(items) < in.Size {
+			break
+		}
+	}
+	w.Flush()
+}
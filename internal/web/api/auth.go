@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/user"
+)
+
+// defaultTokenTTL 未配置有效期时的默认值
+const defaultTokenTTL = 24 * time.Hour
+
+// 上下文中保存的登录信息
+const (
+	ctxKeyUserID     = "uid"
+	ctxKeyUsername   = "username"
+	ctxKeyPermission = "permission"
+	ctxKeyAPIKey     = "api_key"
+)
+
+// headerAPIKey 使用 API Key 访问时的请求头
+const headerAPIKey = "X-API-Key"
+
+// PermissionLoader 根据用户 id 加载数据权限
+type PermissionLoader func(ctx context.Context, uid string) (*user.Permission, error)
+
+// APIKeyVerifier 校验 API Key 及来源 IP
+type APIKeyVerifier func(ctx context.Context, key, ip string) (*user.APIKey, error)
+
+// UserClaims 登录令牌载荷
+type UserClaims struct {
+	UserID   string `json:"uid"`
+	Username string `json:"username"`
+	jwt.RegisteredClaims
+}
+
+// newToken 签发登录令牌
+func newToken(uid, username, secret string, ttl time.Duration) (string, time.Time, error) {
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	claims := UserClaims{
+		UserID:   uid,
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	return token, expiresAt, err
+}
+
+// parseToken 解析并校验登录令牌
+func parseToken(token, secret string) (*UserClaims, error) {
+	var claims UserClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// getToken 优先从请求头获取令牌，websocket 等无法设置请求头的场景从 query 获取
+func getToken(c *gin.Context) string {
+	if v := c.GetHeader("Authorization"); v != "" {
+		return strings.TrimPrefix(v, "Bearer ")
+	}
+	return c.Query("token")
+}
+
+// authMiddleware 登录鉴权，并加载用户的数据权限
+// 支持 JWT 令牌与 API Key 两种方式
+func authMiddleware(secret string, loadPermission PermissionLoader, verifyAPIKey APIKeyVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.GetHeader(headerAPIKey); key != "" {
+			authAPIKey(c, key, loadPermission, verifyAPIKey)
+			return
+		}
+		token := getToken(c)
+		if token == "" {
+			abortUnauthorized(c, "请先登录")
+			return
+		}
+		claims, err := parseToken(token, secret)
+		if err != nil {
+			abortUnauthorized(c, "登录已失效，请重新登录")
+			return
+		}
+		perm, err := loadPermission(c.Request.Context(), claims.UserID)
+		if err != nil {
+			slog.Info("加载用户权限失败", "uid", claims.UserID, "err", err)
+			abortUnauthorized(c, "登录已失效，请重新登录")
+			return
+		}
+		c.Set(ctxKeyUserID, claims.UserID)
+		c.Set(ctxKeyUsername, claims.Username)
+		c.Set(ctxKeyPermission, perm)
+		c.Next()
+	}
+}
+
+// authAPIKey 校验 API Key 及其权限范围，以密钥所属用户的身份访问
+func authAPIKey(c *gin.Context, key string, loadPermission PermissionLoader, verifyAPIKey APIKeyVerifier) {
+	ak, err := verifyAPIKey(c.Request.Context(), key, c.ClientIP())
+	if err != nil {
+		slog.Info("API Key 校验失败", "ip", c.ClientIP(), "err", err)
+		abortUnauthorized(c, "API Key 无效")
+		return
+	}
+	if scope := requiredScope(c); !ak.HasScope(scope) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"reason": "forbidden", "msg": "API Key 缺少权限 " + scope})
+		return
+	}
+	perm, err := loadPermission(c.Request.Context(), ak.UserID)
+	if err != nil {
+		slog.Info("加载用户权限失败", "uid", ak.UserID, "err", err)
+		abortUnauthorized(c, "API Key 无效")
+		return
+	}
+	c.Set(ctxKeyUserID, ak.UserID)
+	c.Set(ctxKeyUsername, perm.Username)
+	c.Set(ctxKeyPermission, perm)
+	c.Set(ctxKeyAPIKey, ak)
+	c.Next()
+}
+
+// requiredScope 根据请求判断 API Key 需要的权限范围
+func requiredScope(c *gin.Context) string {
+	path := c.FullPath()
+	switch {
+	case strings.HasSuffix(path, "/play"):
+		return user.ScopePlay
+	case strings.Contains(path, "/ptz"):
+		return user.ScopePTZ
+	case c.Request.Method == http.MethodGet:
+		return user.ScopeRead
+	}
+	return user.ScopeAdmin
+}
+
+// errNoPermission 无数据权限
+var errNoPermission = web.ErrNotFound.Msg("资源不存在或无权限访问")
+
+// checkScope 校验当前用户是否可访问该资源
+func checkScope(c *gin.Context, resourceType, id string) error {
+	if !getPermission(c).Scope(resourceType).Contains(id) {
+		return errNoPermission
+	}
+	return nil
+}
+
+// adminMiddleware 仅允许管理员访问，需在 authMiddleware 之后使用
+func adminMiddleware(c *gin.Context) {
+	if perm := getPermission(c); perm == nil || !perm.IsAdmin {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"reason": "forbidden", "msg": "无权限访问"})
+		return
+	}
+	c.Next()
+}
+
+func abortUnauthorized(c *gin.Context, msg string) {
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"reason": "unauthorized", "msg": msg})
+}
+
+// getPermission 获取当前登录用户的数据权限
+func getPermission(c *gin.Context) *user.Permission {
+	v, _ := c.Get(ctxKeyPermission)
+	perm, _ := v.(*user.Permission)
+	return perm
+}
+
+// getAPIKey 获取本次请求使用的 API Key，使用登录令牌访问时返回 nil
+func getAPIKey(c *gin.Context) *user.APIKey {
+	v, _ := c.Get(ctxKeyAPIKey)
+	ak, _ := v.(*user.APIKey)
+	return ak
+}
+
+// getUserID 获取当前登录用户 id
+func getUserID(c *gin.Context) string {
+	return c.GetString(ctxKeyUserID)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+	"wvp/internal/conf"
+	"wvp/internal/core/cluster"
+	"wvp/pkg/gbs"
+	"wvp/pkg/redis"
+)
+
+// NewRedisClient 共享的 redis 连接池，首次使用时建立连接
+func NewRedisClient(bc *conf.Bootstrap) *redis.Client {
+	r := bc.Data.Redis
+	return redis.NewClient(redis.Config{Addr: r.Addr, Password: r.Password, DB: r.DB})
+}
+
+// NewCluster 集群部署时注册实例并参与选主
+// 租约续期使用独立连接，避免共享连接池繁忙时续期超时导致失去主节点
+func NewCluster(bc *conf.Bootstrap) (*cluster.Cluster, func()) {
+	cfg := bc.Cluster
+	if !cfg.Enabled {
+		return cluster.New(cluster.Config{}, nil), func() {}
+	}
+	if cfg.NodeID == "" {
+		cfg.NodeID, _ = os.Hostname()
+	}
+	if cfg.Advertise == "" || cfg.Secret == "" {
+		slog.Warn("集群未配置 Advertise 或 Secret，其它实例无法转发请求到本实例")
+	}
+	if bc.Sip.Session.Store == "memory" {
+		slog.Warn("集群部署时点播会话使用内存存储，实例重启后无法恢复会话")
+	}
+	r := bc.Data.Redis
+	cli := redis.NewClient(redis.Config{Addr: r.Addr, Password: r.Password, DB: r.DB, PoolSize: 1})
+	c := cluster.New(cluster.Config{
+		Enabled:   true,
+		NodeID:    cfg.NodeID,
+		Advertise: cfg.Advertise,
+		Secret:    cfg.Secret,
+		LeaseTTL:  cfg.LeaseTTL.Duration(),
+	}, cluster.NewRedisBackend(cli, r.Prefix))
+	c.Start()
+	slog.Info("集群已启用", "node", cfg.NodeID, "advertise", cfg.Advertise)
+	return c, func() {
+		c.Close()
+		_ = cli.Close()
+	}
+}
+
+// registerCluster 实例间转发接口，使用集群密钥鉴权
+func registerCluster(g gin.IRouter, c *cluster.Cluster, svr *gbs.Server) {
+	if !c.Enabled() {
+		return
+	}
+	g.POST(cluster.ForwardPrefix+"/*path", func(ctx *gin.Context) {
+		if !c.VerifySecret(ctx.GetHeader("Authorization")) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"reason": "unauthorized", "msg": "invalid cluster secret"})
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, 1<<20))
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"reason": "bad_request", "msg": err.Error()})
+			return
+		}
+		out, err := svr.ServeForward(ctx.Param("path"), body)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"reason": "bad_request", "msg": err.Error()})
+			return
+		}
+		ctx.JSON(http.StatusOK, out)
+	})
+}
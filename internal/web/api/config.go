@@ -9,6 +9,7 @@ import (
 	"github.com/jinzhu/copier"
 	"gorm.io/gorm"
 	"wvp/internal/conf"
+	"wvp/internal/core/audit"
 	"wvp/internal/core/config"
 	"wvp/internal/core/config/store/configdb"
 )
@@ -33,7 +34,7 @@ func registerConfig(g gin.IRouter, api ConfigAPI, handler ...gin.HandlerFunc) {
 		// group.DELETE("/:id", web.WarpH(api.delConfig))
 
 		group.GET("/info", web.WarpH(api.getConfigInfo))
-		group.PUT("/info/sip", web.WarpH(api.editSIP))
+		group.PUT("/info/sip", adminMiddleware, web.WarpH(api.editSIP))
 	}
 }
 
@@ -73,7 +74,10 @@ func (a ConfigAPI) getConfigInfo(c *gin.Context, _ *struct{}) (*getConfigInfoOut
 	}, nil
 }
 
-func (a ConfigAPI) editSIP(_ *gin.Context, in *conf.SIP) (gin.H, error) {
+func (a ConfigAPI) editSIP(c *gin.Context, in *conf.SIP) (gin.H, error) {
+	params := *in
+	params.Password = ""
+	audit.Record(c.Request.Context(), audit.ActionConfigEdit, "sip", params)
 	sip := a.conf.Sip
 	if err := copier.Copy(&sip, in); err != nil {
 		return nil, web.ErrServer.Msg(err.Error())
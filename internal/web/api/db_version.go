@@ -2,6 +2,6 @@ package api
 
 // 如果需要执行表迁移，递增此版本号和表更新说明
 var (
-	dbVersion = "0.0.10"
-	dbRemark  = "add stream proxy"
+	dbVersion = "0.0.23"
+	dbRemark  = "add channels (device_id, channel_id) unique index"
 )
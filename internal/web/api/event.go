@@ -0,0 +1,217 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"wvp/internal/core/bz"
+	"wvp/internal/core/event"
+	"wvp/internal/core/user"
+)
+
+const (
+	// eventHeartbeatInterval 心跳间隔，避免代理服务器关闭空闲连接
+	eventHeartbeatInterval = 15 * time.Second
+	// eventBufferSize 每个连接缓存的事件数，客户端消费过慢时丢弃
+	eventBufferSize    = 64
+	eventTypeHeartbeat = "heartbeat"
+)
+
+type EventAPI struct {
+	bus *event.Bus
+}
+
+func NewEventAPI(bus *event.Bus) EventAPI {
+	return EventAPI{bus: bus}
+}
+
+func registerEvent(g gin.IRouter, api EventAPI, handler ...gin.HandlerFunc) {
+	group := g.Group("/events", handler...)
+	group.GET("", api.sse)
+	group.GET("/ws", api.ws)
+}
+
+// eventFilter 连接级别的事件过滤条件
+type eventFilter struct {
+	mu        sync.RWMutex
+	types     []string // 事件类型，为空不过滤
+	deviceIDs []string // 设备 id 或国标编码，为空不过滤
+	perm      *user.Permission
+}
+
+// eventFilterInput 查询参数以逗号分隔多个值，websocket 连接可发送 json 修改过滤条件
+type eventFilterInput struct {
+	Types     []string `json:"types"`
+	DeviceIDs []string `json:"device_ids"`
+}
+
+func newEventFilter(c *gin.Context) *eventFilter {
+	f := eventFilter{perm: getPermission(c)}
+	f.set(splitQuery(c.Query("types")), splitQuery(c.Query("device_id")))
+	return &f
+}
+
+func (f *eventFilter) set(types, deviceIDs []string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.types, f.deviceIDs = types, deviceIDs
+}
+
+func (f *eventFilter) match(e event.Event) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.types) > 0 && !slices.Contains(f.types, e.Type) {
+		return false
+	}
+	did, deviceID := event.DeviceOf(e)
+	if len(f.deviceIDs) > 0 && !slices.ContainsFunc(f.deviceIDs, func(v string) bool {
+		return v != "" && (v == did || v == deviceID)
+	}) {
+		return false
+	}
+	return f.allow(e, did)
+}
+
+// allow 数据权限校验，受限用户仅可接收已授权资源的事件，媒体服务器事件仅管理员可接收
+func (f *eventFilter) allow(e event.Event, did string) bool {
+	if f.perm != nil && f.perm.IsAdmin {
+		return true
+	}
+	switch v := e.Data.(type) {
+	case event.Device, event.Catalog, event.Alarm:
+		return did != "" && f.perm.Scope(bz.ResourceDevice).Contains(did)
+	case event.Channel:
+		scope := f.perm.Scope(bz.ResourceChannel)
+		return scope.Contains(v.ID) || scope.ContainsParent(v.DID)
+	case event.Stream:
+		if v.ResourceType == "" {
+			return false
+		}
+		scope := f.perm.Scope(v.ResourceType)
+		return scope.Contains(v.ResourceID) || (v.ResourceType == bz.ResourceChannel && scope.ContainsParent(v.DID))
+	case event.Proxy:
+		return f.perm.Scope(bz.ResourceProxy).Contains(v.ID)
+	}
+	return false
+}
+
+// subscribe 订阅符合条件的事件
+func (a EventAPI) subscribe(f *eventFilter) (<-chan event.Event, func()) {
+	ch := make(chan event.Event, eventBufferSize)
+	cancel := a.bus.Subscribe(func(e event.Event) {
+		if !f.match(e) {
+			return
+		}
+		select {
+		case ch <- e:
+		default:
+			slog.Warn("客户端消费事件过慢，丢弃事件", "type", e.Type, "id", e.ID)
+		}
+	})
+	return ch, cancel
+}
+
+// sse 以 Server-Sent Events 推送事件
+func (a EventAPI) sse(c *gin.Context) {
+	f := newEventFilter(c)
+	ch, cancel := a.subscribe(f)
+	defer cancel()
+
+	// 长连接不受服务端写超时限制
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	ticker := time.NewTicker(eventHeartbeatInterval)
+	defer ticker.Stop()
+	c.Stream(func(_ io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case e := <-ch:
+			c.Render(-1, sse.Event{Id: e.ID, Event: e.Type, Data: e})
+		case <-ticker.C:
+			c.Render(-1, sse.Event{Event: eventTypeHeartbeat, Data: event.NewEvent(eventTypeHeartbeat, nil)})
+		}
+		return true
+	})
+}
+
+// ws 以 websocket 推送事件，鉴权令牌通过 query 参数 token 传递
+func (a EventAPI) ws(c *gin.Context) {
+	f := newEventFilter(c)
+	srv := websocket.Server{
+		// 已完成登录鉴权，不再校验 Origin
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			a.serveWS(ws, f)
+		},
+	}
+	srv.ServeHTTP(c.Writer, c.Request)
+}
+
+func (a EventAPI) serveWS(ws *websocket.Conn, f *eventFilter) {
+	defer ws.Close()
+	// 连接已被接管，清除 http 服务设置的读写超时
+	_ = ws.SetDeadline(time.Time{})
+
+	ch, cancel := a.subscribe(f)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var b []byte
+			if err := websocket.Message.Receive(ws, &b); err != nil {
+				return
+			}
+			var in eventFilterInput
+			if err := json.Unmarshal(b, &in); err != nil {
+				continue
+			}
+			f.set(in.Types, in.DeviceIDs)
+		}
+	}()
+
+	ticker := time.NewTicker(eventHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		var err error
+		select {
+		case <-done:
+			return
+		case e := <-ch:
+			err = websocket.JSON.Send(ws, e)
+		case <-ticker.C:
+			err = websocket.JSON.Send(ws, event.NewEvent(eventTypeHeartbeat, nil))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func splitQuery(s string) []string {
+	if s == "" {
+		return nil
+	}
+	out := make([]string, 0, 2)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"testing"
+
+	"wvp/internal/core/bz"
+	"wvp/internal/core/event"
+	"wvp/internal/core/user"
+)
+
+func TestEventFilter(t *testing.T) {
+	admin := &user.Permission{IsAdmin: true}
+	f := eventFilter{perm: admin}
+	f.set([]string{event.TypeDeviceOnline}, []string{"34020000001320000001"})
+
+	online := event.NewEvent(event.TypeDeviceOnline, event.Device{ID: "gb1", DeviceID: "34020000001320000001"})
+	if !f.match(online) {
+		t.Fatal("expect match")
+	}
+	if f.match(event.NewEvent(event.TypeDeviceOffline, event.Device{ID: "gb1", DeviceID: "34020000001320000001"})) {
+		t.Fatal("type filter not applied")
+	}
+	if f.match(event.NewEvent(event.TypeDeviceOnline, event.Device{ID: "gb2", DeviceID: "34020000001320000002"})) {
+		t.Fatal("device filter not applied")
+	}
+
+	// 受限用户无授权时，不可接收设备事件
+	limited := eventFilter{perm: &user.Permission{}}
+	if limited.match(online) {
+		t.Fatal("limited user should not receive ungranted device event")
+	}
+	if limited.match(event.NewEvent(event.TypeMediaServer, event.MediaServer{ID: "local"})) {
+		t.Fatal("limited user should not receive media server event")
+	}
+}
+
+func TestEventFilterScope(t *testing.T) {
+	f := eventFilter{perm: user.NewPermission("u1", map[string][]string{
+		bz.ResourceDevice: {"gb1"},
+		bz.ResourcePush:   {"m1"},
+		bz.ResourceProxy:  {"p1"},
+	})}
+	cases := []struct {
+		name   string
+		event  event.Event
+		expect bool
+	}{
+		{"granted device alarm", event.NewEvent(event.TypeAlarm, event.Alarm{DID: "gb1"}), true},
+		{"ungranted device alarm", event.NewEvent(event.TypeAlarm, event.Alarm{DID: "gb2"}), false},
+		{"unknown device alarm", event.NewEvent(event.TypeAlarm, event.Alarm{DeviceID: "34020000001320000001"}), false},
+		{"channel stream of granted device", event.NewEvent(event.TypeStreamPublish, event.Stream{ResourceType: bz.ResourceChannel, ResourceID: "ch1", DID: "gb1"}), true},
+		{"channel stream of ungranted device", event.NewEvent(event.TypeStreamPublish, event.Stream{ResourceType: bz.ResourceChannel, ResourceID: "ch2", DID: "gb2"}), false},
+		{"granted push stream", event.NewEvent(event.TypeStreamPublish, event.Stream{ResourceType: bz.ResourcePush, ResourceID: "m1"}), true},
+		{"ungranted proxy stream", event.NewEvent(event.TypeStreamUnpublish, event.Stream{ResourceType: bz.ResourceProxy, ResourceID: "p2"}), false},
+		{"unknown stream", event.NewEvent(event.TypeStreamPublish, event.Stream{App: "live", Stream: "test"}), false},
+		{"granted proxy state", event.NewEvent(event.TypeProxyState, event.Proxy{ID: "p1"}), true},
+		{"ungranted proxy state", event.NewEvent(event.TypeProxyState, event.Proxy{ID: "p2"}), false},
+	}
+	for _, c := range cases {
+		if v := f.match(c.event); v != c.expect {
+			t.Errorf("%s: expect %v, got %v", c.name, c.expect, v)
+		}
+	}
+}
+
+func TestSplitQuery(t *testing.T) {
+	if v := splitQuery(" a, ,b"); len(v) != 2 || v[0] != "a" || v[1] != "b" {
+		t.Fatalf("unexpected %v", v)
+	}
+	if splitQuery("") != nil {
+		t.Fatal("expect nil")
+	}
+}
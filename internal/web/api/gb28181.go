@@ -9,7 +9,8 @@ import (
 	"github.com/ixugo/goweb/pkg/web"
 	"wvp/internal/core/bz"
 	"wvp/internal/core/gb28181"
-	"wvp/internal/core/media"
+	"wvp/internal/core/proxy"
+	"wvp/internal/core/region"
 	"wvp/internal/core/sms"
 	"wvp/internal/core/uniqueid"
 	"wvp/pkg/gbs"
@@ -25,8 +26,8 @@ func NewGB28181API(core gb28181.Core) GB28181API {
 	return GB28181API{gb28181Core: core}
 }
 
-func NewGB28181Core(store gb28181.Storer, uni uniqueid.Core) gb28181.Core {
-	return gb28181.NewCore(store, uni)
+func NewGB28181Core(store gb28181.Storer, uni uniqueid.Core, regions *region.Registry) gb28181.Core {
+	return gb28181.NewCore(store, uni, regions)
 }
 
 func registerGB28181(g gin.IRouter, api GB28181API, handler ...gin.HandlerFunc) {
@@ -35,10 +36,24 @@ func registerGB28181(g gin.IRouter, api GB28181API, handler ...gin.HandlerFunc)
 		group.GET("", web.WarpH(api.findDevice))
 		group.GET("/:id", web.WarpH(api.getDevice))
 		group.PUT("/:id", web.WarpH(api.editDevice))
-		group.POST("", web.WarpH(api.addDevice))
+		group.POST("", adminMiddleware, web.WarpH(api.addDevice)) // 受限用户无法看到新设备，仅管理员可添加
 		group.DELETE("/:id", web.WarpH(api.delDevice))
 
-		group.POST("/:id/catalog", web.WarpH(api.queryCatalog)) // 刷新通道
+		group.POST("/:id/catalog", web.WarpH(api.queryCatalog))               // 刷新通道
+		group.GET("/:id/catalog_tree", web.WarpH(api.getCatalogTree))         // 目录树
+		group.GET("/:id/catalog_progress", web.WarpH(api.getCatalogProgress)) // 目录接收进度
+
+		// 信令抓包
+		trace := group.Group("/:id/sip_trace", adminMiddleware)
+		trace.GET("", web.WarpH(api.findSIPTrace))
+		trace.GET("/ws", api.tailSIPTrace)
+		trace.GET("/pcap", api.exportSIPTrace)
+
+		// 待审批设备
+		pending := group.Group("/pending", adminMiddleware)
+		pending.GET("", web.WarpH(api.findPendingDevice))
+		pending.POST("/:id/approve", web.WarpH(api.approvePendingDevice))
+		pending.DELETE("/:id", web.WarpH(api.delPendingDevice))
 	}
 
 	{
@@ -55,17 +70,24 @@ func registerGB28181(g gin.IRouter, api GB28181API, handler ...gin.HandlerFunc)
 // >>> device >>>>>>>>>>>>>>>>>>>>
 
 func (a GB28181API) findDevice(c *gin.Context, in *gb28181.FindDeviceInput) (any, error) {
+	in.Scope = getPermission(c).Scope(bz.ResourceDevice)
 	items, total, err := a.gb28181Core.FindDevice(c.Request.Context(), in)
 	return gin.H{"items": items, "total": total}, err
 }
 
 func (a GB28181API) getDevice(c *gin.Context, _ *struct{}) (any, error) {
 	deviceID := c.Param("id")
+	if err := checkScope(c, bz.ResourceDevice, deviceID); err != nil {
+		return nil, err
+	}
 	return a.gb28181Core.GetDevice(c.Request.Context(), deviceID)
 }
 
 func (a GB28181API) editDevice(c *gin.Context, in *gb28181.EditDeviceInput) (any, error) {
 	deviceID := c.Param("id")
+	if err := checkScope(c, bz.ResourceDevice, deviceID); err != nil {
+		return nil, err
+	}
 	return a.gb28181Core.EditDevice(c.Request.Context(), in, deviceID)
 }
 
@@ -75,24 +97,112 @@ func (a GB28181API) addDevice(c *gin.Context, in *gb28181.AddDeviceInput) (any,
 
 func (a GB28181API) delDevice(c *gin.Context, _ *struct{}) (any, error) {
 	did := c.Param("id")
+	if err := checkScope(c, bz.ResourceDevice, did); err != nil {
+		return nil, err
+	}
 	return a.gb28181Core.DelDevice(c.Request.Context(), did)
 }
 
 func (a GB28181API) queryCatalog(c *gin.Context, _ *struct{}) (any, error) {
 	did := c.Param("id")
-	if err := a.uc.SipServer.QueryCatalog(did); err != nil {
+	if err := a.checkDeviceID(c, did); err != nil {
+		return nil, err
+	}
+	diff, err := a.uc.SipServer.QueryCatalog(did)
+	if err != nil {
 		return nil, web.ErrDevice.Msg(err.Error())
 	}
-	return gin.H{"msg": "ok"}, nil
+	// 等待超时时 diff 为空，目录将在接收完成后继续核对
+	return gin.H{"msg": "ok", "diff": diff}, nil
+}
+
+// getCatalogProgress 刷新通道后查询目录接收进度，received/total
+func (a GB28181API) getCatalogProgress(c *gin.Context, _ *struct{}) (any, error) {
+	did := c.Param("id")
+	if err := a.checkDeviceID(c, did); err != nil {
+		return nil, err
+	}
+	out, err := a.uc.SipServer.CatalogProgress(did)
+	if err != nil {
+		return nil, web.ErrNotFound.Msg(err.Error())
+	}
+	return out, nil
+}
+
+func (a GB28181API) getCatalogTree(c *gin.Context, _ *struct{}) (any, error) {
+	did := c.Param("id")
+	if err := checkScope(c, bz.ResourceDevice, did); err != nil {
+		return nil, err
+	}
+	return a.gb28181Core.CatalogTree(c.Request.Context(), did)
+}
+
+func (a GB28181API) findPendingDevice(c *gin.Context, in *gb28181.FindPendingDeviceInput) (any, error) {
+	items, total, err := a.gb28181Core.FindPendingDevice(c.Request.Context(), in)
+	return gin.H{"items": items, "total": total}, err
+}
+
+func (a GB28181API) approvePendingDevice(c *gin.Context, in *gb28181.ApprovePendingDeviceInput) (any, error) {
+	id := c.Param("id")
+	return a.gb28181Core.ApprovePendingDevice(c.Request.Context(), in, id)
+}
+
+func (a GB28181API) delPendingDevice(c *gin.Context, _ *struct{}) (any, error) {
+	id := c.Param("id")
+	return a.gb28181Core.DelPendingDevice(c.Request.Context(), id)
+}
+
+// checkDeviceID 按国标编码校验设备数据权限，授权记录保存的是设备 id
+func (a GB28181API) checkDeviceID(c *gin.Context, deviceID string) error {
+	scope := getPermission(c).Scope(bz.ResourceDevice)
+	if !scope.Limited {
+		return nil
+	}
+	dev, err := a.gb28181Core.GetDeviceByDeviceID(c.Request.Context(), deviceID)
+	if err != nil {
+		return err
+	}
+	if !scope.Contains(dev.ID) {
+		return errNoPermission
+	}
+	return nil
 }
 
 // >>> channel >>>>>>>>>>>>>>>>>>>>
 
 func (a GB28181API) findChannel(c *gin.Context, in *gb28181.FindChannelInput) (any, error) {
+	in.Scope = getPermission(c).Scope(bz.ResourceChannel)
+	if in.GroupID != "" {
+		ids, err := a.uc.GroupAPI.groupCore.SubtreeIDs(c.Request.Context(), in.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		in.GroupIDs = ids
+	}
 	items, total, err := a.gb28181Core.FindChannel(c.Request.Context(), in)
 	return gin.H{"items": items, "total": total}, err
 }
 
+// checkChannel 校验通道数据权限，授权通道所属设备后同样可访问
+func (a GB28181API) checkChannel(c *gin.Context, channelID string) error {
+	scope := getPermission(c).Scope(bz.ResourceChannel)
+	if scope.Contains(channelID) {
+		return nil
+	}
+	ch, err := a.gb28181Core.GetChannel(c.Request.Context(), channelID)
+	if err != nil {
+		return err
+	}
+	dev, err := a.gb28181Core.GetDeviceByDeviceID(c.Request.Context(), ch.DeviceID)
+	if err != nil {
+		return err
+	}
+	if !scope.ContainsParent(dev.ID) {
+		return errNoPermission
+	}
+	return nil
+}
+
 // func (a GB28181API) getChannel(c *gin.Context, _ *struct{}) (any, error) {
 // 	channelID := c.Param("id")
 // 	return a.gb28181Core.GetChannel(c.Request.Context(), channelID)
@@ -100,6 +210,9 @@ func (a GB28181API) findChannel(c *gin.Context, in *gb28181.FindChannelInput) (a
 
 func (a GB28181API) editChannel(c *gin.Context, in *gb28181.EditChannelInput) (any, error) {
 	cid := c.Param("id")
+	if err := a.checkChannel(c, cid); err != nil {
+		return nil, err
+	}
 	return a.gb28181Core.EditChannel(c.Request.Context(), in, cid)
 }
 
@@ -115,7 +228,7 @@ func (a GB28181API) editChannel(c *gin.Context, in *gb28181.EditChannelInput) (a
 func (a GB28181API) play(c *gin.Context, _ *struct{}) (*playOutput, error) {
 	channelID := c.Param("id")
 
-	var app, appStream, host, stream, session string
+	var app, appStream, host, stream string
 	var svr *sms.MediaServer
 
 	// 国标逻辑
@@ -126,20 +239,23 @@ func (a GB28181API) play(c *gin.Context, _ *struct{}) (*playOutput, error) {
 			return nil, err
 		}
 
-		app = "rtp"
-		appStream = ch.ID
-
-		svr, err = a.uc.SMSAPI.smsCore.GetMediaServer(c.Request.Context(), sms.DefaultMediaServerID)
+		dev, err := a.gb28181Core.GetDeviceByDeviceID(c.Request.Context(), ch.DeviceID)
 		if err != nil {
 			return nil, err
 		}
+		if scope := getPermission(c).Scope(bz.ResourceChannel); !scope.Contains(ch.ID) && !scope.ContainsParent(dev.ID) {
+			return nil, errNoPermission
+		}
 
-		dev, err := a.gb28181Core.GetDeviceByDeviceID(c.Request.Context(), ch.DeviceID)
+		app = "rtp"
+		appStream = ch.ID
+
+		svr, err = a.uc.SMSAPI.smsCore.GetMediaServer(c.Request.Context(), sms.DefaultMediaServerID)
 		if err != nil {
 			return nil, err
 		}
 
-		if err := a.uc.SipServer.Play(&gbs.PlayInput{
+		if err := a.uc.SipServer.Play(c.Request.Context(), &gbs.PlayInput{
 			Channel:    ch,
 			StreamMode: dev.StreamMode,
 			SMS:        svr,
@@ -147,13 +263,13 @@ func (a GB28181API) play(c *gin.Context, _ *struct{}) (*playOutput, error) {
 			return nil, web.ErrDevice.Msg(err.Error())
 		}
 	} else if strings.HasPrefix(channelID, bz.IDPrefixRTMP) {
-		push, err := a.uc.MediaAPI.mediaCore.GetStreamPush(c.Request.Context(), channelID)
+		if !getPermission(c).Scope(bz.ResourcePush).Contains(channelID) {
+			return nil, errNoPermission
+		}
+		push, err := a.uc.MediaAPI.mediaCore.PlayStreamPush(c.Request.Context(), channelID)
 		if err != nil {
 			return nil, err
 		}
-		if push.Status != media.StatusPushing {
-			return nil, web.ErrNotFound.Msg("未推流")
-		}
 		app = push.App
 		appStream = push.Stream
 
@@ -161,41 +277,41 @@ func (a GB28181API) play(c *gin.Context, _ *struct{}) (*playOutput, error) {
 		if err != nil {
 			return nil, err
 		}
-
-		if !push.IsAuthDisabled && push.Session != "" {
-			session = "session=" + push.Session
-		}
 	} else if strings.HasPrefix(channelID, bz.IDPrefixRTSP) {
-		proxy, err := a.uc.ProxyAPI.proxyCore.GetStreamProxy(c.Request.Context(), channelID)
-		if err != nil {
-			return nil, err
+		if !getPermission(c).Scope(bz.ResourceProxy).Contains(channelID) {
+			return nil, errNoPermission
 		}
-		app = proxy.App
-		appStream = proxy.Stream
-
-		svr, err = a.uc.SMSAPI.smsCore.GetMediaServer(c.Request.Context(), sms.DefaultMediaServerID)
-		if err != nil {
-			return nil, err
-		}
-		resp, err := a.uc.SMSAPI.smsCore.AddStreamProxy(svr, zlm.AddStreamProxyRequest{
-			Vhost:      "__defaultVhost__",
-			App:        proxy.App,
-			Stream:     proxy.Stream,
-			URL:        proxy.SourceURL,
-			RetryCount: 3,
-			RTPType:    proxy.Transport,
-			TimeoutSec: 10,
-			// EnableRTMP:   zlm.NewBool(true),
-			// EnableRTSP:   zlm.NewBool(true),
-			// EnableHLS:    zlm.NewBool(true),
-			// EnableAudio:  zlm.NewBool(true),
-			AddMuteAudio: zlm.NewBool(true),
-			// AutoClose:    zlm.NewBool(false),
+		sp, err := a.uc.ProxyAPI.proxyCore.PlayStreamProxy(c.Request.Context(), channelID, func(sp *proxy.StreamProxy) (string, error) {
+			var err error
+			svr, err = a.uc.SMSAPI.smsCore.GetMediaServer(c.Request.Context(), sms.DefaultMediaServerID)
+			if err != nil {
+				return "", err
+			}
+			resp, err := a.uc.SMSAPI.smsCore.AddStreamProxy(svr, zlm.AddStreamProxyRequest{
+				Vhost:      "__defaultVhost__",
+				App:        sp.App,
+				Stream:     sp.Stream,
+				URL:        sp.SourceURL,
+				RetryCount: 3,
+				RTPType:    sp.Transport,
+				TimeoutSec: 10,
+				// EnableRTMP:   zlm.NewBool(true),
+				// EnableRTSP:   zlm.NewBool(true),
+				// EnableHLS:    zlm.NewBool(true),
+				// EnableAudio:  zlm.NewBool(true),
+				AddMuteAudio: zlm.NewBool(true),
+				// AutoClose:    zlm.NewBool(false),
+			})
+			if err != nil {
+				return "", web.ErrServer.Msg(err.Error())
+			}
+			return resp.Data.Key, nil
 		})
 		if err != nil {
-			return nil, web.ErrServer.Msg(err.Error())
+			return nil, err
 		}
-		a.uc.ProxyAPI.proxyCore.EditStreamProxyKey(c.Request.Context(), resp.Data.Key, proxy.ID)
+		app = sp.App
+		appStream = sp.Stream
 	} else {
 		return nil, web.ErrNotFound.Msg("不支持的播放通道")
 	}
@@ -206,6 +322,16 @@ func (a GB28181API) play(c *gin.Context, _ *struct{}) (*playOutput, error) {
 		host = l[0]
 	}
 
+	// 每个观看者独立签发令牌，rtsp 同时支持通过摘要认证携带
+	var query, rtspUser string
+	if signer := NewPlayTokenSigner(a.uc.Conf); signer.Enabled() {
+		token := signer.Sign(app, appStream, c.ClientIP())
+		query = "token=" + token
+		if a.uc.Conf.Server.PlayToken.Realm != "" {
+			rtspUser = strings.Replace(token, ".", ":", 1) + "@"
+		}
+	}
+
 	// 播放规则
 	// https://github.com/zlmediakit/ZLMediaKit/wiki/%E6%92%AD%E6%94%BEurl%E8%A7%84%E5%88%99
 	return &playOutput{
@@ -214,21 +340,21 @@ func (a GB28181API) play(c *gin.Context, _ *struct{}) (*playOutput, error) {
 		Items: []streamAddrItem{
 			{
 				Label:   "默认线路",
-				WSFLV:   fmt.Sprintf("ws://%s:%d/%s.live.flv", host, svr.Ports.HTTP, stream) + "?" + session,
-				HTTPFLV: fmt.Sprintf("http://%s:%d/%s.live.flv", host, svr.Ports.HTTP, stream) + "?" + session,
-				RTMP:    fmt.Sprintf("rtmp://%s:%d/%s", host, svr.Ports.RTMP, stream) + "?" + session,
-				RTSP:    fmt.Sprintf("rtsp://%s:%d/%s", host, svr.Ports.RTSP, stream) + "?" + session,
-				WebRTC:  fmt.Sprintf("webrtc://%s:%d/index/api/webrtc?app=%s&stream=%s&type=play", host, svr.Ports.HTTP, app, stream) + "&" + session,
-				HLS:     fmt.Sprintf("http://%s:%d/%s/hls.fmp4.m3u8", host, svr.Ports.HTTP, stream) + "?" + session,
+				WSFLV:   fmt.Sprintf("ws://%s:%d/%s.live.flv", host, svr.Ports.HTTP, stream) + "?" + query,
+				HTTPFLV: fmt.Sprintf("http://%s:%d/%s.live.flv", host, svr.Ports.HTTP, stream) + "?" + query,
+				RTMP:    fmt.Sprintf("rtmp://%s:%d/%s", host, svr.Ports.RTMP, stream) + "?" + query,
+				RTSP:    fmt.Sprintf("rtsp://%s%s:%d/%s", rtspUser, host, svr.Ports.RTSP, stream) + "?" + query,
+				WebRTC:  fmt.Sprintf("webrtc://%s:%d/index/api/webrtc?app=%s&stream=%s&type=play", host, svr.Ports.HTTP, app, appStream) + "&" + query,
+				HLS:     fmt.Sprintf("http://%s:%d/%s/hls.fmp4.m3u8", host, svr.Ports.HTTP, stream) + "?" + query,
 			},
 			{
 				Label:   "SSL 线路",
-				WSFLV:   fmt.Sprintf("wss://%s:%d/%s.live.flv", host, svr.Ports.HTTP, stream) + session,
-				HTTPFLV: fmt.Sprintf("https://%s:%d/%s.live.flv", host, svr.Ports.HTTP, stream) + session,
-				RTMP:    fmt.Sprintf("rtmps://%s:%d/%s", host, svr.Ports.RTMPs, stream) + session,
-				RTSP:    fmt.Sprintf("rtsps://%s:%d/%s", host, svr.Ports.RTSPs, stream) + session,
-				WebRTC:  fmt.Sprintf("webrtc://%s:%d/index/api/webrtc?app=%s&stream=%s&type=play", host, svr.Ports.HTTPS, app, stream) + "&" + session,
-				HLS:     fmt.Sprintf("https://%s:%d/%s/hls.fmp4.m3u8", host, svr.Ports.HTTPS, stream) + "?" + session,
+				WSFLV:   fmt.Sprintf("wss://%s:%d/%s.live.flv", host, svr.Ports.HTTPS, stream) + "?" + query,
+				HTTPFLV: fmt.Sprintf("https://%s:%d/%s.live.flv", host, svr.Ports.HTTPS, stream) + "?" + query,
+				RTMP:    fmt.Sprintf("rtmps://%s:%d/%s", host, svr.Ports.RTMPs, stream) + "?" + query,
+				RTSP:    fmt.Sprintf("rtsps://%s%s:%d/%s", rtspUser, host, svr.Ports.RTSPs, stream) + "?" + query,
+				WebRTC:  fmt.Sprintf("webrtc://%s:%d/index/api/webrtc?app=%s&stream=%s&type=play", host, svr.Ports.HTTPS, app, appStream) + "&" + query,
+				HLS:     fmt.Sprintf("https://%s:%d/%s/hls.fmp4.m3u8", host, svr.Ports.HTTPS, stream) + "?" + query,
 			},
 		},
 	}, nil
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/web"
+	"gorm.io/gorm"
+	"wvp/internal/conf"
+	"wvp/internal/core/bz"
+	"wvp/internal/core/group"
+	"wvp/internal/core/group/store/groupdb"
+	"wvp/internal/core/uniqueid"
+	"wvp/pkg/gbs"
+)
+
+type GroupAPI struct {
+	groupCore group.Core
+	uc        *Usecase
+}
+
+func NewGroupCore(db *gorm.DB, uni uniqueid.Core, bc *conf.Bootstrap) group.Core {
+	return group.NewCore(groupdb.NewDB(db).AutoMigrate(true), uni, bc.Sip.Domain)
+}
+
+func NewGroupAPI(core group.Core) GroupAPI {
+	return GroupAPI{groupCore: core}
+}
+
+func registerGroup(g gin.IRouter, api GroupAPI, handler ...gin.HandlerFunc) {
+	group := g.Group("/groups", handler...)
+	group.GET("", web.WarpH(api.findGroupTree))
+	group.GET("/catalog", adminMiddleware, api.getCatalog) // 分组导出为国标目录
+	group.GET("/:id", web.WarpH(api.getGroup))
+	group.GET("/:id/members", web.WarpH(api.findMember))
+
+	admin := group.Group("", adminMiddleware)
+	admin.PUT("/:id", web.WarpH(api.editGroup))
+	admin.POST("", web.WarpH(api.addGroup))
+	admin.DELETE("/:id", web.WarpH(api.delGroup))
+	admin.PUT("/:id/move", web.WarpH(api.moveGroup))
+
+	admin.POST("/:id/members", web.WarpH(api.addMembers))
+	admin.DELETE("/:id/members", web.WarpH(api.delMembers))
+	admin.POST("/:id/members/move", web.WarpH(api.moveMembers))
+}
+
+// >>> group >>>>>>>>>>>>>>>>>>>>
+
+func (a GroupAPI) findGroupTree(c *gin.Context, _ *struct{}) (any, error) {
+	items, err := a.groupCore.FindGroupTree(c.Request.Context())
+	return gin.H{"items": items}, err
+}
+
+func (a GroupAPI) getGroup(c *gin.Context, _ *struct{}) (any, error) {
+	groupID := c.Param("id")
+	return a.groupCore.GetGroup(c.Request.Context(), groupID)
+}
+
+func (a GroupAPI) editGroup(c *gin.Context, in *group.EditGroupInput) (any, error) {
+	groupID := c.Param("id")
+	return a.groupCore.EditGroup(c.Request.Context(), in, groupID)
+}
+
+func (a GroupAPI) addGroup(c *gin.Context, in *group.AddGroupInput) (any, error) {
+	return a.groupCore.AddGroup(c.Request.Context(), in)
+}
+
+func (a GroupAPI) delGroup(c *gin.Context, _ *struct{}) (any, error) {
+	groupID := c.Param("id")
+	return a.groupCore.DelGroup(c.Request.Context(), groupID)
+}
+
+func (a GroupAPI) moveGroup(c *gin.Context, in *group.MoveGroupInput) (any, error) {
+	groupID := c.Param("id")
+	return a.groupCore.MoveGroup(c.Request.Context(), in, groupID)
+}
+
+// >>> member >>>>>>>>>>>>>>>>>>>>
+
+func (a GroupAPI) findMember(c *gin.Context, in *group.FindMemberInput) (any, error) {
+	groupID := c.Param("id")
+	perm := getPermission(c)
+	in.Scopes = map[string]bz.Scope{
+		bz.ResourceChannel: perm.Scope(bz.ResourceChannel),
+		bz.ResourcePush:    perm.Scope(bz.ResourcePush),
+		bz.ResourceProxy:   perm.Scope(bz.ResourceProxy),
+	}
+	items, total, err := a.groupCore.FindMember(c.Request.Context(), in, groupID)
+	return gin.H{"items": items, "total": total}, err
+}
+
+func (a GroupAPI) addMembers(c *gin.Context, in *group.AddMembersInput) (any, error) {
+	groupID := c.Param("id")
+	return a.groupCore.AddMembers(c.Request.Context(), in, groupID)
+}
+
+func (a GroupAPI) delMembers(c *gin.Context, in *group.DelMembersInput) (any, error) {
+	groupID := c.Param("id")
+	return gin.H{"msg": "ok"}, a.groupCore.DelMembers(c.Request.Context(), in, groupID)
+}
+
+func (a GroupAPI) moveMembers(c *gin.Context, in *group.MoveMembersInput) (any, error) {
+	groupID := c.Param("id")
+	return a.groupCore.MoveMembers(c.Request.Context(), in, groupID)
+}
+
+// getCatalog 分组与分组内的国标通道按 GB/T28181 目录应答格式导出，用于向上级平台推送目录，仅管理员可访问
+func (a GroupAPI) getCatalog(c *gin.Context) {
+	ctx := c.Request.Context()
+	nodes, parents, err := a.groupCore.Catalog(ctx)
+	if err != nil {
+		web.Fail(c, err)
+		return
+	}
+	ids := make([]string, 0, len(parents))
+	for id := range parents {
+		ids = append(ids, id)
+	}
+	channels, err := a.uc.GB28181API.gb28181Core.FindChannelByIDs(ctx, ids)
+	if err != nil {
+		web.Fail(c, err)
+		return
+	}
+
+	items := make([]gbs.Channels, 0, len(nodes)+len(channels))
+	for _, v := range nodes {
+		items = append(items, gbs.Channels{
+			ChannelID:       v.ChannelID,
+			Name:            v.Name,
+			ParentID:        v.ParentID,
+			BusinessGroupID: v.BusinessGroupID,
+		})
+	}
+	for _, ch := range channels {
+		item := gbs.FromChannel(ch)
+		p := parents[ch.ID]
+		item.ParentID = p.ParentID
+		item.BusinessGroupID = p.BusinessGroupID
+		items = append(items, item)
+	}
+	c.XML(http.StatusOK, gbs.NewCatalogResponse(a.uc.Conf.Sip.ID, 1, items))
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/ixugo/goweb/pkg/hook"
 	"github.com/ixugo/goweb/pkg/web"
 	"wvp/internal/conf"
+	"wvp/internal/core/bz"
 	"wvp/internal/core/media"
 	"wvp/internal/core/sms"
 )
@@ -30,7 +31,7 @@ func registerMediaAPI(g gin.IRouter, api MediaAPI, handler ...gin.HandlerFunc) {
 		group.GET("", web.WarpH(api.findStreamPush))
 		group.GET("/:id", web.WarpH(api.getStreamPush))
 		group.PUT("/:id", web.WarpH(api.editStreamPush))
-		group.POST("", web.WarpH(api.addStreamPush))
+		group.POST("", adminMiddleware, web.WarpH(api.addStreamPush))
 		group.DELETE("/:id", web.WarpH(api.delStreamPush))
 	}
 }
@@ -38,6 +39,7 @@ func registerMediaAPI(g gin.IRouter, api MediaAPI, handler ...gin.HandlerFunc) {
 // >>> streamPush >>>>>>>>>>>>>>>>>>>>
 
 func (a MediaAPI) findStreamPush(c *gin.Context, in *media.FindStreamPushInput) (*web.PageOutput, error) {
+	in.Scope = getPermission(c).Scope(bz.ResourcePush)
 	items, total, err := a.mediaCore.FindStreamPush(c.Request.Context(), in)
 	if err != nil {
 		return nil, err
@@ -76,11 +78,17 @@ func (a MediaAPI) findStreamPush(c *gin.Context, in *media.FindStreamPushInput)
 
 func (a MediaAPI) getStreamPush(c *gin.Context, _ *struct{}) (*media.StreamPush, error) {
 	streamPushID := c.Param("id")
+	if err := checkScope(c, bz.ResourcePush, streamPushID); err != nil {
+		return nil, err
+	}
 	return a.mediaCore.GetStreamPush(c.Request.Context(), streamPushID)
 }
 
 func (a MediaAPI) editStreamPush(c *gin.Context, in *media.EditStreamPushInput) (*media.StreamPush, error) {
 	streamPushID := c.Param("id")
+	if err := checkScope(c, bz.ResourcePush, streamPushID); err != nil {
+		return nil, err
+	}
 	return a.mediaCore.EditStreamPush(c.Request.Context(), in, streamPushID)
 }
 
@@ -90,5 +98,8 @@ func (a MediaAPI) addStreamPush(c *gin.Context, in *media.AddStreamPushInput) (*
 
 func (a MediaAPI) delStreamPush(c *gin.Context, _ *struct{}) (*media.StreamPush, error) {
 	streamPushID := c.Param("id")
+	if err := checkScope(c, bz.ResourcePush, streamPushID); err != nil {
+		return nil, err
+	}
 	return a.mediaCore.DelStreamPush(c.Request.Context(), streamPushID)
 }
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/gb28181"
+	"wvp/internal/core/media"
+	"wvp/pkg/metrics"
+)
+
+// webhookDuration zlm webhook 处理耗时
+var webhookDuration = metrics.NewHistogramVec("wvp_zlm_webhook_duration_seconds", "ZLMediaKit webhook handler latency by hook.", nil, "hook")
+
+var registerCollectorsOnce sync.Once
+
+// registerMetrics prometheus 指标接口，使用白名单限制访问
+func registerMetrics(r gin.IRouter, uc *Usecase) {
+	cfg := uc.Conf.Server.HTTP.Metrics
+	if !cfg.Enabled {
+		return
+	}
+	registerCollectorsOnce.Do(func() { registerCollectors(uc) })
+	r.GET("/metrics", metricsAccess(cfg.AccessIps), gin.WrapH(metrics.Default.Handler()))
+}
+
+func metricsAccess(ips []string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(ips) > 0 && !slices.Contains(ips, c.RemoteIP()) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
+}
+
+// webhookMetrics 统计 webhook 处理耗时
+func webhookMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		webhookDuration.Since(start, path.Base(c.FullPath()))
+	}
+}
+
+// registerCollectors 注册抓取时计算的状态指标
+func registerCollectors(uc *Usecase) {
+	status := func(online bool) string {
+		if online {
+			return "online"
+		}
+		return "offline"
+	}
+
+	metrics.NewGaugeFunc("wvp_devices", "GB28181 devices by status.", []string{"status"}, func(set func(float64, ...string)) {
+		if uc.SipServer == nil {
+			return
+		}
+		online, offline := uc.SipServer.DeviceStats()
+		set(float64(online), status(true))
+		set(float64(offline), status(false))
+	})
+	metrics.NewGaugeFunc("wvp_sip_banned_ips", "Source IPs currently banned from the SIP listener.", nil, func(set func(float64, ...string)) {
+		if uc.SipServer == nil {
+			return
+		}
+		set(float64(len(uc.SipServer.Guard().Bans())))
+	})
+	metrics.NewGaugeFunc("wvp_gb_play_sessions", "Active GB28181 play sessions.", nil, func(set func(float64, ...string)) {
+		if uc.SipServer == nil {
+			return
+		}
+		set(float64(uc.SipServer.PlaySessions()))
+	})
+
+	gbCore := uc.WebHookAPI.gb28181Core
+	metrics.NewGaugeFunc("wvp_channels", "GB28181 channels by status.", []string{"status"}, func(set func(float64, ...string)) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, online := range []bool{true, false} {
+			_, total, err := gbCore.FindChannel(ctx, &gb28181.FindChannelInput{
+				PagerFilter: web.PagerFilter{Page: 1, Size: 1},
+				IsOnline:    strconv.FormatBool(online),
+			})
+			if err != nil {
+				slog.Error("metrics FindChannel", "err", err)
+				return
+			}
+			set(float64(total), status(online))
+		}
+	})
+
+	mediaCore := uc.WebHookAPI.mediaCore
+	metrics.NewGaugeFunc("wvp_rtmp_pushes", "RTMP push streams currently publishing.", nil, func(set func(float64, ...string)) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, total, err := mediaCore.FindStreamPush(ctx, &media.FindStreamPushInput{
+			PagerFilter: web.PagerFilter{Page: 1, Size: 1},
+			Status:      media.StatusPushing,
+		})
+		if err != nil {
+			slog.Error("metrics FindStreamPush", "err", err)
+			return
+		}
+		set(float64(total))
+	})
+
+	proxyCore := uc.WebHookAPI.proxyCore
+	metrics.NewGaugeFunc("wvp_stream_proxies_pulling", "Stream proxies currently pulling.", nil, func(set func(float64, ...string)) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		total, err := proxyCore.CountPulling(ctx)
+		if err != nil {
+			slog.Error("metrics CountPulling", "err", err)
+			return
+		}
+		set(float64(total))
+	})
+
+	smsCore := uc.WebHookAPI.smsCore
+	metrics.NewGaugeFunc("wvp_media_server_up", "Whether the media server is online.", []string{"id"}, func(set func(float64, ...string)) {
+		smsCore.RangeServers(func(serverID string, isOnline bool, _ int) {
+			var v float64
+			if isOnline {
+				v = 1
+			}
+			set(v, serverID)
+		})
+	})
+	metrics.NewGaugeFunc("wvp_media_server_streams", "Streams registered on the media server.", []string{"id"}, func(set func(float64, ...string)) {
+		smsCore.RangeServers(func(serverID string, _ bool, streams int) {
+			set(float64(streams), serverID)
+		})
+	})
+}
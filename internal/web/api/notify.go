@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/web"
+	"gorm.io/gorm"
+	"wvp/internal/conf"
+	"wvp/internal/core/event"
+	"wvp/internal/core/notify"
+	"wvp/internal/core/notify/store/notifydb"
+	"wvp/internal/core/uniqueid"
+)
+
+type NotifyAPI struct {
+	notifyCore notify.Core
+	dispatcher *notify.Dispatcher
+}
+
+func NewNotifyAPI(db *gorm.DB, uni uniqueid.Core, cfg *conf.Bootstrap, bus *event.Bus) NotifyAPI {
+	core := notify.NewCore(notifydb.NewDB(db).AutoMigrate(true), uni)
+	c := cfg.Server.Notify
+	dispatcher := notify.NewDispatcher(core, notify.DispatcherConfig{
+		Workers:     c.Workers,
+		MaxAttempts: c.MaxAttempts,
+		Timeout:     c.Timeout.Duration(),
+		Retention:   c.Retention.Duration(),
+	})
+	dispatcher.Start(context.Background())
+	bus.Subscribe(dispatcher.Handle)
+	return NotifyAPI{notifyCore: core, dispatcher: dispatcher}
+}
+
+func registerNotify(g gin.IRouter, api NotifyAPI, handler ...gin.HandlerFunc) {
+	group := g.Group("/webhooks", handler...)
+	group.Use(adminMiddleware)
+	group.GET("", web.WarpH(api.findSubscription))
+	group.GET("/events", web.WarpH(api.findEventTypes))
+	group.GET("/:id", web.WarpH(api.getSubscription))
+	group.PUT("/:id", web.WarpH(api.editSubscription))
+	group.POST("", web.WarpH(api.addSubscription))
+	group.DELETE("/:id", web.WarpH(api.delSubscription))
+
+	group.POST("/:id/test", web.WarpH(api.testSubscription))
+	group.GET("/:id/deliveries", web.WarpH(api.findDelivery))
+}
+
+// reload 订阅变更后刷新投递器
+func (a NotifyAPI) reload(ctx context.Context) {
+	if err := a.dispatcher.Reload(ctx); err != nil {
+		slog.Error("加载事件订阅失败", "err", err)
+	}
+}
+
+// >>> subscription >>>>>>>>>>>>>>>>>>>>
+
+func (a NotifyAPI) findSubscription(c *gin.Context, in *notify.FindSubscriptionInput) (any, error) {
+	items, total, err := a.notifyCore.FindSubscription(c.Request.Context(), in)
+	return gin.H{"items": items, "total": total}, err
+}
+
+func (a NotifyAPI) findEventTypes(_ *gin.Context, _ *struct{}) (any, error) {
+	return gin.H{"items": event.Types}, nil
+}
+
+func (a NotifyAPI) getSubscription(c *gin.Context, _ *struct{}) (any, error) {
+	subscriptionID := c.Param("id")
+	return a.notifyCore.GetSubscription(c.Request.Context(), subscriptionID)
+}
+
+func (a NotifyAPI) editSubscription(c *gin.Context, in *notify.EditSubscriptionInput) (any, error) {
+	subscriptionID := c.Param("id")
+	out, err := a.notifyCore.EditSubscription(c.Request.Context(), in, subscriptionID)
+	if err == nil {
+		a.reload(c.Request.Context())
+	}
+	return out, err
+}
+
+func (a NotifyAPI) addSubscription(c *gin.Context, in *notify.AddSubscriptionInput) (any, error) {
+	out, err := a.notifyCore.AddSubscription(c.Request.Context(), in)
+	if err == nil {
+		a.reload(c.Request.Context())
+	}
+	return out, err
+}
+
+func (a NotifyAPI) delSubscription(c *gin.Context, _ *struct{}) (any, error) {
+	subscriptionID := c.Param("id")
+	out, err := a.notifyCore.DelSubscription(c.Request.Context(), subscriptionID)
+	if err == nil {
+		a.reload(c.Request.Context())
+	}
+	return out, err
+}
+
+// testSubscription 发送一条测试事件，结果可在投递记录中查看
+func (a NotifyAPI) testSubscription(c *gin.Context, _ *struct{}) (any, error) {
+	subscriptionID := c.Param("id")
+	sub, err := a.notifyCore.GetSubscription(c.Request.Context(), subscriptionID)
+	if err != nil {
+		return nil, err
+	}
+	e := event.NewEvent("ping", gin.H{"msg": "test"})
+	a.dispatcher.Send(sub, e)
+	return gin.H{"event_id": e.ID}, nil
+}
+
+func (a NotifyAPI) findDelivery(c *gin.Context, in *notify.FindDeliveryInput) (any, error) {
+	in.SubscriptionID = c.Param("id")
+	items, total, err := a.notifyCore.FindDelivery(c.Request.Context(), in)
+	return gin.H{"items": items, "total": total}, err
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"wvp/internal/conf"
+)
+
+// defaultPlayTokenTTL 未配置有效期时的默认值
+const defaultPlayTokenTTL = 2 * time.Hour
+
+// playTokenSkew 允许的时钟偏差，过期时间超出 当前时间+有效期+偏差 的令牌视为伪造
+const playTokenSkew = time.Minute
+
+var (
+	ErrPlayTokenInvalid = errors.New("播放令牌无效")
+	ErrPlayTokenExpired = errors.New("播放令牌已过期")
+)
+
+// PlayTokenSigner 播放令牌签名器
+// 令牌格式为 {过期时间戳}.{签名}，签名内容为 app/stream、过期时间与可选的客户端 IP
+// rtsp 摘要认证时，过期时间戳作为用户名，签名作为密码
+type PlayTokenSigner struct {
+	cfg *conf.Bootstrap
+}
+
+func NewPlayTokenSigner(cfg *conf.Bootstrap) PlayTokenSigner {
+	return PlayTokenSigner{cfg: cfg}
+}
+
+// Enabled 是否启用播放鉴权
+func (p PlayTokenSigner) Enabled() bool {
+	return p.cfg.Server.PlayToken.Enabled
+}
+
+func (p PlayTokenSigner) secret() string {
+	if s := p.cfg.Server.PlayToken.Secret; s != "" {
+		return s
+	}
+	return p.cfg.Server.HTTP.JwtSecret
+}
+
+func (p PlayTokenSigner) ttl() time.Duration {
+	if v := p.cfg.Server.PlayToken.TTL.Duration(); v > 0 {
+		return v
+	}
+	return defaultPlayTokenTTL
+}
+
+// Sign 为指定的流签发令牌，ip 仅在开启 BindIP 时参与签名
+func (p PlayTokenSigner) Sign(app, stream, ip string) string {
+	exp := strconv.FormatInt(time.Now().Add(p.ttl()).Unix(), 10)
+	return exp + "." + p.signature(app, stream, exp, ip)
+}
+
+// Verify 校验令牌
+func (p PlayTokenSigner) Verify(token, app, stream, ip string) error {
+	exp, sign, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrPlayTokenInvalid
+	}
+	return p.verify(exp, sign, app, stream, ip)
+}
+
+// Password 根据用户名(过期时间戳)计算 rtsp 摘要认证的明文密码
+func (p PlayTokenSigner) Password(user, app, stream, ip string) (string, error) {
+	if err := p.checkExpire(user); err != nil {
+		return "", err
+	}
+	return p.signature(app, stream, user, ip), nil
+}
+
+func (p PlayTokenSigner) verify(exp, sign, app, stream, ip string) error {
+	if err := p.checkExpire(exp); err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(sign), []byte(p.signature(app, stream, exp, ip))) {
+		return ErrPlayTokenInvalid
+	}
+	return nil
+}
+
+func (p PlayTokenSigner) checkExpire(exp string) error {
+	ts, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return ErrPlayTokenInvalid
+	}
+	now := time.Now()
+	if now.Unix() > ts {
+		return ErrPlayTokenExpired
+	}
+	if ts > now.Add(p.ttl()+playTokenSkew).Unix() {
+		return ErrPlayTokenInvalid
+	}
+	return nil
+}
+
+func (p PlayTokenSigner) signature(app, stream, exp, ip string) string {
+	if !p.cfg.Server.PlayToken.BindIP {
+		ip = ""
+	}
+	h := hmac.New(sha256.New, []byte(p.secret()))
+	h.Write([]byte(app + "/" + stream + "\n" + exp + "\n" + ip))
+	// 截取一半长度，避免播放地址过长
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
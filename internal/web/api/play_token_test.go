@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"wvp/internal/conf"
+)
+
+func newTestSigner(bindIP bool) PlayTokenSigner {
+	var cfg conf.Bootstrap
+	cfg.Server.PlayToken = conf.ServerPlayToken{Enabled: true, Secret: "secret", BindIP: bindIP}
+	return NewPlayTokenSigner(&cfg)
+}
+
+func TestPlayTokenVerify(t *testing.T) {
+	p := newTestSigner(true)
+	token := p.Sign("rtp", "ch1", "10.0.0.1")
+	exp, sign, _ := strings.Cut(token, ".")
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+	later := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	far := "9999999999"
+	tampered := []byte(sign)
+	tampered[0] ^= 1
+
+	cases := []struct {
+		name   string
+		token  string
+		stream string
+		ip     string
+		expect error
+	}{
+		{"ok", token, "ch1", "10.0.0.1", nil},
+		{"wrong stream", token, "ch2", "10.0.0.1", ErrPlayTokenInvalid},
+		{"wrong ip", token, "ch1", "10.0.0.2", ErrPlayTokenInvalid},
+		{"tampered signature", exp + "." + string(tampered), "ch1", "10.0.0.1", ErrPlayTokenInvalid},
+		{"tampered expire", later + "." + sign, "ch1", "10.0.0.1", ErrPlayTokenInvalid},
+		{"expired", expired + "." + p.signature("rtp", "ch1", expired, "10.0.0.1"), "ch1", "10.0.0.1", ErrPlayTokenExpired},
+		{"far future", far + "." + p.signature("rtp", "ch1", far, "10.0.0.1"), "ch1", "10.0.0.1", ErrPlayTokenInvalid},
+		{"no separator", sign, "ch1", "10.0.0.1", ErrPlayTokenInvalid},
+		{"invalid expire", "abc." + sign, "ch1", "10.0.0.1", ErrPlayTokenInvalid},
+		{"empty", "", "ch1", "10.0.0.1", ErrPlayTokenInvalid},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := p.Verify(c.token, "rtp", c.stream, c.ip); !errors.Is(err, c.expect) {
+				t.Fatalf("expect %v, got %v", c.expect, err)
+			}
+		})
+	}
+
+	// 未绑定 IP 时，不同客户端均可使用
+	unbound := newTestSigner(false)
+	if err := unbound.Verify(unbound.Sign("rtp", "ch1", "10.0.0.1"), "rtp", "ch1", "10.0.0.2"); err != nil {
+		t.Fatalf("expect ok without BindIP, got %v", err)
+	}
+	// 秘钥不同的实例签发的令牌无效
+	other := newTestSigner(true)
+	other.cfg.Server.PlayToken.Secret = "other"
+	if err := other.Verify(token, "rtp", "ch1", "10.0.0.1"); !errors.Is(err, ErrPlayTokenInvalid) {
+		t.Fatalf("expect invalid with other secret, got %v", err)
+	}
+}
+
+func TestPlayTokenPassword(t *testing.T) {
+	p := newTestSigner(false)
+	exp, sign, _ := strings.Cut(p.Sign("live", "cam", ""), ".")
+	expired := strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)
+
+	cases := []struct {
+		name   string
+		user   string
+		stream string
+		match  bool // 密码是否与令牌签名一致
+		err    error
+	}{
+		{"ok", exp, "cam", true, nil},
+		{"wrong stream", exp, "cam2", false, nil},
+		{"expired", expired, "cam", false, ErrPlayTokenExpired},
+		{"invalid user", "admin", "cam", false, ErrPlayTokenInvalid},
+		{"far future", "9999999999", "cam", false, ErrPlayTokenInvalid},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v, err := p.Password(c.user, "live", c.stream, "")
+			if !errors.Is(err, c.err) {
+				t.Fatalf("expect %v, got %v", c.err, err)
+			}
+			if (v == sign) != c.match {
+				t.Fatalf("expect match %v, got %s", c.match, v)
+			}
+		})
+	}
+}
@@ -10,16 +10,22 @@ import (
 	"github.com/ixugo/goweb/pkg/web"
 	"gorm.io/gorm"
 	"wvp/internal/conf"
+	"wvp/internal/core/cluster"
+	"wvp/internal/core/event"
 	"wvp/internal/core/gb28181"
 	"wvp/internal/core/gb28181/store/gb28181cache"
 	"wvp/internal/core/gb28181/store/gb28181db"
+	"wvp/internal/core/gb28181/store/gb28181redis"
 	"wvp/internal/core/media"
 	"wvp/internal/core/media/store/mediadb"
+	"wvp/internal/core/proxy"
+	"wvp/internal/core/proxy/store/proxydb"
 	"wvp/internal/core/uniqueid"
 	"wvp/internal/core/uniqueid/store/uniqueiddb"
 	"wvp/internal/core/version"
 	"wvp/internal/core/version/store/versiondb"
 	"wvp/pkg/gbs"
+	"wvp/pkg/redis"
 )
 
 var (
@@ -34,11 +40,18 @@ var (
 		NewMediaCore, NewMediaAPI,
 		gbs.NewServer,
 		NewGB28181Store,
+		NewPlaySessionStore,
+		NewRedisClient, NewCluster,
 		NewGB28181API,
 		NewGB28181Core,
 		NewGB28181,
-		NewProxyAPI,
+		NewProxyCore, NewProxyAPI,
 		NewConfigAPI,
+		NewUserAPI,
+		NewAuditAPI,
+		NewEventBus, NewNotifyAPI, NewEventAPI,
+		NewGroupCore, NewGroupAPI,
+		NewRegionRegistry, NewRegionAPI,
 	)
 )
 
@@ -53,13 +66,20 @@ type Usecase struct {
 	GB28181API GB28181API
 	ProxyAPI   ProxyAPI
 	ConfigAPI  ConfigAPI
+	UserAPI    UserAPI
+	AuditAPI   AuditAPI
+	NotifyAPI  NotifyAPI
+	EventAPI   EventAPI
+	GroupAPI   GroupAPI
+	RegionAPI  RegionAPI
 
 	SipServer *gbs.Server
+	Cluster   *cluster.Cluster
 }
 
 // NewHTTPHandler 生成Gin框架路由内容
 func NewHTTPHandler(uc *Usecase) http.Handler {
-	cfg := uc.Conf.Server
+	cfg := &uc.Conf.Server
 	// 检查是否设置了 JWT 密钥，如果未设置，则生成一个长度为 32 的随机字符串作为密钥
 	if cfg.HTTP.JwtSecret == "" {
 		cfg.HTTP.JwtSecret = orm.GenerateRandomString(32) // 生成一个长度为 32 的随机字符串作为密钥
@@ -108,13 +128,40 @@ func NewMediaCore(db *gorm.DB, uni uniqueid.Core) media.Core {
 	return media.NewCore(mediadb.NewDB(db).AutoMigrate(true), uni)
 }
 
-func NewGB28181Store(db *gorm.DB) gb28181.Storer {
-	return gb28181cache.NewCache(gb28181db.NewDB(db).AutoMigrate(true))
+// NewGB28181Store 集群部署时设备状态写入 redis 共享
+func NewGB28181Store(db *gorm.DB, bc *conf.Bootstrap, cli *redis.Client, c *cluster.Cluster) gb28181.Storer {
+	cache := gb28181cache.NewCache(gb28181db.NewDB(db).AutoMigrate(true))
+	if c.Enabled() {
+		cache.SetShared(gb28181redis.NewDeviceState(cli, bc.Data.Redis.Prefix), c.NodeID())
+	}
+	return cache
+}
+
+// NewPlaySessionStore 点播会话存储
+func NewPlaySessionStore(db *gorm.DB, bc *conf.Bootstrap, cli *redis.Client) gb28181.PlaySessionStorer {
+	switch bc.Sip.Session.Store {
+	case "memory":
+		return gb28181cache.NewPlaySession()
+	case "redis":
+		return gb28181redis.NewPlaySession(cli, bc.Data.Redis.Prefix)
+	default:
+		return gb28181db.NewPlaySession(db)
+	}
 }
 
-func NewGB28181(store gb28181.Storer, uni uniqueid.Core) gb28181.GB28181 {
+func NewGB28181(store gb28181.Storer, uni uniqueid.Core, bus *event.Bus) gb28181.GB28181 {
 	return gb28181.NewGB28181(
 		store,
 		uni,
+		bus,
 	)
 }
+
+// NewEventBus 事件总线
+func NewEventBus() *event.Bus {
+	return event.NewBus()
+}
+
+func NewProxyCore(db *gorm.DB, uni uniqueid.Core) *proxy.Core {
+	return proxy.NewCore(proxydb.NewDB(db).AutoMigrate(true), uni)
+}
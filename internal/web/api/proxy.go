@@ -4,18 +4,15 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/ixugo/goweb/pkg/web"
-	"gorm.io/gorm"
+	"wvp/internal/core/bz"
 	"wvp/internal/core/proxy"
-	"wvp/internal/core/proxy/store/proxydb"
-	"wvp/internal/core/uniqueid"
 )
 
 type ProxyAPI struct {
 	proxyCore *proxy.Core
 }
 
-func NewProxyAPI(db *gorm.DB, uni uniqueid.Core) ProxyAPI {
-	core := proxy.NewCore(proxydb.NewDB(db).AutoMigrate(true), uni)
+func NewProxyAPI(core *proxy.Core) ProxyAPI {
 	return ProxyAPI{proxyCore: core}
 }
 
@@ -25,7 +22,7 @@ func registerProxy(g gin.IRouter, api ProxyAPI, handler ...gin.HandlerFunc) {
 		group.GET("", web.WarpH(api.findStreamProxy))
 		group.GET("/:id", web.WarpH(api.getStreamProxy))
 		group.PUT("/:id", web.WarpH(api.editStreamProxy))
-		group.POST("", web.WarpH(api.addStreamProxy))
+		group.POST("", adminMiddleware, web.WarpH(api.addStreamProxy))
 		group.DELETE("/:id", web.WarpH(api.delStreamProxy))
 	}
 }
@@ -33,17 +30,24 @@ func registerProxy(g gin.IRouter, api ProxyAPI, handler ...gin.HandlerFunc) {
 // >>> streamProxy >>>>>>>>>>>>>>>>>>>>
 
 func (a ProxyAPI) findStreamProxy(c *gin.Context, in *proxy.FindStreamProxyInput) (any, error) {
+	in.Scope = getPermission(c).Scope(bz.ResourceProxy)
 	items, total, err := a.proxyCore.FindStreamProxy(c.Request.Context(), in)
 	return gin.H{"items": items, "total": total}, err
 }
 
 func (a ProxyAPI) getStreamProxy(c *gin.Context, _ *struct{}) (any, error) {
 	streamProxyID := c.Param("id")
+	if err := checkScope(c, bz.ResourceProxy, streamProxyID); err != nil {
+		return nil, err
+	}
 	return a.proxyCore.GetStreamProxy(c.Request.Context(), streamProxyID)
 }
 
 func (a ProxyAPI) editStreamProxy(c *gin.Context, in *proxy.EditStreamProxyInput) (any, error) {
 	streamProxyID := c.Param("id")
+	if err := checkScope(c, bz.ResourceProxy, streamProxyID); err != nil {
+		return nil, err
+	}
 	return a.proxyCore.EditStreamProxy(c.Request.Context(), in, streamProxyID)
 }
 
@@ -53,5 +57,8 @@ func (a ProxyAPI) addStreamProxy(c *gin.Context, in *proxy.AddStreamProxyInput)
 
 func (a ProxyAPI) delStreamProxy(c *gin.Context, _ *struct{}) (any, error) {
 	streamProxyID := c.Param("id")
+	if err := checkScope(c, bz.ResourceProxy, streamProxyID); err != nil {
+		return nil, err
+	}
 	return a.proxyCore.DelStreamProxy(c.Request.Context(), streamProxyID)
 }
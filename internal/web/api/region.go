@@ -0,0 +1,77 @@
+package api
+
+import (
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/conf"
+	"wvp/internal/core/region"
+)
+
+type RegionAPI struct {
+	regions *region.Registry
+	domain  string
+}
+
+// NewRegionRegistry 加载行政区划数据，数据文件有误时使用内置数据
+func NewRegionRegistry(bc *conf.Bootstrap) *region.Registry {
+	r, err := region.NewRegistry(bc.Sip.CivilCodeFile)
+	if err == nil {
+		if bc.Sip.CivilCodeFile == "" {
+			slog.Warn("使用内置行政区划数据，仅包含部分市、县级，完整数据请配置 Sip.CivilCodeFile")
+		}
+		return r
+	}
+	slog.Error("加载行政区划数据失败，使用内置数据", "err", err, "file", bc.Sip.CivilCodeFile)
+	r, err = region.NewRegistry("")
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func NewRegionAPI(regions *region.Registry, bc *conf.Bootstrap) RegionAPI {
+	return RegionAPI{regions: regions, domain: bc.Sip.Domain}
+}
+
+func registerRegion(g gin.IRouter, api RegionAPI, handler ...gin.HandlerFunc) {
+	group := g.Group("/regions", handler...)
+	group.GET("", web.WarpH(api.findRegion))
+	group.GET("/local", web.WarpH(api.getLocalRegion))
+	group.GET("/:code", web.WarpH(api.getRegion))
+}
+
+type findRegionInput struct {
+	Parent string `form:"parent"` // 上级编码，为空时查询省级
+}
+
+type regionOutput struct {
+	region.Region
+	Path []region.Region `json:"path"` // 从省级到该行政区划的完整路径
+}
+
+// findRegion 查询下级行政区划
+func (a RegionAPI) findRegion(_ *gin.Context, in *findRegionInput) (any, error) {
+	items := a.regions.Children(in.Parent)
+	return gin.H{"items": items, "total": len(items)}, nil
+}
+
+func (a RegionAPI) getRegion(c *gin.Context, _ *struct{}) (*regionOutput, error) {
+	code := c.Param("code")
+	v, ok := a.regions.Get(code)
+	if !ok {
+		return nil, web.ErrNotFound.Msg("行政区划不存在")
+	}
+	return &regionOutput{Region: v, Path: a.regions.Path(code)}, nil
+}
+
+// getLocalRegion 本平台所属的行政区划，按 Sip.Domain 匹配
+func (a RegionAPI) getLocalRegion(_ *gin.Context, _ *struct{}) (*regionOutput, error) {
+	code, err := a.regions.Match(a.domain)
+	if err != nil {
+		return nil, web.ErrNotFound.Msg("未匹配到本平台的行政区划，请检查 Sip.Domain 或 Sip.CivilCodeFile")
+	}
+	v, _ := a.regions.Get(code)
+	return &regionOutput{Region: v, Path: a.regions.Path(code)}, nil
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/web"
+	"wvp/internal/core/audit"
+	"wvp/pkg/gbs"
+)
+
+// SIPAPI 信令服务管理
+type SIPAPI struct {
+	svr *gbs.Server
+}
+
+func registerSIP(g gin.IRouter, svr *gbs.Server, handler ...gin.HandlerFunc) {
+	api := SIPAPI{svr: svr}
+	group := g.Group("/sip", handler...)
+	group.Use(adminMiddleware)
+	group.GET("/bans", web.WarpH(api.findBan))
+	group.DELETE("/bans", web.WarpH(api.clearBan))
+	group.DELETE("/bans/:ip", web.WarpH(api.delBan))
+}
+
+// >>> ban >>>>>>>>>>>>>>>>>>>>
+
+func (a SIPAPI) findBan(_ *gin.Context, _ *struct{}) (any, error) {
+	items := a.svr.Guard().Bans()
+	return gin.H{"items": items, "total": len(items)}, nil
+}
+
+func (a SIPAPI) delBan(c *gin.Context, _ *struct{}) (any, error) {
+	ip := c.Param("ip")
+	audit.Record(c.Request.Context(), audit.ActionSIPUnban, ip, nil)
+	if a.svr.Guard().Unban(ip) == 0 {
+		return nil, web.ErrNotFound.Msg("未找到封禁记录")
+	}
+	return gin.H{"ip": ip}, nil
+}
+
+func (a SIPAPI) clearBan(c *gin.Context, _ *struct{}) (any, error) {
+	audit.Record(c.Request.Context(), audit.ActionSIPUnban, "", nil)
+	return gin.H{"total": a.svr.Guard().Unban("")}, nil
+}
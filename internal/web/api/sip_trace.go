@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/web"
+	"golang.org/x/net/websocket"
+	"wvp/pkg/gbs/sip"
+)
+
+// sipTraceBufferSize websocket 每个连接缓存的报文数，客户端消费过慢时丢弃
+const sipTraceBufferSize = 256
+
+var errCaptureDisabled = web.ErrBadRequest.Msg("未开启信令抓包，请在配置文件中开启 Sip.Capture")
+
+type findSIPTraceInput struct {
+	CallID string `form:"call_id"` // 会话 Call-ID，为空返回全部
+}
+
+// sipTrace 获取抓包与设备国标编码，路径参数 id 为设备 id
+func (a GB28181API) sipTrace(c *gin.Context) (*sip.Capture, string, error) {
+	capture := a.uc.SipServer.Capture()
+	if capture == nil {
+		return nil, "", errCaptureDisabled
+	}
+	dev, err := a.gb28181Core.GetDevice(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		return nil, "", err
+	}
+	return capture, dev.DeviceID, nil
+}
+
+// findSIPTrace 设备最近的信令
+func (a GB28181API) findSIPTrace(c *gin.Context, in *findSIPTraceInput) (any, error) {
+	capture, deviceID, err := a.sipTrace(c)
+	if err != nil {
+		return nil, err
+	}
+	items := capture.Messages(deviceID, in.CallID)
+	return gin.H{"items": items, "total": len(items)}, nil
+}
+
+// exportSIPTrace 导出 pcap 文件，可使用 wireshark 打开
+func (a GB28181API) exportSIPTrace(c *gin.Context) {
+	capture, deviceID, err := a.sipTrace(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"reason": "bad_request", "msg": err.Error()})
+		return
+	}
+	items := capture.Messages(deviceID, c.Query("call_id"))
+
+	filename := deviceID + "_" + time.Now().Format("20060102150405") + ".pcap"
+	c.Header("Content-Type", "application/vnd.tcpdump.pcap")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	_ = sip.WritePcap(c.Writer, items)
+}
+
+// tailSIPTrace 以 websocket 推送设备的实时信令，连接后先推送已缓存的报文
+func (a GB28181API) tailSIPTrace(c *gin.Context) {
+	capture, deviceID, err := a.sipTrace(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"reason": "bad_request", "msg": err.Error()})
+		return
+	}
+	srv := websocket.Server{
+		// 已完成登录鉴权，不再校验 Origin
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			serveSIPTrace(ws, capture, deviceID)
+		},
+	}
+	srv.ServeHTTP(c.Writer, c.Request)
+}
+
+func serveSIPTrace(ws *websocket.Conn, capture *sip.Capture, deviceID string) {
+	defer ws.Close()
+	_ = ws.SetDeadline(time.Time{})
+
+	// 先订阅再读取缓存，按 id 去重
+	ch := make(chan sip.CaptureMessage, sipTraceBufferSize)
+	cancel := capture.Subscribe(deviceID, func(msg sip.CaptureMessage) {
+		select {
+		case ch <- msg:
+		default:
+		}
+	})
+	defer cancel()
+
+	var last uint64
+	for _, msg := range capture.Messages(deviceID, "") {
+		if err := websocket.JSON.Send(ws, msg); err != nil {
+			return
+		}
+		last = msg.ID
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var b []byte
+			if err := websocket.Message.Receive(ws, &b); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-done:
+			return
+		case msg := <-ch:
+			if msg.ID <= last {
+				continue
+			}
+			if err := websocket.JSON.Send(ws, msg); err != nil {
+				return
+			}
+		}
+	}
+}
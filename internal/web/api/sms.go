@@ -6,6 +6,8 @@ import (
 	"github.com/ixugo/goweb/pkg/web"
 	"gorm.io/gorm"
 	"wvp/internal/conf"
+	"wvp/internal/core/cluster"
+	"wvp/internal/core/event"
 	"wvp/internal/core/sms"
 	"wvp/internal/core/sms/store/smsdb"
 )
@@ -15,8 +17,8 @@ type SmsAPI struct {
 	uc      *Usecase
 }
 
-func NewSMSCore(db *gorm.DB, cfg *conf.Bootstrap) sms.Core {
-	core := sms.NewCore(smsdb.NewDB(db).AutoMigrate(true))
+func NewSMSCore(db *gorm.DB, cfg *conf.Bootstrap, bus *event.Bus, c *cluster.Cluster) sms.Core {
+	core := sms.NewCore(smsdb.NewDB(db).AutoMigrate(true), bus, c)
 	if err := core.Run(&cfg.Media, cfg.Server.HTTP.Port); err != nil {
 		panic(err)
 	}
@@ -31,7 +33,7 @@ func registerSms(g gin.IRouter, api SmsAPI, handler ...gin.HandlerFunc) {
 	{
 		group := g.Group("/media_servers", handler...)
 		group.GET("", web.WarpH(api.findMediaServer))
-		group.PUT("/:id", web.WarpH(api.editMediaServer))
+		group.PUT("/:id", adminMiddleware, web.WarpH(api.editMediaServer))
 
 		// group.GET("/:id", web.WarpH(api.getMediaServer))
 		// group.POST("", web.WarpH(api.addMediaServer))
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ixugo/goweb/pkg/orm"
+	"github.com/ixugo/goweb/pkg/web"
+	"gorm.io/gorm"
+	"wvp/internal/conf"
+	"wvp/internal/core/uniqueid"
+	"wvp/internal/core/user"
+	"wvp/internal/core/user/store/userdb"
+)
+
+type UserAPI struct {
+	userCore user.Core
+	conf     *conf.Bootstrap
+}
+
+func NewUserAPI(db *gorm.DB, uni uniqueid.Core, cfg *conf.Bootstrap) UserAPI {
+	core := user.NewCore(userdb.NewDB(db).AutoMigrate(true), uni)
+	initAdmin(core, cfg.Server.Admin)
+	return UserAPI{userCore: core, conf: cfg}
+}
+
+// initAdmin 创建初始管理员，未配置密码时随机生成
+func initAdmin(core user.Core, admin conf.ServerAdmin) {
+	username, password := admin.Username, admin.Password
+	if username == "" {
+		username = "admin"
+	}
+	if password == "" {
+		password = orm.GenerateRandomString(12)
+	}
+	ok, err := core.InitAdmin(context.Background(), username, password)
+	if err != nil {
+		slog.Error("初始化管理员失败", "err", err)
+		return
+	}
+	if ok {
+		slog.Warn("已创建初始管理员，请登录后及时修改密码", "username", username, "password", password)
+	}
+}
+
+func registerUser(g gin.IRouter, api UserAPI, handler ...gin.HandlerFunc) {
+	g.POST("/login", web.WarpH(api.login))
+
+	{
+		group := g.Group("/users", handler...)
+		group.GET("/me", web.WarpH(api.getMe))
+		group.PUT("/me/password", web.WarpH(api.changePassword))
+
+		admin := group.Group("", adminMiddleware)
+		admin.GET("", web.WarpH(api.findUser))
+		admin.GET("/:id", web.WarpH(api.getUser))
+		admin.PUT("/:id", web.WarpH(api.editUser))
+		admin.POST("", web.WarpH(api.addUser))
+		admin.DELETE("/:id", web.WarpH(api.delUser))
+	}
+
+	{
+		group := g.Group("/roles", handler...)
+		group.Use(adminMiddleware)
+		group.GET("", web.WarpH(api.findRole))
+		group.GET("/:id", web.WarpH(api.getRole))
+		group.PUT("/:id", web.WarpH(api.editRole))
+		group.POST("", web.WarpH(api.addRole))
+		group.DELETE("/:id", web.WarpH(api.delRole))
+
+		group.GET("/:id/grants", web.WarpH(api.findGrant))
+		group.POST("/:id/grants", web.WarpH(api.addGrants))
+		group.DELETE("/:id/grants/:grant_id", web.WarpH(api.delGrant))
+	}
+
+	{
+		group := g.Group("/api_keys", handler...)
+		group.GET("", web.WarpH(api.findAPIKey))
+		group.POST("", web.WarpH(api.addAPIKey))
+		group.DELETE("/:id", web.WarpH(api.delAPIKey))
+	}
+}
+
+type loginOutput struct {
+	Token     string     `json:"token"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	User      *user.User `json:"user"`
+}
+
+func (a UserAPI) login(c *gin.Context, in *user.LoginInput) (*loginOutput, error) {
+	u, err := a.userCore.Login(c.Request.Context(), in)
+	if err != nil {
+		return nil, err
+	}
+	token, expiresAt, err := newToken(u.ID, u.Username, a.conf.Server.HTTP.JwtSecret, a.conf.Server.HTTP.TokenTTL.Duration())
+	if err != nil {
+		return nil, web.ErrServer.Msg(err.Error())
+	}
+	return &loginOutput{Token: token, ExpiresAt: expiresAt, User: u}, nil
+}
+
+func (a UserAPI) getMe(c *gin.Context, _ *struct{}) (any, error) {
+	return a.userCore.GetUser(c.Request.Context(), getUserID(c))
+}
+
+func (a UserAPI) changePassword(c *gin.Context, in *user.ChangePasswordInput) (any, error) {
+	if err := a.userCore.ChangePassword(c.Request.Context(), in, getUserID(c)); err != nil {
+		return nil, err
+	}
+	return gin.H{"msg": "ok"}, nil
+}
+
+// >>> user >>>>>>>>>>>>>>>>>>>>
+
+func (a UserAPI) findUser(c *gin.Context, in *user.FindUserInput) (any, error) {
+	items, total, err := a.userCore.FindUser(c.Request.Context(), in)
+	return gin.H{"items": items, "total": total}, err
+}
+
+func (a UserAPI) getUser(c *gin.Context, _ *struct{}) (any, error) {
+	userID := c.Param("id")
+	return a.userCore.GetUser(c.Request.Context(), userID)
+}
+
+func (a UserAPI) editUser(c *gin.Context, in *user.EditUserInput) (any, error) {
+	userID := c.Param("id")
+	return a.userCore.EditUser(c.Request.Context(), in, userID)
+}
+
+func (a UserAPI) addUser(c *gin.Context, in *user.AddUserInput) (any, error) {
+	return a.userCore.AddUser(c.Request.Context(), in)
+}
+
+func (a UserAPI) delUser(c *gin.Context, _ *struct{}) (any, error) {
+	userID := c.Param("id")
+	if userID == getUserID(c) {
+		return nil, web.ErrBadRequest.Msg("不能删除当前登录用户")
+	}
+	return a.userCore.DelUser(c.Request.Context(), userID)
+}
+
+// >>> role >>>>>>>>>>>>>>>>>>>>
+
+func (a UserAPI) findRole(c *gin.Context, in *user.FindRoleInput) (any, error) {
+	items, total, err := a.userCore.FindRole(c.Request.Context(), in)
+	return gin.H{"items": items, "total": total}, err
+}
+
+func (a UserAPI) getRole(c *gin.Context, _ *struct{}) (any, error) {
+	roleID := c.Param("id")
+	return a.userCore.GetRole(c.Request.Context(), roleID)
+}
+
+func (a UserAPI) editRole(c *gin.Context, in *user.EditRoleInput) (any, error) {
+	roleID := c.Param("id")
+	return a.userCore.EditRole(c.Request.Context(), in, roleID)
+}
+
+func (a UserAPI) addRole(c *gin.Context, in *user.AddRoleInput) (any, error) {
+	return a.userCore.AddRole(c.Request.Context(), in)
+}
+
+func (a UserAPI) delRole(c *gin.Context, _ *struct{}) (any, error) {
+	roleID := c.Param("id")
+	return a.userCore.DelRole(c.Request.Context(), roleID)
+}
+
+func (a UserAPI) findGrant(c *gin.Context, in *user.FindGrantInput) (any, error) {
+	roleID := c.Param("id")
+	items, total, err := a.userCore.FindGrant(c.Request.Context(), in, roleID)
+	return gin.H{"items": items, "total": total}, err
+}
+
+func (a UserAPI) addGrants(c *gin.Context, in *user.AddGrantsInput) (any, error) {
+	roleID := c.Param("id")
+	return a.userCore.AddGrants(c.Request.Context(), in, roleID)
+}
+
+func (a UserAPI) delGrant(c *gin.Context, _ *struct{}) (any, error) {
+	roleID := c.Param("id")
+	grantID, err := strconv.Atoi(c.Param("grant_id"))
+	if err != nil {
+		return nil, web.ErrBadRequest.Msg("grant_id 格式错误")
+	}
+	return a.userCore.DelGrant(c.Request.Context(), roleID, grantID)
+}
+
+// >>> api key >>>>>>>>>>>>>>>>>>>>
+
+func (a UserAPI) findAPIKey(c *gin.Context, in *user.FindAPIKeyInput) (any, error) {
+	// 管理员可查看全部密钥，其他用户仅能查看自己的
+	if perm := getPermission(c); perm == nil || !perm.IsAdmin {
+		in.UserID = getUserID(c)
+	}
+	items, total, err := a.userCore.FindAPIKey(c.Request.Context(), in)
+	return gin.H{"items": items, "total": total}, err
+}
+
+func (a UserAPI) addAPIKey(c *gin.Context, in *user.AddAPIKeyInput) (any, error) {
+	return a.userCore.AddAPIKey(c.Request.Context(), in, getUserID(c))
+}
+
+func (a UserAPI) delAPIKey(c *gin.Context, _ *struct{}) (any, error) {
+	keyID := c.Param("id")
+	uid := getUserID(c)
+	if perm := getPermission(c); perm != nil && perm.IsAdmin {
+		uid = ""
+	}
+	return a.userCore.DelAPIKey(c.Request.Context(), keyID, uid)
+}
@@ -2,13 +2,17 @@ package api
 
 import (
 	"log/slog"
+	"net/http"
 	"net/url"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ixugo/goweb/pkg/web"
 	"wvp/internal/conf"
+	"wvp/internal/core/bz"
+	"wvp/internal/core/event"
 	"wvp/internal/core/gb28181"
 	"wvp/internal/core/media"
+	"wvp/internal/core/proxy"
 	"wvp/internal/core/sms"
 	"wvp/pkg/gbs"
 )
@@ -20,9 +24,12 @@ type WebHookAPI struct {
 	conf        *conf.Bootstrap
 	log         *slog.Logger
 	gbs         *gbs.Server
+	playToken   PlayTokenSigner
+	proxyCore   *proxy.Core
+	bus         *event.Bus
 }
 
-func NewWebHookAPI(core sms.Core, mediaCore media.Core, conf *conf.Bootstrap, gbs *gbs.Server, gb28181 gb28181.Core) WebHookAPI {
+func NewWebHookAPI(core sms.Core, mediaCore media.Core, conf *conf.Bootstrap, gbs *gbs.Server, gb28181 gb28181.Core, proxyCore *proxy.Core, bus *event.Bus) WebHookAPI {
 	return WebHookAPI{
 		smsCore:     core,
 		mediaCore:   mediaCore,
@@ -30,21 +37,36 @@ func NewWebHookAPI(core sms.Core, mediaCore media.Core, conf *conf.Bootstrap, gb
 		log:         slog.With("hook", "zlm"),
 		gbs:         gbs,
 		gb28181Core: gb28181,
+		playToken:   NewPlayTokenSigner(conf),
+		proxyCore:   proxyCore,
+		bus:         bus,
 	}
 }
 
 func registerZLMWebhookAPI(r gin.IRouter, api WebHookAPI, handler ...gin.HandlerFunc) {
 	{
-		group := r.Group("/webhook", handler...)
+		group := r.Group("/webhook", append(handler, api.verifyHook)...)
 		group.POST("/on_server_keepalive", web.WarpH(api.onServerKeepalive))
 		group.POST("/on_stream_changed", web.WarpH(api.onStreamChanged))
 		group.POST("/on_publish", web.WarpH(api.onPublish))
 		group.POST("/on_play", web.WarpH(api.onPlay))
+		group.POST("/on_rtsp_realm", web.WarpH(api.onRTSPRealm))
+		group.POST("/on_rtsp_auth", web.WarpH(api.onRTSPAuth))
 		group.POST("/on_stream_none_reader", web.WarpH(api.onStreamNoneReader))
 		group.POST("/on_rtp_server_timeout", web.WarpH(api.onRTPServerTimeout))
 	}
 }
 
+// verifyHook 仅接受已连接的媒体服务器回调，回调地址携带由媒体服务器密钥派生的令牌
+func (w WebHookAPI) verifyHook(c *gin.Context) {
+	if !w.smsCore.VerifyHook(c.Query("token")) {
+		w.log.Warn("拒绝未知来源的回调", "ip", c.ClientIP(), "path", c.FullPath())
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"reason": "forbidden", "msg": "回调令牌无效"})
+		return
+	}
+	c.Next()
+}
+
 // onServerKeepalive 服务器定时上报时间，上报间隔可配置，默认 10s 上报一次
 // https://docs.zlmediakit.com/zh/guide/media_server/web_hook_api.html#_16%E3%80%81on-server-keepalive
 func (w WebHookAPI) onServerKeepalive(_ *gin.Context, in *onServerKeepaliveInput) (DefaultOutput, error) {
@@ -82,6 +104,10 @@ func (w WebHookAPI) onPublish(c *gin.Context, in *onPublishInput) (*onPublishOut
 // https://docs.zlmediakit.com/zh/guide/media_server/web_hook_api.html#_12%E3%80%81on-stream-changed
 func (w WebHookAPI) onStreamChanged(c *gin.Context, in *onStreamChangedInput) (DefaultOutput, error) {
 	w.log.Info("流状态变化", "app", in.App, "stream", in.Stream, "schema", in.Schema, "mediaServerID", in.MediaServerID)
+	// 每种协议都会触发一次，仅以 rtmp 协议作为流状态的依据
+	if in.Schema == "rtmp" {
+		w.publishStreamChanged(c, in)
+	}
 	if in.App == "rtp" {
 		if !in.Regist {
 			ch, err := w.gb28181Core.GetChannel(c.Request.Context(), in.Stream)
@@ -106,36 +132,106 @@ func (w WebHookAPI) onStreamChanged(c *gin.Context, in *onStreamChangedInput) (D
 	return newDefaultOutputOK(), nil
 }
 
-// onPlay rtsp/rtmp/http-flv/ws-flv/hls 播放触发播放器身份验证事件。
+// publishStreamChanged 发布流注册/注销事件，拉流代理同时更新拉流状态
+func (w WebHookAPI) publishStreamChanged(c *gin.Context, in *onStreamChangedInput) {
+	typ := event.TypeStreamUnpublish
+	if in.Regist {
+		typ = event.TypeStreamPublish
+	}
+	data := event.Stream{App: in.App, Stream: in.Stream, Schema: in.Schema, MediaServerID: in.MediaServerID}
+	w.smsCore.StreamChanged(in.MediaServerID, in.App, in.Stream, in.Regist)
+
+	ctx := c.Request.Context()
+	if in.App == "rtp" {
+		// 国标流的 stream 为通道 id
+		data.ResourceType, data.ResourceID = bz.ResourceChannel, in.Stream
+		if ch, err := w.gb28181Core.GetChannel(ctx, in.Stream); err == nil {
+			if dev, err := w.gb28181Core.GetDeviceByDeviceID(ctx, ch.DeviceID); err == nil {
+				data.DID = dev.ID
+			}
+		}
+		w.bus.Publish(typ, data)
+		return
+	}
+	p, changed, err := w.proxyCore.SetPulling(ctx, in.App, in.Stream, in.Regist)
+	if err == nil {
+		data.ResourceType, data.ResourceID = bz.ResourceProxy, p.ID
+	} else if push, err := w.mediaCore.GetStreamPushByAppStream(ctx, in.App, in.Stream); err == nil {
+		data.ResourceType, data.ResourceID = bz.ResourcePush, push.ID
+	}
+	w.bus.Publish(typ, data)
+	if p == nil || !changed {
+		return
+	}
+	w.bus.Publish(event.TypeProxyState, event.Proxy{ID: p.ID, App: p.App, Stream: p.Stream, Pulling: p.Pulling})
+}
+
+// onPlay rtsp/rtmp/http-flv/ws-flv/hls/webrtc 播放触发播放器身份验证事件。
 // 播放流时会触发此事件。如果流不存在，则首先触发 on_play 事件，然后触发 on_stream_not_found 事件。
 // 播放rtsp流时，如果该流开启了rtsp专用认证（on_rtsp_realm），则不会触发on_play事件。
 // https://docs.zlmediakit.com/guide/media_server/web_hook_api.html#_6-on-play
 func (w WebHookAPI) onPlay(c *gin.Context, in *onPublishInput) (DefaultOutput, error) {
-	return newDefaultOutputOK(), nil
-	if in.App == "rtp" {
+	if !w.playToken.Enabled() {
 		return newDefaultOutputOK(), nil
 	}
 
-	switch in.Schema {
-	case "rtmp":
-		params, err := url.ParseQuery(in.Params)
-		if err != nil {
-			slog.Info("onPlay 鉴权失败", "err", err)
-			return DefaultOutput{Code: 1, Msg: err.Error()}, nil
-		}
-		session := params.Get("session")
-		if err := w.mediaCore.OnPlay(c.Request.Context(), media.OnPlayInput{
-			App:     in.App,
-			Stream:  in.Stream,
-			Session: session,
-		}); err != nil {
-			slog.Info("onPlay 鉴权失败", "err", err)
-			return DefaultOutput{Code: 1, Msg: err.Error()}, nil
+	params, err := url.ParseQuery(in.Params)
+	if err != nil {
+		w.log.Info("onPlay 鉴权失败", "err", err)
+		return DefaultOutput{Code: 1, Msg: err.Error()}, nil
+	}
+	token := params.Get("token")
+	if token == "" && w.isPlayAuthDisabled(c, in.App, in.Stream) {
+		return newDefaultOutputOK(), nil
+	}
+	if err := w.playToken.Verify(token, in.App, in.Stream, in.IP); err != nil {
+		w.log.Info("onPlay 鉴权失败", "app", in.App, "stream", in.Stream, "schema", in.Schema, "ip", in.IP, "err", err)
+		return DefaultOutput{Code: 1, Msg: err.Error()}, nil
+	}
+	return newDefaultOutputOK(), nil
+}
+
+// isPlayAuthDisabled 推流设置了不鉴权时，播放也不鉴权
+func (w WebHookAPI) isPlayAuthDisabled(c *gin.Context, app, stream string) bool {
+	if app == "rtp" {
+		return false
+	}
+	push, err := w.mediaCore.GetStreamPushByAppStream(c.Request.Context(), app, stream)
+	if err != nil {
+		return false
+	}
+	return push.IsAuthDisabled
+}
+
+// onRTSPRealm 该rtsp流是否开启rtsp专用方式的鉴权事件，开启后才会触发on_rtsp_auth事件。
+// 携带有效令牌时不开启专用鉴权，交由 on_play 校验；否则要求客户端使用摘要认证
+// https://docs.zlmediakit.com/zh/guide/media_server/web_hook_api.html#_10%E3%80%81on-rtsp-realm
+func (w WebHookAPI) onRTSPRealm(c *gin.Context, in *onRTSPRealmInput) (onRTSPRealmOutput, error) {
+	realm := w.conf.Server.PlayToken.Realm
+	if !w.playToken.Enabled() || realm == "" {
+		return onRTSPRealmOutput{DefaultOutput: newDefaultOutputOK()}, nil
+	}
+	if params, err := url.ParseQuery(in.Params); err == nil {
+		if token := params.Get("token"); token != "" && w.playToken.Verify(token, in.App, in.Stream, in.IP) == nil {
+			return onRTSPRealmOutput{DefaultOutput: newDefaultOutputOK()}, nil
 		}
-	case "rtsp":
 	}
+	if w.isPlayAuthDisabled(c, in.App, in.Stream) {
+		return onRTSPRealmOutput{DefaultOutput: newDefaultOutputOK()}, nil
+	}
+	return onRTSPRealmOutput{DefaultOutput: newDefaultOutputOK(), Realm: realm}, nil
+}
 
-	return newDefaultOutputOK(), nil
+// onRTSPAuth rtsp专用的鉴权事件，先触发on_rtsp_realm事件然后才会触发on_rtsp_auth事件。
+// 用户名为令牌过期时间戳，返回明文密码由 zlm 完成摘要校验
+// https://docs.zlmediakit.com/zh/guide/media_server/web_hook_api.html#_11%E3%80%81on-rtsp-auth
+func (w WebHookAPI) onRTSPAuth(_ *gin.Context, in *onRTSPAuthInput) (onRTSPAuthOutput, error) {
+	passwd, err := w.playToken.Password(in.UserName, in.App, in.Stream, in.IP)
+	if err != nil {
+		w.log.Info("rtsp 鉴权失败", "app", in.App, "stream", in.Stream, "ip", in.IP, "user", in.UserName, "err", err)
+		return onRTSPAuthOutput{DefaultOutput: DefaultOutput{Code: 1, Msg: err.Error()}}, nil
+	}
+	return onRTSPAuthOutput{DefaultOutput: newDefaultOutputOK(), Passwd: passwd}, nil
 }
 
 // onStreamNoneReader 流无人观看时事件，用户可以通过此事件选择是否关闭无人看的流。
@@ -154,8 +250,17 @@ func (w WebHookAPI) onStreamNoneReader(c *gin.Context, in *onStreamNoneReaderInp
 
 // onRTPServerTimeout RTP 服务器超时事件
 // 调用 openRtpServer 接口，rtp server 长时间未收到数据,执行此 web hook,对回复不敏感
+// 设备接受点播但未推流时，结束点播并释放 ssrc，rtp server 已由 zlm 关闭
 // https://docs.zlmediakit.com/zh/guide/media_server/web_hook_api.html#_17%E3%80%81on-rtp-server-timeout
 func (w WebHookAPI) onRTPServerTimeout(c *gin.Context, in *onRTPServerTimeoutInput) (DefaultOutput, error) {
 	w.log.Info("rtp 收流超时", "local_port", in.LocalPort, "ssrc", in.SSRC, "stream_id", in.StreamID, "mediaServerID", in.MediaServerID)
+	ch, err := w.gb28181Core.GetChannel(c.Request.Context(), in.StreamID)
+	if err != nil {
+		w.log.Warn("获取通道失败", "err", err, "stream_id", in.StreamID)
+		return newDefaultOutputOK(), nil
+	}
+	if err := w.gbs.StopPlay(&gbs.StopPlayInput{Channel: ch}); err != nil {
+		w.log.Warn("结束点播失败", "err", err, "stream_id", in.StreamID)
+	}
 	return newDefaultOutputOK(), nil
 }
@@ -201,3 +201,33 @@ type onRTPServerTimeoutInput struct {
 	TCPMode       int    `json:"tcp_mode"`      // openRtpServer 输入的参数
 	MediaServerID string `json:"mediaServerId"` // 服务器 id,通过配置文件设置
 }
+
+type onRTSPRealmInput struct {
+	MediaServerID string `json:"mediaServerId"`
+	App           string `json:"app"`
+	ID            string `json:"id"`     // TCP 链接唯一 ID
+	IP            string `json:"ip"`     // rtsp 播放器 ip
+	Params        string `json:"params"` // 播放 url 参数
+	Port          int    `json:"port"`   // rtsp 播放器端口号
+	Schema        string `json:"schema"` // rtsp 或 rtsps
+	Stream        string `json:"stream"`
+	Vhost         string `json:"vhost"`
+}
+
+type onRTSPRealmOutput struct {
+	DefaultOutput
+	Realm string `json:"realm"` // 空字符串代表不开启 rtsp 专用鉴权
+}
+
+type onRTSPAuthInput struct {
+	onRTSPRealmInput
+	MustNoEncrypt bool   `json:"must_no_encrypt"` // 请求的密码是否必须为明文
+	Realm         string `json:"realm"`           // rtsp 播放鉴权加密 realm
+	UserName      string `json:"user_name"`       // 播放用户名
+}
+
+type onRTSPAuthOutput struct {
+	DefaultOutput
+	Encrypted bool   `json:"encrypted"` // 用户密码是明文还是摘要
+	Passwd    string `json:"passwd"`    // 用户密码明文或摘要(md5(username:realm:password))
+}
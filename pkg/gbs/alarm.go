@@ -0,0 +1,52 @@
+package gbs
+
+import (
+	"wvp/internal/core/event"
+	"wvp/pkg/gbs/sip"
+)
+
+// MessageAlarm 报警通知 xml 结构
+type MessageAlarm struct {
+	CmdType          string `xml:"CmdType"`
+	SN               int    `xml:"SN"`
+	DeviceID         string `xml:"DeviceID"`
+	AlarmPriority    string `xml:"AlarmPriority"`
+	AlarmMethod      string `xml:"AlarmMethod"`
+	AlarmTime        string `xml:"AlarmTime"`
+	AlarmDescription string `xml:"AlarmDescription"`
+	Longitude        string `xml:"Longitude"`
+	Latitude         string `xml:"Latitude"`
+	Info             struct {
+		AlarmType string `xml:"AlarmType"`
+	} `xml:"Info"`
+}
+
+// sipMessageAlarm 设备报警通知
+func (g *GB28181API) sipMessageAlarm(ctx *sip.Context) {
+	var msg MessageAlarm
+	if err := sip.XMLDecode(ctx.Request.Body(), &msg); err != nil {
+		ctx.Log.Error("Message Unmarshal xml err", "err", err)
+		ctx.String(400, "bad xml")
+		return
+	}
+	ctx.Log.Info("设备报警", "device_id", msg.DeviceID, "priority", msg.AlarmPriority, "method", msg.AlarmMethod)
+
+	// 设备 id 用于事件订阅的数据权限校验
+	var did string
+	if dev, err := g.core.GetDevice(ctx.DeviceID); err == nil {
+		did = dev.ID
+	}
+	g.bus.Publish(event.TypeAlarm, event.Alarm{
+		DID:         did,
+		DeviceID:    ctx.DeviceID,
+		ChannelID:   msg.DeviceID,
+		Priority:    msg.AlarmPriority,
+		Method:      msg.AlarmMethod,
+		Type:        msg.Info.AlarmType,
+		Time:        msg.AlarmTime,
+		Description: msg.AlarmDescription,
+		Longitude:   msg.Longitude,
+		Latitude:    msg.Latitude,
+	})
+	ctx.String(200, "OK")
+}
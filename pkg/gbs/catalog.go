@@ -4,7 +4,9 @@ import (
 	"encoding/xml"
 	"log/slog"
 	"net"
+	"time"
 
+	"wvp/internal/core/gb28181"
 	"wvp/pkg/gbs/sip"
 )
 
@@ -18,6 +20,17 @@ type MessageDeviceListResponse struct {
 	Item     []Channels `xml:"DeviceList>Item"`
 }
 
+// NewCatalogResponse 目录查询应答，deviceID 为本平台国标编码
+func NewCatalogResponse(deviceID string, sn int, items []Channels) *MessageDeviceListResponse {
+	return &MessageDeviceListResponse{
+		CmdType:  "Catalog",
+		SN:       sn,
+		DeviceID: deviceID,
+		SumNum:   len(items),
+		Item:     items,
+	}
+}
+
 // sipMessageCatalog 设备目录信息查询应答
 // GB/T28181 90 页 A.2.6.4
 func (g GB28181API) sipMessageCatalog(ctx *sip.Context) {
@@ -66,23 +79,88 @@ func (g GB28181API) sipMessageCatalog(ctx *sip.Context) {
 	ctx.String(200, "OK")
 }
 
-// QueryCatalog 设备目录查询或订阅请求
+// catalogBatch 目录接收过程中每批保存的通道数
+const catalogBatch = 200
+
+// startCatalog 接收设备目录，分批写入数据库，接收结束后标记移除的通道
+// 收集器在单个协程中依次回调，syncs 无需加锁
+func (g *GB28181API) startCatalog() {
+	syncs := make(map[string]*gb28181.CatalogSync)
+	flush := func(deviceID string, c []*Channels) {
+		cs, ok := syncs[deviceID]
+		if !ok {
+			var err error
+			if cs, err = g.core.NewCatalogSync(deviceID); err != nil {
+				slog.Error("加载设备目录失败", "err", err, "device_id", deviceID)
+				return
+			}
+			syncs[deviceID] = cs
+		}
+		out := make([]*gb28181.Channel, len(c))
+		for i, ch := range c {
+			out[i] = ch.toChannel(deviceID)
+		}
+		if err := cs.Save(out); err != nil {
+			slog.Error("保存设备目录失败", "err", err, "device_id", deviceID, "count", len(out))
+		}
+	}
+	done := func(deviceID string, full bool) {
+		cs, ok := syncs[deviceID]
+		if !ok {
+			return
+		}
+		delete(syncs, deviceID)
+		diff, err := cs.Finish(full)
+		if err != nil {
+			slog.Error("核对设备目录失败", "err", err, "device_id", deviceID)
+			return
+		}
+		slog.Info("设备目录核对完成", "device_id", deviceID, "total", diff.Total, "added", len(diff.Added),
+			"updated", len(diff.Updated), "removed", len(diff.Removed), "partial", diff.Partial)
+		if dev, ok := g.svr.memoryStorer.Load(deviceID); ok {
+			for _, id := range diff.Removed {
+				dev.Channels.Delete(id)
+			}
+			for _, id := range diff.Added {
+				dev.LoadChannels(&gb28181.Channel{ChannelID: id})
+			}
+		}
+		g.catalogDiffs.Store(deviceID, diff)
+	}
+	g.catalog.Start(flush, done)
+}
+
+// CatalogProgress 设备目录接收进度
+func (g *GB28181API) CatalogProgress(deviceID string) (*sip.CollectorProgress, error) {
+	v, ok := g.catalog.Progress(deviceID)
+	if !ok {
+		return nil, ErrCatalogNotQueried
+	}
+	return &v, nil
+}
+
+// catalogWait 等待目录接收与核对，未接收完整的目录在 10 秒无新数据后保存
+const catalogWait = 15 * time.Second
+
+// QueryCatalog 设备目录查询或订阅请求，返回目录核对结果，等待超时返回 nil
 // GB/T28181 81 页 A.2.4.3
-func (g *GB28181API) QueryCatalog(deviceID string) error {
+func (g *GB28181API) QueryCatalog(deviceID string) (*gb28181.CatalogDiff, error) {
 	slog.Debug("QueryCatalog", "deviceID", deviceID)
 	ipc, ok := g.svr.memoryStorer.Load(deviceID)
 	if !ok {
-		return ErrDeviceOffline
+		return nil, ErrDeviceOffline
 	}
 
+	g.catalogDiffs.Delete(deviceID)
 	_, err := g.svr.wrapRequest(ipc, sip.MethodMessage, &sip.ContentTypeXML, sip.GetCatalogXML(deviceID))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	g.catalog.Run(deviceID)
-	g.catalog.Wait(deviceID)
-	return nil
+	g.catalog.WaitTimeout(deviceID, catalogWait)
+	diff, _ := g.catalogDiffs.LoadAndDelete(deviceID)
+	return diff, nil
 }
 
 type Targeter interface {
@@ -95,7 +173,10 @@ type RequestOption func(*sip.Request)
 
 func (s *Server) wrapRequest(t Targeter, method string, contentType *sip.ContentType, body []byte, opts ...RequestOption) (*sip.Transaction, error) {
 	to := t.To()
-	conn := t.Conn()
+	conn, err := s.targetConn(t)
+	if err != nil {
+		return nil, err
+	}
 	source := t.Source()
 
 	hb := sip.NewHeaderBuilder().
@@ -103,7 +184,7 @@ func (s *Server) wrapRequest(t Targeter, method string, contentType *sip.Content
 		SetFrom(s.fromAddress).
 		SetContentType(contentType).
 		SetMethod(method).
-		SetContact(s.fromAddress).
+		SetContact(s.ContactAddress(s.fromAddress, conn)).
 		AddVia(&sip.ViaHop{
 			Params: sip.NewParams().Add("branch", sip.String{Str: sip.GenerateBranch()}),
 		})
@@ -0,0 +1,95 @@
+package gbs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ixugo/goweb/pkg/conc"
+)
+
+// 实例间转发的信令请求
+const (
+	forwardPlay     = "/sip/play"
+	forwardStopPlay = "/sip/stop_play"
+	forwardCatalog  = "/sip/catalog"
+	forwardProgress = "/sip/catalog_progress"
+)
+
+type forwardCatalogInput struct {
+	DeviceID string `json:"device_id"`
+}
+
+// remoteOwner 设备信令连接由其它实例持有时，返回该实例标识
+func (s *Server) remoteOwner(deviceID string) (string, bool) {
+	if !s.cluster.Enabled() {
+		return "", false
+	}
+	node := s.memoryStorer.Owner(deviceID)
+	return node, !s.cluster.IsLocal(node)
+}
+
+func (s *Server) forward(node, path string, in, out any) error {
+	slog.Debug("forward sip request", "node", node, "path", path)
+	return s.cluster.Forward(context.Background(), node, path, in, out)
+}
+
+// ServeForward 在本实例执行其它实例转发的信令请求，返回值作为响应内容
+func (s *Server) ServeForward(path string, body []byte) (any, error) {
+	switch path {
+	case forwardPlay:
+		var in PlayInput
+		if err := json.Unmarshal(body, &in); err != nil {
+			return nil, err
+		}
+		if in.Channel == nil {
+			return nil, ErrDeviceNotExist
+		}
+		return nil, s.gb.Play(&in)
+	case forwardStopPlay:
+		var in StopPlayInput
+		if err := json.Unmarshal(body, &in); err != nil {
+			return nil, err
+		}
+		if in.Channel == nil {
+			return nil, ErrDeviceNotExist
+		}
+		return nil, s.gb.StopPlay(&in)
+	case forwardCatalog:
+		var in forwardCatalogInput
+		if err := json.Unmarshal(body, &in); err != nil {
+			return nil, err
+		}
+		return s.gb.QueryCatalog(in.DeviceID)
+	case forwardProgress:
+		var in forwardCatalogInput
+		if err := json.Unmarshal(body, &in); err != nil {
+			return nil, err
+		}
+		return s.gb.CatalogProgress(in.DeviceID)
+	}
+	return nil, fmt.Errorf("unsupported forward path %s", path)
+}
+
+// startCatalogSync 定时查询在线设备目录，集群部署时仅主节点执行
+func (s *Server) startCatalogSync(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	conc.Timer(context.Background(), interval, interval, func() {
+		if !s.cluster.IsLeader() {
+			return
+		}
+		s.memoryStorer.RangeDevices(func(key string, value *Device) bool {
+			if !value.IsOnline {
+				return true
+			}
+			if _, err := s.QueryCatalog(key); err != nil {
+				slog.Error("定时查询设备目录失败", "err", err, "device_id", key)
+			}
+			return true
+		})
+	})
+}
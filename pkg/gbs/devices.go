@@ -26,18 +26,24 @@ type Device struct {
 	registerWithKeepaliveMutex sync.Mutex
 	// 播放互斥锁也可以移动到 channel 属性
 	playMutex sync.Mutex
+	// 主动建立 tcp 连接时使用，避免并发重复连接
+	dialMutex sync.Mutex
 
 	IsOnline bool
 	Address  string
 	Password string
+	Node     string // 集群部署时持有设备信令连接的实例
 
-	conn   sip.Connection
-	source net.Addr
-	to     *sip.Address
+	conn      sip.Connection
+	source    net.Addr
+	to        *sip.Address
+	transport string // 信令传输协议 udp/tcp/tls
 
 	LastKeepaliveAt time.Time
 	LastRegisterAt  time.Time
 	Expires         int
+	Keepalives      int // 心跳间隔(秒)
+	KeepaliveCount  int // 心跳超时次数
 }
 
 func NewDevice(conn sip.Connection, d *gb28181.Device) *Device {
@@ -47,11 +53,15 @@ func NewDevice(conn sip.Connection, d *gb28181.Device) *Device {
 		return nil
 	}
 
-	addr, err := net.ResolveUDPAddr("udp", d.Address)
+	addr, err := resolveAddr(d.Trasnport, d.Address)
 	if err != nil {
-		slog.Error("resolve udp addr", "err", err, "did", d.ID)
+		slog.Error("resolve addr", "err", err, "did", d.ID, "transport", d.Trasnport)
 		return nil
 	}
+	// tcp/tls 设备需等待设备重新连接，tcp 设备可在请求时主动连接
+	if isTCP(addr) {
+		conn = nil
+	}
 
 	c := Device{
 		conn:   conn,
@@ -60,9 +70,13 @@ func NewDevice(conn sip.Connection, d *gb28181.Device) *Device {
 			URI:    uri,
 			Params: sip.NewParams(),
 		},
+		transport:       strings.ToLower(d.Trasnport),
 		Address:         d.Address,
 		LastKeepaliveAt: d.KeepaliveAt.Time,
 		LastRegisterAt:  d.RegisteredAt.Time,
+		Expires:         d.Expires,
+		Keepalives:      d.Keepalives,
+		KeepaliveCount:  d.KeepaliveCount,
 		IsOnline:        d.IsOnline,
 		Password:        d.Password,
 	}
@@ -70,6 +84,17 @@ func NewDevice(conn sip.Connection, d *gb28181.Device) *Device {
 	return &c
 }
 
+// expired 心跳超时或注册过期，返回离线原因
+func (d *Device) expired(now time.Time) (string, bool) {
+	if now.After(gb28181.KeepaliveDeadline(d.LastKeepaliveAt, d.Keepalives, d.KeepaliveCount)) {
+		return "keepalive timeout", true
+	}
+	if t := gb28181.RegisterDeadline(d.LastRegisterAt, d.Expires); !t.IsZero() && now.After(t) {
+		return "register expired", true
+	}
+	return "", false
+}
+
 func (d *Device) LoadChannels(channels ...*gb28181.Channel) {
 	for _, channel := range channels {
 		ch := Channel{
@@ -134,11 +159,7 @@ func (c *Channel) init(domain string) {
 }
 
 func newDevice(network, address string, conn sip.Connection) *Device {
-	if network == "tcp" {
-		return nil
-	}
-
-	raddr, err := net.ResolveUDPAddr(network, address)
+	raddr, err := resolveAddr(network, address)
 	if err != nil {
 		return nil
 	}
@@ -149,6 +170,18 @@ func newDevice(network, address string, conn sip.Connection) *Device {
 	return &dev
 }
 
+// resolveAddr 按传输协议解析设备地址，默认 udp
+func resolveAddr(network, address string) (net.Addr, error) {
+	if strings.EqualFold(network, "tcp") || strings.EqualFold(network, "tls") {
+		return net.ResolveTCPAddr("tcp", address)
+	}
+	return net.ResolveUDPAddr("udp", address)
+}
+
+func isTCP(addr net.Addr) bool {
+	return addr != nil && addr.Network() == "tcp"
+}
+
 // func NewClient() *Client {
 // 	return &Client{
 // 		devices: conc.Map[string, *Device]{},
@@ -242,11 +275,24 @@ type Channels struct {
 	Owner        string `xml:"Owner"  json:"owner"  gorm:"column:owner"`
 	CivilCode    string `xml:"CivilCode" json:"civilcode"  gorm:"column:civilcode"`
 	// Address ip地址
-	Address     string `xml:"Address"  json:"address"  gorm:"column:address"`
-	Parental    int    `xml:"Parental"  json:"parental"  gorm:"column:parental"`
-	SafetyWay   int    `xml:"SafetyWay"  json:"safetyway"  gorm:"column:safetyway"`
-	RegisterWay int    `xml:"RegisterWay"  json:"registerway"  gorm:"column:registerway"`
-	Secrecy     int    `xml:"Secrecy" json:"secrecy"  gorm:"column:secrecy"`
+	Address     string  `xml:"Address"  json:"address"  gorm:"column:address"`
+	Parental    int     `xml:"Parental"  json:"parental"  gorm:"column:parental"`
+	SafetyWay   int     `xml:"SafetyWay"  json:"safetyway"  gorm:"column:safetyway"`
+	RegisterWay int     `xml:"RegisterWay"  json:"registerway"  gorm:"column:registerway"`
+	Secrecy     int     `xml:"Secrecy" json:"secrecy"  gorm:"column:secrecy"`
+	ParentID    string  `xml:"ParentID" json:"parentid"`
+	Block       string  `xml:"Block" json:"block"`
+	CertNum     string  `xml:"CertNum" json:"certnum"`
+	Certifiable int     `xml:"Certifiable" json:"certifiable"`
+	ErrCode     int     `xml:"ErrCode" json:"errcode"`
+	EndTime     string  `xml:"EndTime" json:"endtime"`
+	IPAddress   string  `xml:"IPAddress" json:"ipaddress"`
+	Port        int     `xml:"Port" json:"port"`
+	Longitude   float64 `xml:"Longitude" json:"longitude"`
+	Latitude    float64 `xml:"Latitude" json:"latitude"`
+	// BusinessGroupID 虚拟组织所属的业务分组
+	BusinessGroupID string       `xml:"BusinessGroupID" json:"businessgroupid"`
+	Info            *ChannelInfo `xml:"Info" json:"info"`
 	// Status 状态  on 在线
 	Status string `xml:"Status"  json:"status"  gorm:"column:status"`
 	// Active 最后活跃时间
@@ -269,6 +315,106 @@ type Channels struct {
 	addr *sip.Address `gorm:"-"`
 }
 
+// ChannelInfo 设备目录扩展信息
+type ChannelInfo struct {
+	PTZType             int    `xml:"PTZType"`
+	PositionType        int    `xml:"PositionType"`
+	RoomType            int    `xml:"RoomType"`
+	UseType             int    `xml:"UseType"`
+	SupplyLightType     int    `xml:"SupplyLightType"`
+	DirectionType       int    `xml:"DirectionType"`
+	Resolution          string `xml:"Resolution"`
+	BusinessGroupID     string `xml:"BusinessGroupID"`
+	DownloadSpeed       string `xml:"DownloadSpeed"`
+	SVCSpaceSupportMode int    `xml:"SVCSpaceSupportMode"`
+	SVCTimeSupportMode  int    `xml:"SVCTimeSupportMode"`
+}
+
+// toChannel 转换为通道模型，保留目录上报的全部属性
+func (c *Channels) toChannel(deviceID string) *gb28181.Channel {
+	out := gb28181.Channel{
+		DeviceID:  deviceID,
+		ChannelID: c.ChannelID,
+		Name:      c.Name,
+		IsOnline:  c.Status == "OK" || c.Status == "ON",
+		Ext: gb28181.DeviceExt{
+			Manufacturer: c.Manufacturer,
+			Model:        c.Model,
+		},
+		ParentID:        c.ParentID,
+		CivilCode:       c.CivilCode,
+		BusinessGroupID: c.BusinessGroupID,
+		Owner:           c.Owner,
+		Address:         c.Address,
+		Block:           c.Block,
+		Parental:        c.Parental,
+		SafetyWay:       c.SafetyWay,
+		RegisterWay:     c.RegisterWay,
+		Secrecy:         c.Secrecy,
+		CertNum:         c.CertNum,
+		Certifiable:     c.Certifiable,
+		ErrCode:         c.ErrCode,
+		EndTime:         c.EndTime,
+		IPAddress:       c.IPAddress,
+		Port:            c.Port,
+		Longitude:       c.Longitude,
+		Latitude:        c.Latitude,
+	}
+	if info := c.Info; info != nil {
+		out.PTZType = info.PTZType
+		out.Info = gb28181.ChannelInfo{
+			PTZType:             info.PTZType,
+			PositionType:        info.PositionType,
+			RoomType:            info.RoomType,
+			UseType:             info.UseType,
+			SupplyLightType:     info.SupplyLightType,
+			DirectionType:       info.DirectionType,
+			Resolution:          info.Resolution,
+			DownloadSpeed:       info.DownloadSpeed,
+			SVCSpaceSupportMode: info.SVCSpaceSupportMode,
+			SVCTimeSupportMode:  info.SVCTimeSupportMode,
+		}
+		// 2016 版本的业务分组在 Info 中上报
+		if out.BusinessGroupID == "" {
+			out.BusinessGroupID = info.BusinessGroupID
+		}
+	}
+	return &out
+}
+
+// FromChannel 通道转换为目录应答中的条目
+func FromChannel(ch *gb28181.Channel) Channels {
+	status := "OFF"
+	if ch.IsOnline {
+		status = "ON"
+	}
+	return Channels{
+		ChannelID:       ch.ChannelID,
+		Name:            ch.Name,
+		Manufacturer:    ch.Ext.Manufacturer,
+		Model:           ch.Ext.Model,
+		Owner:           ch.Owner,
+		CivilCode:       ch.CivilCode,
+		Address:         ch.Address,
+		Parental:        ch.Parental,
+		SafetyWay:       ch.SafetyWay,
+		RegisterWay:     ch.RegisterWay,
+		Secrecy:         ch.Secrecy,
+		ParentID:        ch.ParentID,
+		Block:           ch.Block,
+		CertNum:         ch.CertNum,
+		Certifiable:     ch.Certifiable,
+		ErrCode:         ch.ErrCode,
+		EndTime:         ch.EndTime,
+		IPAddress:       ch.IPAddress,
+		Port:            ch.Port,
+		Longitude:       ch.Longitude,
+		Latitude:        ch.Latitude,
+		BusinessGroupID: ch.BusinessGroupID,
+		Status:          status,
+	}
+}
+
 // 同步摄像头编码格式
 func SyncDevicesCodec(ssrc, deviceid string) {
 	resp := zlmGetMediaList(zlmGetMediaListReq{streamID: ssrc})
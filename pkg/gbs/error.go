@@ -13,4 +13,6 @@ var (
 
 	ErrDeviceOffline  = errors.New("device offline")
 	ErrChannelOffline = errors.New("channel offline")
+
+	ErrCatalogNotQueried = errors.New("catalog not queried")
 )
@@ -67,8 +67,7 @@ func (ri *apiRecordItem) Start() (string, interface{}) {
 		case <-tick.C:
 			// 自动停止录制
 			ri.Stop()
-			url := <-ri.resp
-			notify(notifyRecordStop(url, ri.req))
+			<-ri.resp
 		case <-ri.clos:
 			// 调用stop接口
 		}
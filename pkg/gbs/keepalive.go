@@ -1,6 +1,8 @@
 package gbs
 
 import (
+	"net/http"
+
 	"github.com/ixugo/goweb/pkg/orm"
 	"wvp/internal/core/gb28181"
 	"wvp/pkg/gbs/sip"
@@ -23,15 +25,82 @@ func (g *GB28181API) sipMessageKeepalive(ctx *sip.Context) {
 		return
 	}
 
+	// 注册过期或已注销的设备需要重新注册
+	if dev, ok := g.svr.memoryStorer.Load(ctx.DeviceID); !ok || !dev.IsOnline {
+		ctx.String(http.StatusNotFound, "device not registered")
+		return
+	}
+
 	if err := g.svr.memoryStorer.Change(ctx.DeviceID, func(d *gb28181.Device) {
 		d.KeepaliveAt = orm.Now()
 		d.IsOnline = msg.Status == "OK" || msg.Status == "ON"
 		d.Address = ctx.Source.String()
-		d.Trasnport = ctx.Source.Network()
+		d.Trasnport = ctx.Transport()
 	}, func(d *Device) {
+		// 设备重连后使用最新的连接
+		if conn := ctx.Request.GetConnection(); conn != nil {
+			d.conn = conn
+			d.source = ctx.Source
+			d.transport = conn.Network()
+		}
+		d.Node = g.svr.cluster.NodeID()
 	}); err != nil {
 		ctx.Log.Error("keepalive", "err", err)
 	}
 
 	ctx.String(200, "OK")
 }
+
+// QueryConfigDownload 查询设备基本参数配置，获取心跳间隔与超时次数
+// GB/T28181 A.2.4.7
+func (g GB28181API) QueryConfigDownload(ctx *sip.Context) {
+	tx, err := ctx.SendRequest(sip.MethodMessage, sip.GetConfigDownloadXML(ctx.DeviceID, "BasicParam"))
+	if err != nil {
+		ctx.Log.Error("QueryConfigDownload", "err", err)
+		return
+	}
+	if _, err := sipResponse(tx); err != nil {
+		ctx.Log.Error("sipResponse", "err", err)
+		return
+	}
+}
+
+// MessageConfigDownloadResponse 设备配置查询应答结构
+type MessageConfigDownloadResponse struct {
+	CmdType    string `xml:"CmdType"`
+	SN         int    `xml:"SN"`
+	DeviceID   string `xml:"DeviceID"`
+	Result     string `xml:"Result"`
+	BasicParam *struct {
+		Expiration        int `xml:"Expiration"`        // 注册过期时间
+		HeartBeatInterval int `xml:"HeartBeatInterval"` // 心跳间隔时间
+		HeartBeatCount    int `xml:"HeartBeatCount"`    // 心跳超时次数
+	} `xml:"BasicParam"`
+}
+
+// sipMessageConfigDownload 设备配置查询应答，设备上报的心跳参数用于计算离线时间
+// GB/T28181 A.2.6.8
+func (g GB28181API) sipMessageConfigDownload(ctx *sip.Context) {
+	var msg MessageConfigDownloadResponse
+	if err := sip.XMLDecode(ctx.Request.Body(), &msg); err != nil {
+		ctx.Log.Error("sipMessageConfigDownload", "err", err)
+		ctx.String(http.StatusBadRequest, ErrXMLDecode.Error())
+		return
+	}
+	ctx.String(http.StatusOK, "OK")
+
+	param := msg.BasicParam
+	if param == nil || (param.HeartBeatInterval <= 0 && param.HeartBeatCount <= 0) {
+		return
+	}
+	if err := g.svr.memoryStorer.Change(ctx.DeviceID, func(d *gb28181.Device) {
+		if param.HeartBeatInterval > 0 {
+			d.Keepalives = param.HeartBeatInterval
+		}
+		if param.HeartBeatCount > 0 {
+			d.KeepaliveCount = param.HeartBeatCount
+		}
+	}, func(*Device) {}); err != nil {
+		ctx.Log.Error("ConfigDownload", "err", err)
+	}
+}
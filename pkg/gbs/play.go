@@ -3,7 +3,10 @@ package gbs
 import (
 	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,9 +15,13 @@ import (
 	"wvp/internal/core/sms"
 	"wvp/pkg/gbs/m"
 	"wvp/pkg/gbs/sip"
+	"wvp/pkg/metrics"
 	"wvp/pkg/zlm"
 )
 
+// inviteDuration 点播 INVITE 到收到最终响应的耗时
+var inviteDuration = metrics.NewHistogramVec("wvp_sip_invite_duration_seconds", "Latency from sending INVITE to receiving the final response.", []float64{0.1, 0.25, 0.5, 1, 2, 3, 5, 10}, "result")
+
 type PlayInput struct {
 	Channel    *gb28181.Channel
 	SMS        *sms.MediaServer
@@ -25,26 +32,36 @@ type StopPlayInput struct {
 	Channel *gb28181.Channel
 }
 
+// StopPlay 结束点播并释放 ssrc，设备离线时仅释放本地资源
 func (g *GB28181API) StopPlay(in *StopPlayInput) error {
-	ch, ok := g.svr.memoryStorer.GetChannel(in.Channel.DeviceID, in.Channel.ChannelID)
-	if !ok {
-		return ErrDeviceNotExist
+	ch, online := g.svr.memoryStorer.GetChannel(in.Channel.DeviceID, in.Channel.ChannelID)
+	if online {
+		ch.device.playMutex.Lock()
+		defer ch.device.playMutex.Unlock()
 	}
 
-	ch.device.playMutex.Lock()
-	defer ch.device.playMutex.Unlock()
-
-	key := "play:" + in.Channel.DeviceID + ":" + in.Channel.ChannelID
+	key := gb28181.PlaySessionID(in.Channel.DeviceID, in.Channel.ChannelID)
 	stream, ok := g.streams.LoadAndDelete(key)
 	if !ok {
 		return nil
 	}
-	if stream.Resp == nil {
+	g.delSession(key)
+	if stream.sms != nil {
+		g.sms.ReleaseSSRC(stream.sms, stream.ssrc)
+	}
+	if stream.Dialog == nil {
 		return nil
 	}
-	req := sip.NewRequestFromResponse(sip.MethodBYE, stream.Resp)
-	req.SetDestination(ch.Source())
-	req.SetConnection(ch.Conn())
+	g.svr.RemoveDialog(stream.Dialog)
+	if !online {
+		return ErrDeviceNotExist
+	}
+	conn, err := g.svr.targetConn(ch)
+	if err != nil {
+		return err
+	}
+	stream.Dialog.SetTarget(ch.Source(), conn)
+	req := stream.Dialog.NewRequest(sip.MethodBYE, nil, nil)
 
 	tx, err := g.svr.Request(req)
 	if err != nil {
@@ -54,6 +71,37 @@ func (g *GB28181API) StopPlay(in *StopPlayInput) error {
 	return err
 }
 
+// handlerBye 设备结束点播会话，释放流与 RTP 服务器
+func (g *GB28181API) handlerBye(ctx *sip.Context) {
+	ctx.String(http.StatusOK, http.StatusText(http.StatusOK))
+
+	var (
+		key    string
+		stream *Streams
+	)
+	g.streams.Range(func(k string, v *Streams) bool {
+		if v.Dialog == ctx.Dialog {
+			key, stream = k, v
+			return false
+		}
+		return true
+	})
+	if stream == nil {
+		return
+	}
+	g.streams.Delete(key)
+	g.delSession(key)
+	ctx.Log.Info("device bye", "stream", stream.StreamID, "channel_id", stream.ChannelID)
+
+	if stream.sms == nil {
+		return
+	}
+	g.sms.ReleaseSSRC(stream.sms, stream.ssrc)
+	if _, err := g.sms.CloseRTPServer(stream.sms, zlm.CloseRTPServerRequest{StreamID: stream.StreamID}); err != nil {
+		ctx.Log.Error("CloseRTPServer", "err", err, "stream", stream.StreamID)
+	}
+}
+
 /*
 根据设备的设备id和通道id进行播放，
 发送请求给zlm服务器进行数据，一般都是设备主动rtmp推流数据
@@ -68,25 +116,48 @@ func (g *GB28181API) Play(in *PlayInput) error {
 	defer ch.device.playMutex.Unlock()
 
 	// 播放中
-	key := "play:" + in.Channel.DeviceID + ":" + in.Channel.ChannelID
-	stream, ok := g.streams.LoadOrStore(key, &Streams{})
+	key := gb28181.PlaySessionID(in.Channel.DeviceID, in.Channel.ChannelID)
+	stream, ok := g.streams.LoadOrStore(key, &Streams{
+		DeviceID:  in.Channel.DeviceID,
+		ChannelID: in.Channel.ChannelID,
+		StreamID:  in.Channel.ID,
+		sms:       in.SMS,
+	})
 	if ok {
 		return nil
 	}
 
-	// 开启RTP服务器等待接收视频流
+	ssrc, err := g.sms.AllocSSRC(in.SMS, g.cfg.Domain, false, in.Channel.ID)
+	if err != nil {
+		g.streams.Delete(key)
+		return err
+	}
+	stream.ssrc = ssrc
+	v, _ := strconv.ParseUint(ssrc, 10, 32)
+
+	// 开启RTP服务器等待接收视频流，ssrc 不匹配的流将被丢弃
 	resp, err := g.sms.OpenRTPServer(in.SMS, zlm.OpenRTPServerRequest{
 		TCPMode:  in.StreamMode,
 		StreamID: in.Channel.ID,
+		SSRC:     uint32(v),
 	})
 	if err != nil {
+		g.streams.Delete(key)
+		g.sms.ReleaseSSRC(in.SMS, ssrc)
 		return err
 	}
 
+	stream.port = resp.Port
+
 	if err := g.sipPlayPush2(ch, in, resp.Port, stream); err != nil {
+		g.streams.Delete(key)
+		g.sms.ReleaseSSRC(in.SMS, ssrc)
+		if _, err := g.sms.CloseRTPServer(in.SMS, zlm.CloseRTPServerRequest{StreamID: in.Channel.ID}); err != nil {
+			slog.Error("CloseRTPServer", "err", err, "stream", in.Channel.ID)
+		}
 		return err
 	}
-
+	g.saveSession(key, stream)
 	return nil
 }
 
@@ -145,7 +216,7 @@ func (g *GB28181API) sipPlayPush2(ch *Channel, in *PlayInput, port int, stream *
 			},
 		},
 		Medias: []sdp.Media{video},
-		SSRC:   g.getSSRC(0),
+		SSRC:   stream.ssrc,
 		// URI:    fmt.Sprintf("%s:0", channel.ChannelID),
 	}
 
@@ -155,29 +226,37 @@ func (g *GB28181API) sipPlayPush2(ch *Channel, in *PlayInput, port int, stream *
 	// uri, _ := sip.ParseURI(channel.URIStr)
 	// channel.addr = &sip.Address{URI: uri}
 	// _serverDevices.addr.Params.Add("tag", sip.String{Str: sip.RandString(20)})
+	start := time.Now()
 	tx, err := g.svr.wrapRequest(ch, sip.MethodInvite, &sip.ContentTypeSDP, body, func(r *sip.Request) {
 		r.AppendHeader(&sip.GenericHeader{HeaderName: "Subject", Contents: fmt.Sprintf("%s:%s,%s:%s", ch.ChannelID, in.Channel.ID, in.Channel.DeviceID, in.Channel.ID)})
 	})
 	if err != nil {
+		inviteDuration.Since(start, "error")
 		return err
 	}
 	resp, err := sipResponse(tx)
 	if err != nil {
+		if resp == nil {
+			inviteDuration.Since(start, "timeout")
+		} else {
+			inviteDuration.Since(start, "error")
+		}
 		return err
 	}
+	inviteDuration.Since(start, "ok")
 
-	if contact, _ := resp.Contact(); contact == nil {
-		resp.AppendHeader(&sip.ContactHeader{
-			DisplayName: g.svr.fromAddress.DisplayName,
-			Address:     &sip.URI{FUser: sip.String{Str: g.cfg.ID}, FHost: g.cfg.Domain},
-			Params:      sip.NewParams(),
-		})
+	dialog, err := sip.NewDialog(resp)
+	if err != nil {
+		return err
 	}
+	g.svr.AddDialog(dialog)
+	stream.Dialog = dialog
 
-	stream.Resp = resp
-
-	ackReq := sip.NewRequestFromResponse(sip.MethodACK, resp)
-	return tx.Request(ackReq)
+	if err := tx.Request(dialog.NewRequest(sip.MethodACK, nil, nil)); err != nil {
+		g.svr.RemoveDialog(dialog)
+		return err
+	}
+	return nil
 
 	// data.Resp = response
 	// // ACK
@@ -346,9 +425,12 @@ func sipPlayPush(data *Streams, channel Channels, device Devices) (*Streams, err
 		// logrus.Warningln("sipPlayPush response fail.id:", device.DeviceID, channel.ChannelID, "err:", err)
 		return data, err
 	}
-	data.Resp = response
+	data.Dialog, err = sip.NewDialog(response)
+	if err != nil {
+		return data, err
+	}
 	// ACK
-	tx.Request(sip.NewRequestFromResponse(sip.MethodACK, response))
+	tx.Request(data.Dialog.NewRequest(sip.MethodACK, nil, nil))
 
 	callid, _ := response.CallID()
 	data.CallID = string(*callid)
@@ -379,15 +461,14 @@ func SipStopPlay(ssrc string) {
 		return
 	}
 	play := data.(*Streams)
-	if play.StreamType == m.StreamTypePush {
+	if play.StreamType == m.StreamTypePush && play.Dialog != nil {
 		// 推流，需要发送关闭请求
-		resp := play.Resp
 		u, ok := _activeDevices.Load(play.DeviceID)
 		if !ok {
 			return
 		}
 		user := u.(Devices)
-		req := sip.NewRequestFromResponse(sip.MethodBYE, resp)
+		req := play.Dialog.NewRequest(sip.MethodBYE, nil, nil)
 		req.SetDestination(user.source)
 		tx, err := svr.Request(req)
 		if err != nil {
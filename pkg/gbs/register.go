@@ -1,7 +1,7 @@
 package gbs
 
 import (
-	"fmt"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
@@ -10,6 +10,7 @@ import (
 	"github.com/ixugo/goweb/pkg/conc"
 	"github.com/ixugo/goweb/pkg/orm"
 	"wvp/internal/conf"
+	"wvp/internal/core/event"
 	"wvp/internal/core/gb28181"
 	"wvp/internal/core/sms"
 	"wvp/pkg/gbs/sip"
@@ -22,59 +23,35 @@ type GB28181API struct {
 	core gb28181.GB28181
 
 	catalog *sip.Collector[Channels]
+	// 最近一次目录核对结果，目录查询接口等待并返回
+	catalogDiffs *conc.Map[string, *gb28181.CatalogDiff]
 
-	// TODO: 待替换成 redis
-	streams *conc.Map[string, *Streams]
+	streams  *conc.Map[string, *Streams] // 运行中的点播会话
+	sessions gb28181.PlaySessionStorer   // 点播会话持久化，用于重启后恢复
 
 	svr *Server
 
 	sms *sms.NodeManager
+	bus *event.Bus
+
+	nonces sip.NonceStorer // 注册鉴权已下发的 nonce
 }
 
-func NewGB28181API(cfg *conf.Bootstrap, store gb28181.GB28181, sms *sms.NodeManager) *GB28181API {
+func NewGB28181API(cfg *conf.Bootstrap, store gb28181.GB28181, sms *sms.NodeManager, bus *event.Bus, sessions gb28181.PlaySessionStorer, nonces sip.NonceStorer) *GB28181API {
 	g := GB28181API{
-		cfg:  &cfg.Sip,
-		core: store,
-		sms:  sms,
-		catalog: sip.NewCollector[Channels](func(c1, c2 *Channels) bool {
-			return c1.ChannelID == c2.ChannelID
+		cfg:      &cfg.Sip,
+		core:     store,
+		sms:      sms,
+		bus:      bus,
+		sessions: sessions,
+		nonces:   nonces,
+		catalog: sip.NewCollector(catalogBatch, func(c *Channels) string {
+			return c.ChannelID
 		}),
-		streams: &conc.Map[string, *Streams]{},
+		streams:      &conc.Map[string, *Streams]{},
+		catalogDiffs: &conc.Map[string, *gb28181.CatalogDiff]{},
 	}
-	go g.catalog.Start(func(s string, c []*Channels) {
-		// 零值不做变更，没有通道又何必注册上来
-		if len(c) == 0 {
-			return
-		}
-
-		// ipc, ok := g.svr.devices.Load(s)
-		// if ok {
-		// 	ipc.channels.Clear()
-		// 	for _, ch := range c {
-		// 		ch := Channel{
-		// 			ChannelID: ch.ChannelID,
-		// 			device:    ipc,
-		// 		}
-		// 		ch.init(g.cfg.Domain)
-		// 		ipc.channels.Store(ch.ChannelID, &ch)
-		// 	}
-		// }
-
-		out := make([]*gb28181.Channel, len(c))
-		for i, ch := range c {
-			out[i] = &gb28181.Channel{
-				DeviceID:  s,
-				ChannelID: ch.ChannelID,
-				Name:      ch.Name,
-				IsOnline:  ch.Status == "OK" || ch.Status == "ON",
-				Ext: gb28181.DeviceExt{
-					Manufacturer: ch.Manufacturer,
-					Model:        ch.Model,
-				},
-			}
-		}
-		g.core.SaveChannels(out)
-	})
+	go g.startCatalog()
 	return &g
 }
 
@@ -84,38 +61,34 @@ func (g *GB28181API) handlerRegister(ctx *sip.Context) {
 		return
 	}
 
-	dev, err := g.core.GetDeviceByDeviceID(ctx.DeviceID)
-	if err != nil {
-		ctx.Log.Error("GetDeviceByDeviceID", "err", err)
-		ctx.String(http.StatusInternalServerError, "server db error")
+	// 先做白名单与来源 IP 检查，未知设备需鉴权通过后才记录，避免未鉴权的注册请求写库
+	in := gb28181.AdmitDeviceInput{
+		Policy:    g.cfg.AdmissionPolicy,
+		DeviceID:  ctx.DeviceID,
+		Transport: ctx.Transport(),
+		Address:   ctx.Source.String(),
+	}
+	dev, err := g.core.AdmitDevice(&in)
+	unknown := errors.Is(err, gb28181.ErrAdmissionUnknown)
+	if err != nil && !unknown {
+		g.rejectAdmission(ctx, err)
 		return
 	}
 
-	password := dev.Password
-	if password == "" {
-		password = g.cfg.Password
+	password := g.cfg.Password
+	if dev != nil && dev.Password != "" {
+		password = dev.Password
 	}
 	// 免鉴权
-	if dev.Password == ignorePassword {
+	if dev != nil && dev.Password == ignorePassword {
 		password = ""
 	}
-	if password != "" {
-		hdrs := ctx.Request.GetHeaders("Authorization")
-		if len(hdrs) == 0 {
-			resp := sip.NewResponseFromRequest("", ctx.Request, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized), nil)
-			resp.AppendHeader(&sip.GenericHeader{HeaderName: "WWW-Authenticate", Contents: fmt.Sprintf("Digest nonce=\"%s\", algorithm=MD5, realm=\"%s\",qop=\"auth\"", sip.RandString(32), g.cfg.Domain)})
-			_ = ctx.Tx.Respond(resp)
-			return
-		}
-		authenticateHeader := hdrs[0].(*sip.GenericHeader)
-		auth := sip.AuthFromValue(authenticateHeader.Contents)
-		auth.SetPassword(password)
-		auth.SetUsername(dev.DeviceID)
-		auth.SetMethod(ctx.Request.Method())
-		auth.SetURI(auth.Get("uri"))
-		if auth.CalcResponse() != auth.Get("response") {
-			ctx.Log.Info("设备注册鉴权失败")
-			ctx.String(http.StatusUnauthorized, "wrong password")
+	if password != "" && !g.authenticate(ctx, ctx.DeviceID, password) {
+		return
+	}
+	if unknown {
+		if dev, err = g.core.AdmitUnknownDevice(&in); err != nil {
+			g.rejectAdmission(ctx, err)
 			return
 		}
 	}
@@ -132,7 +105,7 @@ func (g *GB28181API) handlerRegister(ctx *sip.Context) {
 	expire := ctx.GetHeader("Expires")
 	if expire == "0" {
 		ctx.Log.Info("设备注销")
-		g.logout(ctx.DeviceID, func(b *gb28181.Device) {
+		g.logout(ctx.DeviceID, "unregister", func(b *gb28181.Device) {
 			b.IsOnline = false
 			b.Address = ctx.Source.String()
 		})
@@ -141,37 +114,149 @@ func (g *GB28181API) handlerRegister(ctx *sip.Context) {
 	}
 	g.login(ctx, expire)
 
-	conn := ctx.Request.GetConnection()
-	fmt.Printf(">>> %p\n", conn)
-
 	ctx.Log.Info("设备注册成功")
 
 	respFn()
 
 	g.QueryDeviceInfo(ctx)
+	g.QueryConfigDownload(ctx)
 	g.QueryCatalog(dev.DeviceID)
 }
 
+// rejectAdmission 准入失败时响应设备
+func (g *GB28181API) rejectAdmission(ctx *sip.Context, err error) {
+	if errors.Is(err, gb28181.ErrAdmissionDenied) || errors.Is(err, gb28181.ErrAdmissionPending) {
+		ctx.Log.Info("设备准入拒绝", "err", err, "source", ctx.Source)
+		ctx.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+	ctx.Log.Error("AdmitDevice", "err", err)
+	ctx.String(http.StatusInternalServerError, "server db error")
+}
+
+// authenticate 注册鉴权，失败时已响应 401
+func (g *GB28181API) authenticate(ctx *sip.Context, deviceID, password string) bool {
+	hdrs := ctx.Request.GetHeaders("Authorization")
+	if len(hdrs) == 0 {
+		g.challenge(ctx, false)
+		return false
+	}
+	authenticateHeader, ok := hdrs[0].(*sip.GenericHeader)
+	if !ok {
+		g.challenge(ctx, false)
+		return false
+	}
+	auth := sip.AuthFromValue(authenticateHeader.Contents)
+	if !sip.SupportedAlgorithm(auth.Algorithm()) {
+		ctx.Log.Info("设备注册鉴权失败", "algorithm", auth.Algorithm())
+		g.challenge(ctx, false)
+		return false
+	}
+	if auth.Realm() != g.cfg.Domain {
+		ctx.Log.Info("设备注册鉴权失败", "realm", auth.Realm())
+		g.challenge(ctx, false)
+		return false
+	}
+	auth.SetRealm(g.cfg.Domain)
+	auth.SetPassword(password)
+	auth.SetUsername(deviceID)
+	auth.SetMethod(ctx.Request.Method())
+	auth.SetURI(auth.Get("uri"))
+	ip := sip.SourceIP(ctx.Source)
+	if auth.CalcResponse() != auth.Get("response") {
+		ctx.Log.Info("设备注册鉴权失败", "ip", ip)
+		g.svr.Guard().Fail(ip, "wrong password")
+		ctx.String(http.StatusUnauthorized, "wrong password")
+		return false
+	}
+	g.svr.Guard().Success(ip)
+	// 凭据正确但 nonce 过期或重放，要求设备使用新 nonce 重新计算
+	if err := g.nonces.Use(auth.Nonce(), auth.NC()); errors.Is(err, sip.ErrStaleNonce) {
+		ctx.Log.Info("设备注册 nonce 失效", "err", err)
+		g.challenge(ctx, true)
+		return false
+	} else if err != nil {
+		ctx.Log.Error("校验 nonce 失败", "err", err)
+		ctx.String(http.StatusInternalServerError, "nonce store unavailable")
+		return false
+	}
+	return true
+}
+
+// challenge 下发新的 nonce 要求设备鉴权
+func (g *GB28181API) challenge(ctx *sip.Context, stale bool) {
+	algorithm := g.cfg.Auth.Algorithm
+	if algorithm == "" {
+		algorithm = sip.AlgorithmMD5
+	}
+	nonce, err := g.nonces.Issue()
+	if err != nil {
+		ctx.Log.Error("下发 nonce 失败", "err", err)
+		ctx.String(http.StatusInternalServerError, "nonce store unavailable")
+		return
+	}
+	resp := sip.NewResponseFromRequest("", ctx.Request, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized), nil)
+	resp.AppendHeader(&sip.GenericHeader{
+		HeaderName: "WWW-Authenticate",
+		Contents:   sip.Challenge(g.cfg.Domain, nonce, algorithm, stale),
+	})
+	_ = ctx.Tx.Respond(resp)
+}
+
 func (g GB28181API) login(ctx *sip.Context, expire string) {
 	slog.Info("status change 设备上线", "device_id", ctx.DeviceID)
-	g.svr.memoryStorer.Change(ctx.DeviceID, func(d *gb28181.Device) {
+	var id string
+	var wasOnline bool
+	if err := g.svr.memoryStorer.Change(ctx.DeviceID, func(d *gb28181.Device) {
+		id, wasOnline = d.ID, d.IsOnline
 		d.IsOnline = true
 		d.RegisteredAt = orm.Now()
 		d.KeepaliveAt = orm.Now()
+		d.Trasnport = ctx.Transport()
 		d.Expires, _ = strconv.Atoi(expire)
 	}, func(d *Device) {
 		d.conn = ctx.Request.GetConnection()
 		d.source = ctx.Source
 		d.to = ctx.To
+		d.transport = ctx.Transport()
+		d.Node = g.svr.cluster.NodeID()
+	}); err != nil || wasOnline {
+		return
+	}
+	g.bus.Publish(event.TypeDeviceOnline, event.Device{
+		ID:        id,
+		DeviceID:  ctx.DeviceID,
+		Address:   ctx.Source.String(),
+		Transport: ctx.Transport(),
 	})
 }
 
-func (g GB28181API) logout(deviceID string, changeFn func(*gb28181.Device)) error {
-	slog.Info("status change 设备离线", "device_id", deviceID)
-	return g.svr.memoryStorer.Change(deviceID, changeFn, func(d *Device) {
+// logout 设备离线，reason 为离线原因
+func (g GB28181API) logout(deviceID, reason string, changeFn func(*gb28181.Device)) error {
+	slog.Info("status change 设备离线", "device_id", deviceID, "reason", reason)
+	var dev gb28181.Device
+	var wasOnline bool
+	if err := g.svr.memoryStorer.Change(deviceID, func(d *gb28181.Device) {
+		wasOnline = d.IsOnline
+		changeFn(d)
+		dev = *d
+	}, func(d *Device) {
 		d.conn = nil
 		d.source = nil
 		d.to = nil
 		d.Expires = 0
-	})
+		d.Node = ""
+	}); err != nil {
+		return err
+	}
+	if wasOnline {
+		g.bus.Publish(event.TypeDeviceOffline, event.Device{
+			ID:        dev.ID,
+			DeviceID:  deviceID,
+			Address:   dev.Address,
+			Transport: dev.Trasnport,
+			Reason:    reason,
+		})
+	}
+	return nil
 }
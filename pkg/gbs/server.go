@@ -3,6 +3,7 @@ package gbs
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
@@ -13,10 +14,15 @@ import (
 	"github.com/ixugo/goweb/pkg/conc"
 	"github.com/ixugo/goweb/pkg/system"
 	"wvp/internal/conf"
+	"wvp/internal/core/audit"
+	"wvp/internal/core/cluster"
+	"wvp/internal/core/event"
 	"wvp/internal/core/gb28181"
 	"wvp/internal/core/sms"
 	"wvp/pkg/gbs/m"
 	"wvp/pkg/gbs/sip"
+	"wvp/pkg/gbs/sip/sipredis"
+	"wvp/pkg/redis"
 )
 
 type MemoryStorer interface {
@@ -29,6 +35,9 @@ type MemoryStorer interface {
 	Store(deviceID string, value *Device)
 	GetChannel(deviceID, channelID string) (*Channel, bool)
 
+	Sync(ctx context.Context) error // 集群部署时同步其它实例持有的设备状态
+	Owner(deviceID string) string   // 持有设备信令连接的实例，单实例部署时为空串
+
 	// Change(deviceID string, changeFn func(*gb28181.Device)) // 修改设备
 }
 
@@ -39,10 +48,20 @@ type Server struct {
 
 	fromAddress  *sip.Address
 	memoryStorer MemoryStorer
+	cluster      *cluster.Cluster
 }
 
-func NewServer(cfg *conf.Bootstrap, store gb28181.GB28181, sc sms.Core) (*Server, func()) {
-	api := NewGB28181API(cfg, store, sc.NodeManager)
+// NewServer 集群部署时 nonce 与封禁记录保存在 redis，多个实例共享
+func NewServer(cfg *conf.Bootstrap, store gb28181.GB28181, sc sms.Core, bus *event.Bus, sessions gb28181.PlaySessionStorer, cl *cluster.Cluster, cli *redis.Client) (*Server, func()) {
+	nonceExpires := time.Duration(cfg.Sip.Auth.NonceExpires)
+	if nonceExpires <= 0 {
+		nonceExpires = 5 * time.Minute
+	}
+	var nonces sip.NonceStorer = sip.NewNonceStore(nonceExpires)
+	if cl.Enabled() {
+		nonces = sipredis.NewNonceStore(cli, cfg.Data.Redis.Prefix, nonceExpires)
+	}
+	api := NewGB28181API(cfg, store, sc.NodeManager, bus, sessions, nonces)
 
 	ip := system.LocalIP()
 	uri, _ := sip.ParseSipURI(fmt.Sprintf("sip:%s@%s:%d", cfg.Sip.ID, ip, cfg.Sip.Port))
@@ -53,11 +72,29 @@ func NewServer(cfg *conf.Bootstrap, store gb28181.GB28181, sc sms.Core) (*Server
 	}
 
 	svr = sip.NewServer(&from)
+	guardCfg := cfg.Sip.Guard
+	guard := sip.NewGuard(sip.GuardConfig{
+		Rate:        guardCfg.Rate,
+		Burst:       guardCfg.Burst,
+		MaxFailures: guardCfg.MaxFailures,
+		FailWindow:  time.Duration(guardCfg.FailWindow),
+		BanDuration: time.Duration(guardCfg.BanDuration),
+	})
+	if cl.Enabled() {
+		guard.SetStore(sipredis.NewGuardStore(cli, cfg.Data.Redis.Prefix))
+	}
+	svr.SetGuard(guard)
+	if capture := cfg.Sip.Capture; capture.Enabled {
+		svr.SetCapture(sip.NewCapture(capture.Size))
+	}
 	svr.Register(api.handlerRegister)
 	msg := svr.Message()
 	msg.Handle("Keepalive", api.sipMessageKeepalive)
 	msg.Handle("Catalog", api.sipMessageCatalog)
 	msg.Handle("DeviceInfo", api.sipMessageDeviceInfo)
+	msg.Handle("ConfigDownload", api.sipMessageConfigDownload)
+	msg.Handle("Alarm", api.sipMessageAlarm)
+	svr.Bye(api.handlerBye)
 
 	// msg.Handle("RecordInfo", api.handlerMessage)
 
@@ -67,12 +104,19 @@ func NewServer(cfg *conf.Bootstrap, store gb28181.GB28181, sc sms.Core) (*Server
 		fromAddress:  &from,
 		gb:           api,
 		memoryStorer: store.Store().(MemoryStorer),
+		cluster:      cl,
 	}
 	api.svr = &c
+	svr.OnTCPClose(c.onTCPClose)
 
 	go svr.ListenUDPServer(fmt.Sprintf(":%d", cfg.Sip.Port))
 	go svr.ListenTCPServer(fmt.Sprintf(":%d", cfg.Sip.Port))
+	if tls := cfg.Sip.TLS; tls.Enabled {
+		go svr.ListenTLSServer(fmt.Sprintf(":%d", tls.Port), tls.CertFile, tls.KeyFile)
+	}
 	go c.startTickerCheck()
+	go c.startGuardSync()
+	go c.startCatalogSync(cfg.Sip.CatalogInterval.Duration())
 	// 等待 UDP 连接
 	for {
 		time.Sleep(50 * time.Millisecond)
@@ -81,20 +125,45 @@ func NewServer(cfg *conf.Bootstrap, store gb28181.GB28181, sc sms.Core) (*Server
 			break
 		}
 	}
+	go api.RecoverSessions(context.Background())
 	return &c, c.Close
 }
 
-// startTickerCheck 定时检查离线
+// startGuardSync 集群部署时定时同步其它实例的封禁记录
+func (s *Server) startGuardSync() {
+	if !s.cluster.Enabled() {
+		return
+	}
+	conc.Timer(context.Background(), 5*time.Second, time.Second, func() {
+		if err := s.Guard().Sync(); err != nil {
+			slog.Error("同步共享封禁记录失败", "err", err)
+		}
+	})
+}
+
+// startTickerCheck 定时检查离线，截止时间由设备心跳参数与注册有效期计算
+// 集群部署时仅主节点执行，检查前同步其它实例持有的设备状态
 func (s *Server) startTickerCheck() {
 	conc.Timer(context.Background(), 60*time.Second, time.Second, func() {
+		if !s.cluster.IsLeader() {
+			return
+		}
+		if err := s.memoryStorer.Sync(context.Background()); err != nil {
+			slog.Error("同步设备共享状态失败", "err", err)
+			return
+		}
 		now := time.Now()
 		s.memoryStorer.RangeDevices(func(key string, value *Device) bool {
 			if !value.IsOnline {
 				return true
 			}
 
-			if sub := now.Sub(value.LastKeepaliveAt); sub >= 3*60*time.Second || value.conn == nil {
-				s.gb.logout(key, func(d *gb28181.Device) {
+			reason, expired := value.expired(now)
+			if value.conn == nil && s.cluster.IsLocal(value.Node) {
+				reason, expired = "keepalive timeout", true
+			}
+			if expired {
+				s.gb.logout(key, reason, func(d *gb28181.Device) {
 					d.IsOnline = false
 				})
 			}
@@ -103,6 +172,82 @@ func (s *Server) startTickerCheck() {
 	})
 }
 
+// onTCPClose 设备 tcp 连接断开，设备离线
+func (s *Server) onTCPClose(conn sip.Connection) {
+	s.memoryStorer.RangeDevices(func(key string, value *Device) bool {
+		if value.conn != conn {
+			return true
+		}
+		if err := s.gb.logout(key, "tcp connection closed", func(d *gb28181.Device) {
+			d.IsOnline = false
+		}); err != nil {
+			slog.Error("logout", "err", err, "device_id", key)
+		}
+		return true
+	})
+}
+
+// targetConn 获取设备的信令连接，tcp 设备没有可用连接时主动连接设备地址
+func (s *Server) targetConn(t Targeter) (sip.Connection, error) {
+	if conn := t.Conn(); conn != nil {
+		return conn, nil
+	}
+	var dev *Device
+	switch v := t.(type) {
+	case *Device:
+		dev = v
+	case *Channel:
+		dev = v.device
+	}
+	if dev != nil && dev.transport == "tls" {
+		// tls 设备无法主动连接，等待设备重新连接
+		return nil, ErrDeviceOffline
+	}
+	if dev == nil || !isTCP(dev.source) {
+		if conn := s.UDPConn(); conn != nil {
+			return conn, nil
+		}
+		return nil, ErrDeviceOffline
+	}
+
+	dev.dialMutex.Lock()
+	defer dev.dialMutex.Unlock()
+	if dev.conn != nil {
+		return dev.conn, nil
+	}
+	conn, err := s.DialTCP(dev.source.String())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDeviceOffline, err)
+	}
+	dev.conn = conn
+	return conn, nil
+}
+
+// DeviceStats 内存中设备在线/离线数量
+func (s *Server) DeviceStats() (online, offline int) {
+	s.memoryStorer.RangeDevices(func(_ string, value *Device) bool {
+		if value.IsOnline {
+			online++
+		} else {
+			offline++
+		}
+		return true
+	})
+	return
+}
+
+// PlaySessions 已建立的国标点播会话数量
+func (s *Server) PlaySessions() int {
+	var n int
+	s.gb.streams.Range(func(_ string, v *Streams) bool {
+		if v.Dialog != nil {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
 // MODDEBUG MODDEBUG
 var MODDEBUG = "DEBUG"
 
@@ -131,7 +276,7 @@ func LoadSYSInfo() {
 	config = m.MConfig
 	_activeDevices = ActiveDevices{sync.Map{}}
 
-	StreamList = streamsList{&sync.Map{}, &sync.Map{}, 0}
+	StreamList = streamsList{&sync.Map{}, &sync.Map{}}
 	ssrcLock = &sync.Mutex{}
 	_recordList = &sync.Map{}
 	RecordList = apiRecordList{items: map[string]*apiRecordItem{}, l: sync.RWMutex{}}
@@ -196,15 +341,43 @@ func sipResponse(tx *sip.Transaction) (*sip.Response, error) {
 	return response, nil
 }
 
-// QueryCatalog 查询 catalog
-func (s *Server) QueryCatalog(deviceID string) error {
+// QueryCatalog 查询 catalog 并返回目录核对结果，设备由其它实例持有时转发至该实例
+func (s *Server) QueryCatalog(deviceID string) (*gb28181.CatalogDiff, error) {
+	if node, ok := s.remoteOwner(deviceID); ok {
+		var out *gb28181.CatalogDiff
+		err := s.forward(node, forwardCatalog, forwardCatalogInput{DeviceID: deviceID}, &out)
+		return out, err
+	}
 	return s.gb.QueryCatalog(deviceID)
 }
 
-func (s *Server) Play(in *PlayInput) error {
+// CatalogProgress 设备目录接收进度，设备由其它实例持有时转发至该实例
+func (s *Server) CatalogProgress(deviceID string) (*sip.CollectorProgress, error) {
+	if node, ok := s.remoteOwner(deviceID); ok {
+		var out *sip.CollectorProgress
+		err := s.forward(node, forwardProgress, forwardCatalogInput{DeviceID: deviceID}, &out)
+		return out, err
+	}
+	return s.gb.CatalogProgress(deviceID)
+}
+
+// Play 点播通道，设备由其它实例持有时转发至该实例，并记录审计结果
+func (s *Server) Play(ctx context.Context, in *PlayInput) error {
+	err := s.play(in)
+	audit.RecordResult(ctx, audit.ActionPlay, in.Channel.ID, nil, err)
+	return err
+}
+
+func (s *Server) play(in *PlayInput) error {
+	if node, ok := s.remoteOwner(in.Channel.DeviceID); ok {
+		return s.forward(node, forwardPlay, in, nil)
+	}
 	return s.gb.Play(in)
 }
 
 func (s *Server) StopPlay(in *StopPlayInput) error {
+	if node, ok := s.remoteOwner(in.Channel.DeviceID); ok {
+		return s.forward(node, forwardStopPlay, in, nil)
+	}
 	return s.gb.StopPlay(in)
 }
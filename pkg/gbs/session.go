@@ -0,0 +1,203 @@
+package gbs
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/ixugo/goweb/pkg/orm"
+	"wvp/internal/core/gb28181"
+	"wvp/internal/core/sms"
+	"wvp/pkg/gbs/sip"
+	"wvp/pkg/zlm"
+)
+
+// saveSession 持久化点播会话
+func (g *GB28181API) saveSession(key string, stream *Streams) {
+	if stream.Dialog == nil {
+		return
+	}
+	b, _ := json.Marshal(stream.Dialog.State())
+	s := gb28181.PlaySession{
+		ID:        key,
+		DeviceID:  stream.DeviceID,
+		ChannelID: stream.ChannelID,
+		StreamID:  stream.StreamID,
+		SSRC:      stream.ssrc,
+		RTPPort:   stream.port,
+		CallID:    stream.Dialog.CallID(),
+		Dialog:    string(b),
+		Node:      g.svr.cluster.NodeID(),
+		CreatedAt: orm.Now(),
+	}
+	if stream.sms != nil {
+		s.MediaServerID = stream.sms.ID
+	}
+	if err := g.sessions.Save(context.Background(), &s); err != nil {
+		slog.Error("保存点播会话失败", "err", err, "id", key)
+	}
+}
+
+// delSession 删除持久化的点播会话
+func (g *GB28181API) delSession(key string) {
+	if err := g.sessions.Del(context.Background(), key); err != nil {
+		slog.Error("删除点播会话失败", "err", err, "id", key)
+	}
+}
+
+// RecoverSessions 程序启动时核对上次运行遗留的点播会话
+// 媒体服务器上仍有该流时恢复会话，否则向设备发送 BYE 并释放 ssrc 与 rtp 端口
+// 集群部署时仅核对本实例建立的会话
+func (g *GB28181API) RecoverSessions(ctx context.Context) {
+	items, err := g.sessions.List(ctx)
+	if err != nil {
+		slog.Error("加载点播会话失败", "err", err)
+		return
+	}
+	var adopted, closed int
+	for _, s := range items {
+		if !g.svr.cluster.IsLocal(s.Node) {
+			continue
+		}
+		if g.recoverSession(ctx, s) {
+			adopted++
+		} else {
+			closed++
+		}
+	}
+	if adopted+closed > 0 {
+		slog.Info("点播会话核对完成", "adopted", adopted, "closed", closed)
+	}
+	g.releaseOrphanSSRCs(ctx, items)
+}
+
+// orphanSSRCAge 超过该时间仍未被会话引用的 ssrc 视为遗留，需大于 INVITE 事务超时
+const orphanSSRCAge = time.Minute
+
+// releaseOrphanSSRCs 释放未被点播会话引用的 ssrc
+// ssrc 在 INVITE 前分配，会话在 ACK 后持久化，期间程序退出会遗留 ssrc
+func (g *GB28181API) releaseOrphanSSRCs(ctx context.Context, items []*gb28181.PlaySession) {
+	// 集群部署时内存存储看不到其它实例的会话
+	if g.svr.cluster.Enabled() && g.cfg.Session.Store == "memory" {
+		return
+	}
+	refs := make(map[string]struct{}, len(items))
+	for _, s := range items {
+		refs[s.MediaServerID+":"+s.SSRC] = struct{}{}
+	}
+	n, err := g.sms.ReleaseOrphanSSRCs(ctx, time.Now().Add(-orphanSSRCAge), func(serverID, ssrc string) bool {
+		_, ok := refs[serverID+":"+ssrc]
+		return ok
+	})
+	if err != nil {
+		slog.Error("释放遗留的 ssrc 失败", "err", err)
+		return
+	}
+	if n > 0 {
+		slog.Info("释放遗留的 ssrc", "count", n)
+	}
+}
+
+// recoverSession 返回会话是否被恢复
+func (g *GB28181API) recoverSession(ctx context.Context, s *gb28181.PlaySession) bool {
+	log := slog.With("id", s.ID, "stream", s.StreamID, "call_id", s.CallID)
+
+	dialog, err := restoreDialog(s.Dialog)
+	if err != nil {
+		log.Warn("点播会话对话状态无效", "err", err)
+	}
+
+	ms, err := g.svr.mediaService.GetMediaServer(ctx, s.MediaServerID)
+	if err != nil {
+		log.Warn("点播会话的媒体服务器不存在", "err", err, "media_server_id", s.MediaServerID)
+		ms = &sms.MediaServer{ID: s.MediaServerID}
+	} else if dialog != nil {
+		live, err := g.streamAlive(ms, s)
+		if err != nil {
+			// 无法确认时保留会话，由用户停止播放或设备 BYE 时释放
+			log.Warn("查询媒体服务器流状态失败，保留会话", "err", err)
+			live = true
+		}
+		if live {
+			g.adoptSession(s, dialog, ms)
+			log.Info("恢复点播会话")
+			return true
+		}
+	}
+
+	log.Info("关闭失效的点播会话")
+	if dialog != nil {
+		g.sendBye(s, dialog)
+	}
+	if ms.IP != "" {
+		if _, err := g.sms.CloseRTPServer(ms, zlm.CloseRTPServerRequest{StreamID: s.StreamID}); err != nil {
+			log.Debug("CloseRTPServer", "err", err)
+		}
+	}
+	g.sms.ReleaseSSRC(ms, s.SSRC)
+	g.delSession(s.ID)
+	return false
+}
+
+// streamAlive 媒体服务器上流已注册，或 rtp 端口仍在接收数据
+func (g *GB28181API) streamAlive(ms *sms.MediaServer, s *gb28181.PlaySession) (bool, error) {
+	list, err := g.sms.GetMediaList(ms, zlm.GetMediaListRequest{App: "rtp", Stream: s.StreamID})
+	if err != nil {
+		return false, err
+	}
+	if len(list.Data) > 0 {
+		return true, nil
+	}
+	info, err := g.sms.GetRTPInfo(ms, zlm.GetRTPInfoRequest{StreamID: s.StreamID})
+	if err != nil {
+		return false, err
+	}
+	return info.Exist && (s.RTPPort == 0 || info.LocalPort == s.RTPPort), nil
+}
+
+// adoptSession 恢复会话到内存，设备发送的 BYE 与停止播放可正常处理
+func (g *GB28181API) adoptSession(s *gb28181.PlaySession, dialog *sip.Dialog, ms *sms.MediaServer) {
+	g.svr.AddDialog(dialog)
+	g.streams.Store(s.ID, &Streams{
+		DeviceID:  s.DeviceID,
+		ChannelID: s.ChannelID,
+		StreamID:  s.StreamID,
+		ssrc:      s.SSRC,
+		port:      s.RTPPort,
+		Dialog:    dialog,
+		sms:       ms,
+	})
+}
+
+// sendBye 通知设备停止推流，设备不在线时忽略
+func (g *GB28181API) sendBye(s *gb28181.PlaySession, dialog *sip.Dialog) {
+	log := slog.With("id", s.ID, "call_id", s.CallID)
+	ch, ok := g.svr.memoryStorer.GetChannel(s.DeviceID, s.ChannelID)
+	if !ok || ch.Source() == nil {
+		log.Debug("设备不存在或地址未知，跳过 BYE")
+		return
+	}
+	conn, err := g.svr.targetConn(ch)
+	if err != nil {
+		log.Debug("设备连接不可用，跳过 BYE", "err", err)
+		return
+	}
+	dialog.SetTarget(ch.Source(), conn)
+	tx, err := g.svr.Request(dialog.NewRequest(sip.MethodBYE, nil, nil))
+	if err != nil {
+		log.Warn("发送 BYE 失败", "err", err)
+		return
+	}
+	if _, err := sipResponse(tx); err != nil {
+		log.Debug("BYE 响应", "err", err)
+	}
+}
+
+func restoreDialog(v string) (*sip.Dialog, error) {
+	var state sip.DialogState
+	if err := json.Unmarshal([]byte(v), &state); err != nil {
+		return nil, err
+	}
+	return sip.RestoreDialog(state)
+}
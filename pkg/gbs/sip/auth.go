@@ -2,13 +2,21 @@ package sip
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"hash"
 	"regexp"
 	"strings"
 )
 
-// Authorization currently only Digest and MD5
+// 摘要算法，GB28181-2022 允许使用 SHA-256
+const (
+	AlgorithmMD5    = "MD5"
+	AlgorithmSHA256 = "SHA-256"
+)
+
+// Authorization Digest 鉴权，支持 MD5 与 SHA-256
 type Authorization struct {
 	realm     string
 	nonce     string
@@ -28,7 +36,7 @@ type Authorization struct {
 // AuthFromValue AuthFromValue
 func AuthFromValue(value string) *Authorization {
 	auth := &Authorization{
-		algorithm: "MD5",
+		algorithm: AlgorithmMD5,
 		other:     make(map[string]string),
 		Data:      make(map[string]string),
 	}
@@ -81,6 +89,38 @@ func (auth *Authorization) SetUsername(username string) *Authorization {
 	return auth
 }
 
+// SetRealm 使用服务端的 realm 计算，避免客户端篡改
+func (auth *Authorization) SetRealm(realm string) *Authorization {
+	auth.realm = realm
+
+	return auth
+}
+
+// Realm Realm
+func (auth *Authorization) Realm() string {
+	return auth.realm
+}
+
+// Nonce Nonce
+func (auth *Authorization) Nonce() string {
+	return auth.nonce
+}
+
+// Algorithm Algorithm
+func (auth *Authorization) Algorithm() string {
+	return auth.algorithm
+}
+
+// Qop 客户端未携带 qop 时为空，兼容 RFC 2069
+func (auth *Authorization) Qop() string {
+	return auth.qop
+}
+
+// NC nonce-count
+func (auth *Authorization) NC() string {
+	return auth.nc
+}
+
 // SetURI SetURI
 func (auth *Authorization) SetURI(uri string) *Authorization {
 	auth.uri = uri
@@ -104,7 +144,8 @@ func (auth *Authorization) SetPassword(password string) *Authorization {
 
 // CalcResponse CalcResponse
 func (auth *Authorization) CalcResponse() string {
-	auth.response = CalcResponse(
+	auth.response = CalcResponseWithAlgorithm(
+		auth.algorithm,
 		auth.username,
 		auth.realm,
 		auth.password,
@@ -142,20 +183,45 @@ func (auth *Authorization) String() string {
 
 // CalcResponse Authorization response https://www.ietf.org/rfc/rfc2617.txt
 func CalcResponse(username, realm, password, method, uri, nonce, qop, cnonce, nc string) string {
+	return CalcResponseWithAlgorithm(AlgorithmMD5, username, realm, password, method, uri, nonce, qop, cnonce, nc)
+}
+
+// SupportedAlgorithm 是否支持的摘要算法
+func SupportedAlgorithm(algorithm string) bool {
+	return newDigestHash(algorithm) != nil
+}
+
+func newDigestHash(algorithm string) func() hash.Hash {
+	switch strings.ToUpper(algorithm) {
+	case "", AlgorithmMD5:
+		return md5.New
+	case AlgorithmSHA256:
+		return sha256.New
+	}
+	return nil
+}
+
+// CalcResponseWithAlgorithm 按算法计算 response https://www.rfc-editor.org/rfc/rfc7616
+// 不支持的算法返回空字符串
+func CalcResponseWithAlgorithm(algorithm, username, realm, password, method, uri, nonce, qop, cnonce, nc string) string {
+	newHash := newDigestHash(algorithm)
+	if newHash == nil {
+		return ""
+	}
 	calcA1 := func() string {
-		encoder := md5.New()
+		encoder := newHash()
 		encoder.Write([]byte(username + ":" + realm + ":" + password))
 
 		return hex.EncodeToString(encoder.Sum(nil))
 	}
 	calcA2 := func() string {
-		encoder := md5.New()
+		encoder := newHash()
 		encoder.Write([]byte(method + ":" + uri))
 
 		return hex.EncodeToString(encoder.Sum(nil))
 	}
 
-	encoder := md5.New()
+	encoder := newHash()
 	encoder.Write([]byte(calcA1() + ":" + nonce + ":"))
 	if qop != "" {
 		encoder.Write([]byte(nc + ":" + cnonce + ":" + qop + ":"))
@@ -163,3 +229,12 @@ func CalcResponse(username, realm, password, method, uri, nonce, qop, cnonce, nc
 	encoder.Write([]byte(calcA2()))
 	return hex.EncodeToString(encoder.Sum(nil))
 }
+
+// Challenge WWW-Authenticate 质询内容，stale 表示 nonce 过期但凭据正确，客户端无需重新输入密码
+func Challenge(realm, nonce, algorithm string, stale bool) string {
+	str := fmt.Sprintf(`Digest realm="%s",nonce="%s",algorithm=%s,qop="auth"`, realm, nonce, algorithm)
+	if stale {
+		str += ",stale=true"
+	}
+	return str
+}
@@ -0,0 +1,72 @@
+package sip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalcResponseWithAlgorithm(t *testing.T) {
+	// RFC 7616 3.9.1
+	const (
+		nonce  = "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"
+		cnonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+	)
+	for _, tc := range []struct {
+		algorithm string
+		expect    string
+	}{
+		{AlgorithmMD5, "8ca523f5e9506fed4657c9700eebdbec"},
+		{AlgorithmSHA256, "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+		{"SHA-512-256", ""},
+	} {
+		got := CalcResponseWithAlgorithm(tc.algorithm, "Mufasa", "http-auth@example.org", "Circle of Life", "GET", "/dir/index.html", nonce, "auth", cnonce, "00000001")
+		if got != tc.expect {
+			t.Fatalf("%s: expect %s, got %s", tc.algorithm, tc.expect, got)
+		}
+	}
+}
+
+func TestAuthFromValue(t *testing.T) {
+	auth := AuthFromValue(`Digest username="34020000001320000001",realm="3402000000",nonce="abc",uri="sip:34020000002000000001@3402000000",response="r",algorithm=SHA-256,cnonce="c",qop=auth,nc=00000002`)
+	if auth.Algorithm() != AlgorithmSHA256 || auth.Realm() != "3402000000" || auth.Qop() != "auth" || auth.NC() != "00000002" {
+		t.Fatalf("unexpected auth %s", auth)
+	}
+}
+
+func TestNonceStore(t *testing.T) {
+	s := NewNonceStore(time.Minute)
+	nonce, _ := s.Issue()
+
+	if err := s.Use("unknown", "00000001"); err != ErrStaleNonce {
+		t.Fatal("expect unknown nonce stale")
+	}
+	if err := s.Use(nonce, "00000001"); err != nil {
+		t.Fatal(err)
+	}
+	// nonce-count 必须递增
+	if err := s.Use(nonce, "00000001"); err != ErrStaleNonce {
+		t.Fatal("expect replayed nc stale")
+	}
+	if err := s.Use(nonce, "00000002"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Use(nonce, ""); err != ErrStaleNonce {
+		t.Fatal("expect used nonce stale without qop")
+	}
+
+	// 未携带 qop 时只能使用一次
+	nonce, _ = s.Issue()
+	if err := s.Use(nonce, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Use(nonce, ""); err != ErrStaleNonce {
+		t.Fatal("expect replayed nonce stale")
+	}
+
+	expired := NewNonceStore(time.Millisecond)
+	nonce, _ = expired.Issue()
+	time.Sleep(5 * time.Millisecond)
+	if err := expired.Use(nonce, "00000001"); err != ErrStaleNonce {
+		t.Fatal("expect expired nonce stale")
+	}
+}
@@ -0,0 +1,242 @@
+package sip
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// captureMaxDevices 最多保留的设备数，超出时淘汰最久未更新的设备
+const captureMaxDevices = 4096
+
+// CaptureMessage 抓取的信令报文
+type CaptureMessage struct {
+	ID        uint64    `json:"id"`
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // in:设备发往平台 out:平台发往设备
+	DeviceID  string    `json:"device_id"`
+	Network   string    `json:"network"` // udp/tcp/tls
+	Src       string    `json:"src"`
+	Dst       string    `json:"dst"`
+	Method    string    `json:"method"`
+	Status    int       `json:"status,omitempty"` // 响应状态码，请求为 0
+	CallID    string    `json:"call_id"`
+	Data      string    `json:"data"`
+}
+
+type captureRing struct {
+	items     []CaptureMessage
+	next      int
+	updatedAt time.Time
+}
+
+func (r *captureRing) add(msg CaptureMessage, size int) {
+	if len(r.items) < size {
+		r.items = append(r.items, msg)
+	} else {
+		r.items[r.next] = msg
+		r.next = (r.next + 1) % size
+	}
+	r.updatedAt = msg.Time
+}
+
+// list 按时间先后返回
+func (r *captureRing) list() []CaptureMessage {
+	out := make([]CaptureMessage, 0, len(r.items))
+	out = append(out, r.items[r.next:]...)
+	return append(out, r.items[:r.next]...)
+}
+
+type captureSub struct {
+	deviceID string
+	fn       func(CaptureMessage)
+}
+
+// Capture 按设备保存最近的信令报文，用于排查设备兼容问题
+type Capture struct {
+	size int
+
+	mu    sync.RWMutex
+	seq   uint64
+	rings map[string]*captureRing
+	subs  map[uint64]captureSub
+	subID uint64
+}
+
+// NewCapture size 为每个设备保留的报文数
+func NewCapture(size int) *Capture {
+	return &Capture{
+		size:  max(1, size),
+		rings: make(map[string]*captureRing),
+		subs:  make(map[uint64]captureSub),
+	}
+}
+
+// Messages 设备最近的信令，callID 不为空时仅返回该会话的信令
+func (c *Capture) Messages(deviceID, callID string) []CaptureMessage {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	r, ok := c.rings[deviceID]
+	if !ok {
+		return []CaptureMessage{}
+	}
+	items := r.list()
+	if callID == "" {
+		return items
+	}
+	out := make([]CaptureMessage, 0, len(items))
+	for _, v := range items {
+		if v.CallID == callID {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Subscribe 订阅设备的实时信令，fn 在抓包路径上同步执行，不可阻塞
+func (c *Capture) Subscribe(deviceID string, fn func(CaptureMessage)) func() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subID++
+	id := c.subID
+	c.subs[id] = captureSub{deviceID: deviceID, fn: fn}
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.subs, id)
+	}
+}
+
+// record 记录收发的报文，未开启抓包时 c 为 nil
+func (c *Capture) record(direction string, msg Message, data []byte, conn Connection, remote net.Addr) {
+	if c == nil || conn == nil {
+		return
+	}
+	deviceID := captureDeviceID(direction, msg)
+	if deviceID == "" {
+		return
+	}
+
+	local := captureLocalAddr(conn.LocalAddr(), remote)
+	out := CaptureMessage{
+		Time:      time.Now(),
+		Direction: direction,
+		DeviceID:  deviceID,
+		Network:   conn.Network(),
+		Src:       addrString(remote),
+		Dst:       local,
+		Data:      string(data),
+	}
+	if direction == directionOut {
+		out.Src, out.Dst = out.Dst, out.Src
+	}
+	if callID, ok := msg.CallID(); ok {
+		out.CallID = string(*callID)
+	}
+	switch v := msg.(type) {
+	case *Request:
+		out.Method = v.Method()
+	case *Response:
+		out.Status = v.StatusCode()
+		if cseq, ok := v.CSeq(); ok {
+			out.Method = cseq.MethodName
+		}
+	}
+
+	c.mu.Lock()
+	c.seq++
+	out.ID = c.seq
+	r, ok := c.rings[deviceID]
+	if !ok {
+		if len(c.rings) >= captureMaxDevices {
+			c.evict()
+		}
+		r = &captureRing{}
+		c.rings[deviceID] = r
+	}
+	r.add(out, c.size)
+	subs := make([]func(CaptureMessage), 0, len(c.subs))
+	for _, sub := range c.subs {
+		if sub.deviceID == deviceID {
+			subs = append(subs, sub.fn)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, fn := range subs {
+		fn(out)
+	}
+}
+
+// evict 淘汰最久未更新的设备
+func (c *Capture) evict() {
+	var (
+		key    string
+		oldest time.Time
+	)
+	for k, r := range c.rings {
+		if key == "" || r.updatedAt.Before(oldest) {
+			key, oldest = k, r.updatedAt
+		}
+	}
+	delete(c.rings, key)
+}
+
+// captureDeviceID 报文所属设备，设备发起的请求取 From，平台发起的请求取 To
+func captureDeviceID(direction string, msg Message) string {
+	_, isReq := msg.(*Request)
+	if (direction == directionIn) == isReq {
+		if from, ok := msg.From(); ok {
+			return uriUser(from.Address)
+		}
+		return ""
+	}
+	if to, ok := msg.To(); ok {
+		return uriUser(to.Address)
+	}
+	return ""
+}
+
+func uriUser(u *URI) string {
+	if u == nil || u.FUser == nil {
+		return ""
+	}
+	return u.FUser.String()
+}
+
+// captureLocalAddr 监听在未指定地址时，按对端地址族补全本端地址
+func captureLocalAddr(local, remote net.Addr) string {
+	ip, port := addrIPPort(local)
+	if ip == nil || ip.IsUnspecified() {
+		ip = net.IPv6unspecified
+		if rip, _ := addrIPPort(remote); rip != nil && rip.To4() != nil {
+			ip = net.IPv4zero
+		}
+	}
+	return net.JoinHostPort(ip.String(), port)
+}
+
+func addrIPPort(addr net.Addr) (net.IP, string) {
+	switch v := addr.(type) {
+	case *net.UDPAddr:
+		return v.IP, strconv.Itoa(v.Port)
+	case *net.TCPAddr:
+		return v.IP, strconv.Itoa(v.Port)
+	case nil:
+		return nil, "0"
+	}
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return nil, "0"
+	}
+	return net.ParseIP(host), port
+}
+
+func addrString(addr net.Addr) string {
+	ip, port := addrIPPort(addr)
+	if ip == nil {
+		ip = net.IPv4zero
+	}
+	return net.JoinHostPort(ip.String(), port)
+}
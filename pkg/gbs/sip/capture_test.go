@@ -0,0 +1,86 @@
+package sip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestCaptureRing(t *testing.T) {
+	conn, peer := newTestUDP(t)
+	c := NewCapture(2)
+
+	var live []CaptureMessage
+	cancel := c.Subscribe("34020000001320000001", func(msg CaptureMessage) {
+		live = append(live, msg)
+	})
+	for _, method := range []string{MethodRegister, MethodMessage, MethodBYE} {
+		req := newTestRequest(t, method, peer.LocalAddr())
+		c.record(directionIn, req, req.Raw(), conn, peer.LocalAddr())
+	}
+	cancel()
+	req := newTestRequest(t, MethodInvite, peer.LocalAddr())
+	c.record(directionOut, req, req.Raw(), conn, peer.LocalAddr())
+
+	if len(live) != 3 {
+		t.Fatalf("expect 3 live messages, got %d", len(live))
+	}
+	items := c.Messages("34020000001320000001", "")
+	if len(items) != 2 || items[0].Method != MethodBYE || items[1].Method != MethodInvite {
+		t.Fatalf("unexpected ring %+v", items)
+	}
+	in, out := items[0], items[1]
+	if in.Src != peer.LocalAddr().String() || in.Dst != conn.LocalAddr().String() || in.Network != "udp" {
+		t.Fatalf("unexpected inbound addr %s -> %s", in.Src, in.Dst)
+	}
+	if out.Src != in.Dst || out.Dst != in.Src {
+		t.Fatalf("unexpected outbound addr %s -> %s", out.Src, out.Dst)
+	}
+	if len(c.Messages("unknown", "")) != 0 {
+		t.Fatal("expect empty")
+	}
+}
+
+func TestWritePcap(t *testing.T) {
+	msgs := []CaptureMessage{
+		{Time: time.Now(), Network: "udp", Src: "192.168.1.2:5060", Dst: "192.168.1.1:15060", Data: "REGISTER sip:a SIP/2.0\r\n\r\n"},
+		{Time: time.Now(), Network: "tcp", Src: "192.168.1.1:15060", Dst: "[::1]:5060", Data: "SIP/2.0 200 OK\r\n\r\n"},
+	}
+	var buf bytes.Buffer
+	if err := WritePcap(&buf, msgs); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if binary.LittleEndian.Uint32(b) != pcapMagic || binary.LittleEndian.Uint32(b[20:]) != linkTypeRaw {
+		t.Fatal("bad pcap header")
+	}
+	b = b[24:]
+
+	// ipv4 udp
+	n := binary.LittleEndian.Uint32(b[8:])
+	packet := b[16 : 16+n]
+	if fold(checksum(0, packet[:20])) != 0xffff {
+		t.Fatal("bad ipv4 checksum")
+	}
+	src, dst := netip.MustParseAddr("192.168.1.2"), netip.MustParseAddr("192.168.1.1")
+	if packet[9] != protocolUDP || fold(checksum(uint32(protocolUDP)+uint32(len(packet)-20), append(append(src.AsSlice(), dst.AsSlice()...), packet[20:]...))) != 0xffff {
+		t.Fatal("bad udp checksum")
+	}
+	if string(packet[28:]) != msgs[0].Data {
+		t.Fatal("bad udp payload")
+	}
+	b = b[16+n:]
+
+	// ipv4 与 ipv6 混用时转为 ipv6
+	n = binary.LittleEndian.Uint32(b[8:])
+	packet = b[16 : 16+n]
+	if packet[0]>>4 != 6 || packet[6] != protocolTCP {
+		t.Fatal("expect ipv6 tcp")
+	}
+	if net.IP(packet[8:24]).String() != "192.168.1.1" || string(packet[60:]) != msgs[1].Data {
+		t.Fatal("bad tcp packet")
+	}
+}
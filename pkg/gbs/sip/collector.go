@@ -2,25 +2,29 @@ package sip
 
 import (
 	"log/slog"
-	"slices"
+	"sync"
 	"time"
 )
 
 // Collector .
 // 1. 收集器
-// 2. 分门别类
-// 3. 定时同步，超时删除，删除之前再同步一次
-// 4. 不会去重
+// 2. 分门别类，按 keyFn 去重
+// 3. 每累计 batch 条数据交给 flush 保存，内存中仅保留已接收数据的去重键
+// 4. 接收完整或 10 秒无新数据时调用 done 结束
 // 如何使用?
-// 1. 通过 NewCatalogRecv 创建一个新的收集器
-// 2. s.createCh <- deviceID
-// 3. s.catalog.msg <- &CollectorMsg[Channel]{Data: &c, Total: msg.SumNum, Key: msg.DeviceID}
+// 1. 通过 NewCollector 创建一个新的收集器
+// 2. c.Run(deviceID)
+// 3. c.Write(&CollectorMsg[Channel]{Data: &c, Total: msg.SumNum, Key: msg.DeviceID})
 type Collector[T any] struct {
-	data       map[string]*Content[T]
-	msg        chan *CollectorMsg[T]
-	createCh   chan string
-	noRepeatFn NoRepeatFn[T]
-	observer   *Observer
+	data     map[string]*Content[T]
+	msg      chan *CollectorMsg[T]
+	createCh chan string
+	keyFn    func(*T) string
+	batch    int
+	observer *Observer
+
+	mu       sync.RWMutex
+	progress map[string]CollectorProgress
 }
 
 func (c *Collector[T]) Run(key string) {
@@ -40,35 +44,87 @@ type CollectorMsg[T any] struct {
 	Total int
 }
 
-type NoRepeatFn[T any] func(*T, *T) bool
+// CollectorProgress 接收进度
+type CollectorProgress struct {
+	Received  int       `json:"received"`   // 已接收的数量
+	Total     int       `json:"total"`      // 设备上报的总数，未收到数据时为 -1
+	Done      bool      `json:"done"`       // 是否已结束接收
+	StartedAt time.Time `json:"started_at"` // 开始时间
+	UpdatedAt time.Time `json:"updated_at"` // 最后接收数据的时间
+}
 
-// newCollector 创建一个新的收集器
-// noRepeatFn 用于提前去重，避免重复数据存储
-func NewCollector[T any](noRepeatFn NoRepeatFn[T]) *Collector[T] {
+// NewCollector 创建一个新的收集器
+// batch 为每次交给 flush 保存的数量，keyFn 返回数据的去重键
+func NewCollector[T any](batch int, keyFn func(*T) string) *Collector[T] {
+	if batch <= 0 {
+		batch = 200
+	}
 	return &Collector[T]{
-		data:       make(map[string]*Content[T]),
-		msg:        make(chan *CollectorMsg[T], 10),
-		createCh:   make(chan string, 10),
-		noRepeatFn: noRepeatFn,
-		observer:   NewObserver(),
+		data:     make(map[string]*Content[T]),
+		msg:      make(chan *CollectorMsg[T], 100),
+		createCh: make(chan string, 10),
+		keyFn:    keyFn,
+		batch:    batch,
+		observer: NewObserver(),
+		progress: make(map[string]CollectorProgress),
 	}
 }
 
 type Content[T any] struct {
+	startedAt    time.Time
 	lastUpdateAt time.Time
-	data         []*T
+	pending      []*T
+	seen         map[string]struct{}
 	total        int
 }
 
+func (c *Content[T]) full() bool {
+	return c.total > 0 && len(c.seen) >= c.total
+}
+
 // Wait 在执行 Start 以后，可以调用 Wait 等待
 func (c *Collector[T]) Wait(key string) {
 	c.observer.DefaultRegister(key)
 }
 
+// WaitTimeout 自定义等待时间
+func (c *Collector[T]) WaitTimeout(key string, d time.Duration) {
+	c.observer.RegisterWithTimeout(key, d)
+}
+
+// Progress 最近一次接收的进度
+func (c *Collector[T]) Progress(key string) (CollectorProgress, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	v, ok := c.progress[key]
+	return v, ok
+}
+
+func (c *Collector[T]) setProgress(key string, v *Content[T], done bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.progress[key] = CollectorProgress{
+		Received:  len(v.seen),
+		Total:     v.total,
+		Done:      done,
+		StartedAt: v.startedAt,
+		UpdatedAt: v.lastUpdateAt,
+	}
+}
+
 // Start 启动定时任务检查和保存数据
-func (c *Collector[T]) Start(save func(string, []*T)) {
-	fn := func(k string, data []*T) {
-		save(k, data)
+// flush 分批保存数据，done 在接收结束时调用，接收数量达到设备上报的总数时 full 为 true，超时未接收完整时为 false
+// 未接收到任何数据的任务不调用 done
+func (c *Collector[T]) Start(flush func(key string, data []*T), done func(key string, full bool)) {
+	finish := func(k string, v *Content[T]) {
+		if len(v.pending) > 0 {
+			flush(k, v.pending)
+		}
+		if len(v.seen) > 0 {
+			done(k, v.full())
+		}
+		delete(c.data, k)
+		c.setProgress(k, v, true)
 		c.observer.Notify(k)
 	}
 
@@ -79,35 +135,44 @@ func (c *Collector[T]) Start(save func(string, []*T)) {
 		case <-check.C:
 			for k, v := range c.data {
 				if time.Since(v.lastUpdateAt) > 10*time.Second {
-					fn(k, v.data)
-					delete(c.data, k)
-					continue
-				}
-				if v.total > 0 && len(v.data) >= v.total {
-					fn(k, v.data)
-					delete(c.data, k)
-					continue
+					finish(k, v)
 				}
 			}
-		case v := <-c.createCh:
-			c.data[v] = &Content[T]{lastUpdateAt: time.Now(), data: make([]*T, 0, 2), total: -1}
+		case key := <-c.createCh:
+			now := time.Now()
+			v := Content[T]{
+				startedAt:    now,
+				lastUpdateAt: now,
+				pending:      make([]*T, 0, 2),
+				seen:         make(map[string]struct{}, 8),
+				total:        -1,
+			}
+			c.data[key] = &v
+			c.setProgress(key, &v, false)
 		case msg := <-c.msg:
 			data, exist := c.data[msg.Key]
 			if !exist {
 				slog.Debug("key 不存在或已过期", "key", msg.Key, "data", msg.Data)
 				continue
 			}
-			// 如果数据已存在且无重复，跳过该消息
-			if slices.ContainsFunc(data.data, func(v *T) bool {
-				return c.noRepeatFn(v, msg.Data)
-			}) {
+			data.lastUpdateAt = time.Now()
+			data.total = msg.Total
+			id := c.keyFn(msg.Data)
+			if _, ok := data.seen[id]; ok {
 				slog.Debug("catalog 发现重复数据", "key", msg.Key, "data", msg.Data)
 				continue
 			}
-			// 添加数据到对应的条目并更新最后更新时间和总量
-			data.data = append(data.data, msg.Data)
-			data.lastUpdateAt = time.Now()
-			data.total = msg.Total
+			data.seen[id] = struct{}{}
+			data.pending = append(data.pending, msg.Data)
+			if data.full() {
+				finish(msg.Key, data)
+				continue
+			}
+			if len(data.pending) >= c.batch {
+				flush(msg.Key, data.pending)
+				data.pending = make([]*T, 0, c.batch)
+			}
+			c.setProgress(msg.Key, data, false)
 		}
 	}
 }
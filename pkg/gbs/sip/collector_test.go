@@ -0,0 +1,45 @@
+package sip
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectorBatch(t *testing.T) {
+	type item struct{ ID string }
+	c := NewCollector(2, func(v *item) string { return v.ID })
+
+	batches := make(chan int, 10)
+	done := make(chan bool, 1)
+	go c.Start(func(_ string, data []*item) {
+		batches <- len(data)
+	}, func(_ string, full bool) {
+		done <- full
+	})
+
+	c.Run("dev")
+	time.Sleep(50 * time.Millisecond)
+	for _, id := range []string{"1", "2", "2", "3"} {
+		c.Write(&CollectorMsg[item]{Key: "dev", Data: &item{ID: id}, Total: 4})
+	}
+	time.Sleep(50 * time.Millisecond)
+	if p, ok := c.Progress("dev"); !ok || p.Received != 3 || p.Total != 4 || p.Done {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+	c.Write(&CollectorMsg[item]{Key: "dev", Data: &item{ID: "4"}, Total: 4})
+
+	select {
+	case full := <-done:
+		if !full {
+			t.Fatal("expect full catalog")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("collector not finished")
+	}
+	if a, b := <-batches, <-batches; a != 2 || b != 2 {
+		t.Fatalf("unexpected batches %d %d", a, b)
+	}
+	if p, _ := c.Progress("dev"); !p.Done || p.Received != 4 {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+}
@@ -16,6 +16,7 @@ type Packet struct {
 	raddr      net.Addr      // 远程地址
 	bodylength int           // 消息体长度
 	conn       Connection    // 网络连接实例
+	data       []byte        // 原始报文
 }
 
 func newPacket(data []byte, raddr net.Addr, conn Connection) Packet {
@@ -25,6 +26,7 @@ func newPacket(data []byte, raddr net.Addr, conn Connection) Packet {
 		raddr:      raddr,
 		bodylength: getBodyLength(data),
 		conn:       conn,
+		data:       data,
 	}
 }
 
@@ -76,6 +78,7 @@ type connection struct {
 	laddr    net.Addr // 本地地址
 	raddr    net.Addr // 远程地址
 	logKey   string   // 日志标识符
+	network  string   // 网络类型，为空时使用本地地址的网络类型
 }
 
 func NewUDPConnection(baseConn net.Conn) Connection {
@@ -98,6 +101,18 @@ func NewTCPConnection(baseConn net.Conn) Connection {
 	return conn
 }
 
+// NewTLSConnection tls 连接，与 tcp 使用相同的分帧方式
+func NewTLSConnection(baseConn net.Conn) Connection {
+	conn := &connection{
+		baseConn: baseConn,
+		laddr:    baseConn.LocalAddr(),
+		raddr:    baseConn.RemoteAddr(),
+		logKey:   "tls ",
+		network:  "tls",
+	}
+	return conn
+}
+
 // Read 从连接读取数据
 func (conn *connection) Read(buf []byte) (int, error) {
 	var (
@@ -138,10 +153,10 @@ func (conn *connection) Write(buf []byte) (int, error) {
 
 // WriteTo 向指定地址写入数据
 func (conn *connection) WriteTo(buf []byte, raddr net.Addr) (num int, err error) {
-	if conn.Network() == "tcp" {
-		num, err = conn.baseConn.Write(buf)
-	} else {
+	if conn.Network() == "udp" {
 		num, err = conn.baseConn.(net.PacketConn).WriteTo(buf, raddr)
+	} else {
+		num, err = conn.baseConn.Write(buf)
 	}
 	if err != nil {
 		return num, NewError(err, conn.logKey, "writeTo", conn.baseConn.LocalAddr().String(), raddr.String())
@@ -171,6 +186,9 @@ func (conn *connection) Close() error {
 
 // Network 获取网络类型
 func (conn *connection) Network() string {
+	if conn.network != "" {
+		return conn.network
+	}
 	return conn.baseConn.LocalAddr().Network()
 }
 
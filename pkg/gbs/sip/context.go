@@ -16,6 +16,7 @@ type HandlerFunc func(*Context)
 type Context struct {
 	Request  *Request      // SIP请求对象
 	Tx       *Transaction  // 事务对象
+	Dialog   *Dialog       // 请求所属的对话，对话外请求为 nil
 	handlers []HandlerFunc // 处理函数列表
 	index    int8          // 当前执行的处理函数索引
 
@@ -104,6 +105,17 @@ func (c *Context) GetHeader(key string) string {
 	return ""
 }
 
+// Transport 请求所在连接的传输协议 udp/tcp/tls
+func (c *Context) Transport() string {
+	if c.Request.conn != nil {
+		return c.Request.conn.Network()
+	}
+	if c.Source != nil {
+		return c.Source.Network()
+	}
+	return ""
+}
+
 // Abort 中止处理流程
 func (c *Context) Abort() {
 	c.index = abortIndex
@@ -0,0 +1,308 @@
+package sip
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Dialog 对话 (RFC 3261 12)，由 INVITE/SUBSCRIBE 的 2xx 响应建立
+// 用于发送 ACK/BYE/INFO/re-INVITE/SUBSCRIBE 刷新等对话内请求
+type Dialog struct {
+	id        string
+	callID    CallID
+	localTag  string
+	remoteTag string
+	local     *Address // From，携带本端 tag
+	remote    *Address // To，携带对端 tag
+	routeSet  []*URI   // Record-Route 反序
+	via       *ViaHop  // 本端 Via 模板
+
+	mu           sync.Mutex
+	remoteTarget *URI   // 对端 Contact，随目标刷新请求更新
+	localSeq     uint32 // 本端 CSeq
+	remoteSeq    uint32 // 对端 CSeq，0 表示尚未收到对端请求
+	dest         net.Addr
+	conn         Connection
+}
+
+// NewDialog 根据 UAC 收到的 2xx 响应创建对话
+func NewDialog(res *Response) (*Dialog, error) {
+	callID, ok := res.CallID()
+	if !ok {
+		return nil, fmt.Errorf("missing required 'Call-ID' header")
+	}
+	from, ok := res.From()
+	if !ok {
+		return nil, fmt.Errorf("missing required 'From' header")
+	}
+	to, ok := res.To()
+	if !ok {
+		return nil, fmt.Errorf("missing required 'To' header")
+	}
+	cseq, ok := res.CSeq()
+	if !ok {
+		return nil, fmt.Errorf("missing required 'CSeq' header")
+	}
+
+	d := Dialog{
+		callID:   *callID,
+		local:    NewAddressFromFromHeader(from),
+		remote:   &Address{DisplayName: to.DisplayName, URI: to.Address.Clone(), Params: cloneWithNil(to.Params)},
+		localSeq: cseq.SeqNo,
+		dest:     res.Source(),
+		conn:     res.conn,
+	}
+	d.localTag = paramTag(d.local.Params)
+	d.remoteTag = paramTag(d.remote.Params)
+	d.id = dialogID(string(d.callID), d.localTag, d.remoteTag)
+
+	// 设备未携带 Contact 时使用 To 地址
+	d.remoteTarget = d.remote.URI.Clone()
+	if contact, ok := res.Contact(); ok && contact.Address != nil {
+		d.remoteTarget = contact.Address.Clone()
+	}
+	for _, h := range res.GetHeaders("Record-Route") {
+		for _, u := range h.(*RecordRouteHeader).Addresses {
+			d.routeSet = append(d.routeSet, u.Clone())
+		}
+	}
+	slices.Reverse(d.routeSet)
+	if via, ok := res.ViaHop(); ok {
+		d.via = via.Clone()
+	}
+	return &d, nil
+}
+
+// DialogState 对话状态，持久化后用于程序重启时恢复对话
+type DialogState struct {
+	CallID       string   `json:"call_id"`
+	Local        string   `json:"local"`         // 本端地址，携带本端 tag
+	Remote       string   `json:"remote"`        // 对端地址，携带对端 tag
+	RemoteTarget string   `json:"remote_target"` // 对端 Contact
+	RouteSet     []string `json:"route_set,omitempty"`
+	ViaTransport string   `json:"via_transport,omitempty"`
+	ViaHost      string   `json:"via_host,omitempty"`
+	ViaPort      int      `json:"via_port,omitempty"`
+	LocalSeq     uint32   `json:"local_seq"`
+	RemoteSeq    uint32   `json:"remote_seq"`
+}
+
+// State 对话状态快照
+func (d *Dialog) State() DialogState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	s := DialogState{
+		CallID:       string(d.callID),
+		Local:        addressValue(d.local),
+		Remote:       addressValue(d.remote),
+		RemoteTarget: d.remoteTarget.String(),
+		LocalSeq:     d.localSeq,
+		RemoteSeq:    d.remoteSeq,
+	}
+	for _, u := range d.routeSet {
+		s.RouteSet = append(s.RouteSet, u.String())
+	}
+	if d.via != nil {
+		s.ViaTransport, s.ViaHost = d.via.Transport, d.via.Host
+		if d.via.Port != nil {
+			s.ViaPort = int(*d.via.Port)
+		}
+	}
+	return s
+}
+
+// RestoreDialog 根据持久化的状态恢复对话，发送请求前需通过 SetTarget 设置目标地址与连接
+func RestoreDialog(s DialogState) (*Dialog, error) {
+	if s.CallID == "" {
+		return nil, fmt.Errorf("missing call-id")
+	}
+	local, err := parseAddress(s.Local)
+	if err != nil {
+		return nil, fmt.Errorf("local address: %w", err)
+	}
+	remote, err := parseAddress(s.Remote)
+	if err != nil {
+		return nil, fmt.Errorf("remote address: %w", err)
+	}
+	target, err := ParseURI(s.RemoteTarget)
+	if err != nil {
+		return nil, fmt.Errorf("remote target: %w", err)
+	}
+
+	d := Dialog{
+		callID:       CallID(s.CallID),
+		local:        local,
+		remote:       remote,
+		remoteTarget: target,
+		localSeq:     s.LocalSeq,
+		remoteSeq:    s.RemoteSeq,
+	}
+	d.localTag = paramTag(d.local.Params)
+	d.remoteTag = paramTag(d.remote.Params)
+	d.id = dialogID(s.CallID, d.localTag, d.remoteTag)
+	for _, v := range s.RouteSet {
+		u, err := ParseURI(v)
+		if err != nil {
+			return nil, fmt.Errorf("route set: %w", err)
+		}
+		d.routeSet = append(d.routeSet, u)
+	}
+	if s.ViaHost != "" {
+		d.via = &ViaHop{ProtocolName: "SIP", ProtocolVersion: "2.0", Transport: s.ViaTransport, Host: s.ViaHost, Params: NewParams()}
+		if s.ViaPort > 0 {
+			d.via.Port = NewPort(s.ViaPort)
+		}
+	}
+	return &d, nil
+}
+
+// addressValue 地址的头域值形式，如 "name" <sip:id@host>;tag=xx
+func addressValue(a *Address) string {
+	params := a.Params
+	if params == nil {
+		params = NewParams()
+	}
+	h := FromHeader{DisplayName: a.DisplayName, Address: a.URI, Params: params}
+	return strings.TrimPrefix(h.String(), "From: ")
+}
+
+func parseAddress(v string) (*Address, error) {
+	name, uri, params, err := ParseAddressValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return &Address{DisplayName: name, URI: uri, Params: params}, nil
+}
+
+// ID 对话标识 Call-ID + 本端 tag + 对端 tag
+func (d *Dialog) ID() string {
+	return d.id
+}
+
+// CallID CallID
+func (d *Dialog) CallID() string {
+	return string(d.callID)
+}
+
+// SetTarget 更新对话请求的目标地址与连接，用于设备重新建立 tcp 连接的场景
+func (d *Dialog) SetTarget(dest net.Addr, conn Connection) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dest = dest
+	d.conn = conn
+}
+
+// NewRequest 创建对话内请求，ACK 使用 INVITE 的序号，其它方法序号递增
+func (d *Dialog) NewRequest(method string, contentType *ContentType, body []byte) *Request {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if method != MethodACK {
+		d.localSeq++
+	}
+
+	via := ViaHop{Params: NewParams()}
+	if d.via != nil {
+		via = ViaHop{ProtocolName: d.via.ProtocolName, ProtocolVersion: d.via.ProtocolVersion, Transport: d.via.Transport, Host: d.via.Host, Port: d.via.Port, Params: NewParams()}
+	}
+	via.Params.Add("branch", String{Str: GenerateBranch()})
+
+	callID := d.callID
+	hb := NewHeaderBuilder().SetCallID(&callID).SetMethod(method).SetSeqNo(uint(d.localSeq)).
+		SetFrom(d.local).SetToWithParam(d.remote).AddVia(&via).SetContentType(contentType)
+	if isTargetRefresh(method) {
+		hb.SetContact(&Address{DisplayName: d.local.DisplayName, URI: d.local.URI, Params: NewParams()})
+	}
+	hdrs := hb.Build()
+	if len(d.routeSet) > 0 {
+		uris := make([]*URI, 0, len(d.routeSet))
+		for _, u := range d.routeSet {
+			uris = append(uris, u.Clone())
+		}
+		hdrs = append(hdrs, &RouteHeader{Addresses: uris})
+	}
+
+	req := NewRequest("", method, d.remoteTarget.Clone(), DefaultSipVersion, hdrs, body)
+	req.SetDestination(d.dest)
+	req.SetConnection(d.conn)
+	return req
+}
+
+// Update 目标刷新请求的 2xx 响应更新对端 Contact
+func (d *Dialog) Update(res *Response) {
+	if !res.IsSuccess() {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if contact, ok := res.Contact(); ok && contact.Address != nil {
+		d.remoteTarget = contact.Address.Clone()
+	}
+}
+
+// receiveRequest 校验对端请求序号，目标刷新请求更新对端 Contact (RFC 3261 12.2.2)
+func (d *Dialog) receiveRequest(req *Request) error {
+	cseq, ok := req.CSeq()
+	if !ok {
+		return fmt.Errorf("missing required 'CSeq' header")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.remoteSeq != 0 && cseq.SeqNo <= d.remoteSeq {
+		return fmt.Errorf("cseq out of order, got %d, last %d", cseq.SeqNo, d.remoteSeq)
+	}
+	d.remoteSeq = cseq.SeqNo
+	if isTargetRefresh(req.Method()) {
+		if contact, ok := req.Contact(); ok && contact.Address != nil {
+			d.remoteTarget = contact.Address.Clone()
+		}
+	}
+	return nil
+}
+
+func isTargetRefresh(method string) bool {
+	return method == MethodInvite || method == MethodSubscribe
+}
+
+func paramTag(params Params) string {
+	if params == nil {
+		return ""
+	}
+	if v, ok := params.Get("tag"); ok && v != nil {
+		return v.String()
+	}
+	return ""
+}
+
+func dialogID(callID, localTag, remoteTag string) string {
+	return callID + "|" + localTag + "|" + remoteTag
+}
+
+// AddDialog 登记对话，用于匹配对端发起的对话内请求
+func (s *Server) AddDialog(d *Dialog) {
+	s.dialogs.Store(d.ID(), d)
+}
+
+// RemoveDialog 删除对话
+func (s *Server) RemoveDialog(d *Dialog) {
+	s.dialogs.Delete(d.ID())
+}
+
+// matchDialog 匹配对端发起的对话内请求，本端 tag 为 To tag，对端 tag 为 From tag
+func (s *Server) matchDialog(req *Request) (*Dialog, bool) {
+	callID, ok := req.CallID()
+	if !ok {
+		return nil, false
+	}
+	to, ok := req.To()
+	if !ok {
+		return nil, false
+	}
+	from, ok := req.From()
+	if !ok {
+		return nil, false
+	}
+	return s.dialogs.Load(dialogID(string(*callID), paramTag(to.Params), paramTag(from.Params)))
+}
@@ -0,0 +1,126 @@
+package sip
+
+import (
+	"net"
+	"testing"
+)
+
+func newTestDialog(t *testing.T) (*Request, *Response, *Dialog) {
+	t.Helper()
+	dest := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5060}
+	req := newTestRequest(t, MethodInvite, dest)
+	res := NewResponseFromRequest("", req, 200, "OK", nil)
+	to, _ := res.To()
+	to.Params = NewParams().Add("tag", String{Str: "remote"})
+	contact, _ := ParseSipURI("sip:34020000001320000001@192.168.1.2:5061")
+	res.AppendHeader(&ContactHeader{Address: &contact, Params: NewParams()})
+	r1, _ := ParseSipURI("sip:p1.example.com;lr")
+	r2, _ := ParseSipURI("sip:p2.example.com;lr")
+	res.AppendHeader(&RecordRouteHeader{Addresses: []*URI{&r1, &r2}})
+	res.SetSource(dest)
+
+	d, err := NewDialog(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req, res, d
+}
+
+func TestDialogNewRequest(t *testing.T) {
+	req, _, d := newTestDialog(t)
+	inviteSeq, _ := req.CSeq()
+
+	ack := d.NewRequest(MethodACK, nil, nil)
+	if cseq, _ := ack.CSeq(); cseq.SeqNo != inviteSeq.SeqNo || cseq.MethodName != MethodACK {
+		t.Fatalf("expect ACK with invite seq, got %s", cseq)
+	}
+
+	bye := d.NewRequest(MethodBYE, nil, nil)
+	if cseq, _ := bye.CSeq(); cseq.SeqNo != inviteSeq.SeqNo+1 {
+		t.Fatalf("expect BYE seq increase, got %s", cseq)
+	}
+	if callID, _ := bye.CallID(); string(*callID) != d.CallID() {
+		t.Fatal("call-id mismatch")
+	}
+	if to, _ := bye.To(); paramTag(to.Params) != "remote" {
+		t.Fatal("expect remote tag")
+	}
+	from, _ := bye.From()
+	reqFrom, _ := req.From()
+	if paramTag(from.Params) != paramTag(reqFrom.Params) {
+		t.Fatal("expect local tag")
+	}
+	if bye.Recipient().Host() != "192.168.1.2" {
+		t.Fatalf("expect remote target, got %s", bye.Recipient())
+	}
+	routes := bye.GetHeaders("Route")
+	if len(routes) != 1 {
+		t.Fatal("expect route header")
+	}
+	if addrs := routes[0].(*RouteHeader).Addresses; addrs[0].Host() != "p2.example.com" {
+		t.Fatalf("expect reversed route set, got %s", routes[0])
+	}
+}
+
+func TestServerMatchDialog(t *testing.T) {
+	_, _, d := newTestDialog(t)
+	s := NewServer(&Address{})
+	s.AddDialog(d)
+
+	// 对端发起的请求 From/To 与本端相反
+	bye := d.NewRequest(MethodBYE, nil, nil)
+	from, _ := bye.From()
+	to, _ := bye.To()
+	from.Params, to.Params = to.Params, from.Params
+	got, ok := s.matchDialog(bye)
+	if !ok || got != d {
+		t.Fatal("expect matched dialog")
+	}
+	if err := got.receiveRequest(bye); err != nil {
+		t.Fatal(err)
+	}
+	if err := got.receiveRequest(bye); err == nil {
+		t.Fatal("expect cseq out of order")
+	}
+
+	s.RemoveDialog(d)
+	if _, ok := s.matchDialog(bye); ok {
+		t.Fatal("expect dialog removed")
+	}
+}
+
+func TestRestoreDialog(t *testing.T) {
+	_, _, d := newTestDialog(t)
+	_ = d.NewRequest(MethodBYE, nil, nil)
+
+	got, err := RestoreDialog(d.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ID() != d.ID() {
+		t.Fatalf("expect id %s, got %s", d.ID(), got.ID())
+	}
+
+	want := d.NewRequest(MethodBYE, nil, nil)
+	bye := got.NewRequest(MethodBYE, nil, nil)
+	if bye.Recipient().String() != want.Recipient().String() {
+		t.Fatalf("expect recipient %s, got %s", want.Recipient(), bye.Recipient())
+	}
+	if cseq, _ := bye.CSeq(); cseq.SeqNo != mustCSeq(t, want) {
+		t.Fatalf("expect seq %d, got %d", mustCSeq(t, want), cseq.SeqNo)
+	}
+	for _, name := range []string{"From", "To", "Route"} {
+		if a, b := bye.GetHeaders(name)[0].String(), want.GetHeaders(name)[0].String(); a != b {
+			t.Fatalf("%s mismatch, expect %s, got %s", name, b, a)
+		}
+	}
+}
+
+func mustCSeq(t *testing.T, req *Request) uint32 {
+	t.Helper()
+	cseq, ok := req.CSeq()
+	if !ok {
+		t.Fatal("missing cseq")
+	}
+	return cseq.SeqNo
+}
@@ -0,0 +1,318 @@
+package sip
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 拦截原因
+const (
+	BlockRateLimit = "rate_limit"
+	BlockBanned    = "banned"
+)
+
+// guardIdle 空闲超过该时间的限速与失败记录会被清理
+const guardIdle = 10 * time.Minute
+
+// GuardConfig 信令防护参数
+type GuardConfig struct {
+	Rate        float64       // 单个来源 IP 每秒允许的请求数，小于等于 0 不限速
+	Burst       int           // 突发请求数
+	MaxFailures int           // 窗口内鉴权失败达到该次数后封禁，小于等于 0 不封禁
+	FailWindow  time.Duration // 鉴权失败统计窗口
+	BanDuration time.Duration // 封禁时长
+}
+
+// Ban 封禁记录
+type Ban struct {
+	IP        string    `json:"ip"`
+	Reason    string    `json:"reason"`
+	Failures  int       `json:"failures"`
+	BannedAt  time.Time `json:"banned_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GuardStore 集群部署时多个实例共享的鉴权失败与封禁记录
+type GuardStore interface {
+	Fail(ip string, window time.Duration) (int, error) // 记录鉴权失败，返回窗口内的失败次数
+	Success(ip string) error
+	Ban(ban Ban) error
+	Unban(ip string) (int, error) // ip 为空时解除全部
+	Bans() ([]Ban, error)
+}
+
+type bucket struct {
+	tokens  float64
+	last    time.Time
+	limited bool // 处于限速中，仅在进入限速时记录日志
+}
+
+type failure struct {
+	count int
+	first time.Time
+}
+
+// Guard 按来源 IP 限速，鉴权失败过多时临时封禁
+type Guard struct {
+	cfg   GuardConfig
+	store GuardStore // 为空时仅记录在本实例
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	failures  map[string]*failure
+	bans      map[string]*Ban
+	cleanedAt time.Time
+}
+
+// NewGuard 创建信令防护，零值配置不做任何限制
+func NewGuard(cfg GuardConfig) *Guard {
+	if cfg.Burst <= 0 {
+		cfg.Burst = max(1, int(cfg.Rate))
+	}
+	return &Guard{
+		cfg:      cfg,
+		buckets:  make(map[string]*bucket),
+		failures: make(map[string]*failure),
+		bans:     make(map[string]*Ban),
+	}
+}
+
+// SetStore 设置共享存储，需在处理请求前调用，本实例的封禁记录通过 Sync 与共享存储同步
+func (g *Guard) SetStore(store GuardStore) {
+	g.store = store
+}
+
+// Sync 从共享存储加载全部实例的封禁记录
+func (g *Guard) Sync() error {
+	if g.store == nil {
+		return nil
+	}
+	bans, err := g.store.Bans()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	clear(g.bans)
+	for _, ban := range bans {
+		if now.Before(ban.ExpiresAt) {
+			g.bans[ban.IP] = &ban
+		}
+	}
+	return nil
+}
+
+// Allow 来源 IP 是否允许处理请求，不允许时返回拦截原因
+func (g *Guard) Allow(ip string) (string, bool) {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cleanup(now)
+
+	if g.banned(ip, now) {
+		return BlockBanned, false
+	}
+	if g.cfg.Rate <= 0 {
+		return "", true
+	}
+
+	b, ok := g.buckets[ip]
+	if !ok {
+		b = &bucket{tokens: float64(g.cfg.Burst), last: now}
+		g.buckets[ip] = b
+	}
+	b.tokens = min(float64(g.cfg.Burst), b.tokens+now.Sub(b.last).Seconds()*g.cfg.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		if !b.limited {
+			b.limited = true
+			slog.Warn("sip rate limited", "ip", ip)
+		}
+		return BlockRateLimit, false
+	}
+	b.tokens--
+	b.limited = false
+	return "", true
+}
+
+// RetryAfter 被限速的请求建议的重试间隔，单位秒，至少 1 秒
+func (g *Guard) RetryAfter() int {
+	if g.cfg.Rate <= 0 {
+		return 1
+	}
+	return max(1, int(math.Ceil(1/g.cfg.Rate)))
+}
+
+// Banned 来源 IP 是否被封禁
+func (g *Guard) Banned(ip string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.banned(ip, time.Now())
+}
+
+func (g *Guard) banned(ip string, now time.Time) bool {
+	ban, ok := g.bans[ip]
+	if !ok {
+		return false
+	}
+	if now.After(ban.ExpiresAt) {
+		delete(g.bans, ip)
+		return false
+	}
+	return true
+}
+
+// Fail 记录鉴权失败，达到次数后封禁，返回是否被封禁
+func (g *Guard) Fail(ip string, reason string) bool {
+	if g.cfg.MaxFailures <= 0 {
+		return false
+	}
+	// 共享存储不可用时按本实例的失败次数封禁
+	count := -1
+	if g.store != nil {
+		n, err := g.store.Fail(ip, g.cfg.FailWindow)
+		if err != nil {
+			slog.Error("记录共享鉴权失败次数失败", "err", err, "ip", ip)
+		} else {
+			count = n
+		}
+	}
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if count < 0 {
+		f, ok := g.failures[ip]
+		if !ok || now.Sub(f.first) > g.cfg.FailWindow {
+			f = &failure{first: now}
+			g.failures[ip] = f
+		}
+		f.count++
+		count = f.count
+	}
+	if count < g.cfg.MaxFailures {
+		return false
+	}
+
+	delete(g.failures, ip)
+	ban := Ban{
+		IP:        ip,
+		Reason:    reason,
+		Failures:  count,
+		BannedAt:  now,
+		ExpiresAt: now.Add(g.cfg.BanDuration),
+	}
+	g.bans[ip] = &ban
+	if g.store != nil {
+		if err := g.store.Ban(ban); err != nil {
+			slog.Error("保存共享封禁记录失败", "err", err, "ip", ip)
+		}
+	}
+	bansTotal.Inc()
+	slog.Warn("sip ip banned", "ip", ip, "reason", reason, "failures", count, "duration", g.cfg.BanDuration)
+	return true
+}
+
+// Success 鉴权成功，清除失败记录
+func (g *Guard) Success(ip string) {
+	if g.store != nil {
+		if err := g.store.Success(ip); err != nil {
+			slog.Error("清除共享鉴权失败次数失败", "err", err, "ip", ip)
+		}
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.failures, ip)
+}
+
+// Bans 当前生效的封禁记录
+func (g *Guard) Bans() []Ban {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make([]Ban, 0, len(g.bans))
+	for ip, ban := range g.bans {
+		if now.After(ban.ExpiresAt) {
+			delete(g.bans, ip)
+			continue
+		}
+		out = append(out, *ban)
+	}
+	slices.SortFunc(out, func(a, b Ban) int {
+		return b.BannedAt.Compare(a.BannedAt)
+	})
+	return out
+}
+
+// Unban 解除封禁，ip 为空时解除全部，返回解除的数量
+func (g *Guard) Unban(ip string) int {
+	var shared int
+	if g.store != nil {
+		n, err := g.store.Unban(ip)
+		if err != nil {
+			slog.Error("解除共享封禁记录失败", "err", err, "ip", ip)
+		}
+		shared = n
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if ip == "" {
+		n := len(g.bans)
+		clear(g.bans)
+		clear(g.failures)
+		return max(n, shared)
+	}
+	delete(g.failures, ip)
+	if _, ok := g.bans[ip]; !ok {
+		return shared
+	}
+	delete(g.bans, ip)
+	return 1
+}
+
+// cleanup 清理空闲的限速与失败记录
+func (g *Guard) cleanup(now time.Time) {
+	if now.Sub(g.cleanedAt) < guardIdle {
+		return
+	}
+	g.cleanedAt = now
+	for ip, b := range g.buckets {
+		if now.Sub(b.last) > guardIdle {
+			delete(g.buckets, ip)
+		}
+	}
+	for ip, f := range g.failures {
+		if now.Sub(f.first) > max(g.cfg.FailWindow, guardIdle) {
+			delete(g.failures, ip)
+		}
+	}
+	for ip, ban := range g.bans {
+		if now.After(ban.ExpiresAt) {
+			delete(g.bans, ip)
+		}
+	}
+}
+
+// SourceIP 取来源地址的 IP
+func SourceIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	switch v := addr.(type) {
+	case *net.UDPAddr:
+		return v.IP.String()
+	case *net.TCPAddr:
+		return v.IP.String()
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return strings.TrimSpace(addr.String())
+	}
+	return host
+}
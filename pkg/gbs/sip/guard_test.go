@@ -0,0 +1,129 @@
+package sip
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGuardRateLimit(t *testing.T) {
+	g := NewGuard(GuardConfig{Rate: 1, Burst: 2})
+	for i := range 2 {
+		if _, ok := g.Allow("10.0.0.1"); !ok {
+			t.Fatalf("expect request %d allowed", i)
+		}
+	}
+	if reason, ok := g.Allow("10.0.0.1"); ok || reason != BlockRateLimit {
+		t.Fatal("expect rate limited")
+	}
+	// 不同来源互不影响
+	if _, ok := g.Allow("10.0.0.2"); !ok {
+		t.Fatal("expect other ip allowed")
+	}
+	if v := NewGuard(GuardConfig{Rate: 0.2}).RetryAfter(); v != 5 {
+		t.Fatalf("expect retry after 5s, got %d", v)
+	}
+}
+
+func TestGuardBan(t *testing.T) {
+	g := NewGuard(GuardConfig{MaxFailures: 3, FailWindow: time.Minute, BanDuration: time.Minute})
+	const ip = "10.0.0.1"
+	g.Fail(ip, "wrong password")
+	g.Success(ip)
+	g.Fail(ip, "wrong password")
+	g.Fail(ip, "wrong password")
+	if g.Banned(ip) {
+		t.Fatal("expect failures reset after success")
+	}
+	if !g.Fail(ip, "wrong password") {
+		t.Fatal("expect banned")
+	}
+	if reason, ok := g.Allow(ip); ok || reason != BlockBanned {
+		t.Fatal("expect banned ip blocked")
+	}
+	if bans := g.Bans(); len(bans) != 1 || bans[0].IP != ip || bans[0].Failures != 3 {
+		t.Fatalf("unexpected bans %+v", bans)
+	}
+
+	if g.Unban(ip) != 1 || g.Banned(ip) {
+		t.Fatal("expect unbanned")
+	}
+}
+
+var _ GuardStore = (*testGuardStore)(nil)
+
+// testGuardStore 多个 Guard 共用，模拟集群共享存储
+type testGuardStore struct {
+	failures map[string]int
+	bans     map[string]Ban
+}
+
+func (s *testGuardStore) Fail(ip string, _ time.Duration) (int, error) {
+	s.failures[ip]++
+	return s.failures[ip], nil
+}
+
+func (s *testGuardStore) Success(ip string) error {
+	delete(s.failures, ip)
+	return nil
+}
+
+func (s *testGuardStore) Ban(ban Ban) error {
+	s.bans[ban.IP] = ban
+	return nil
+}
+
+func (s *testGuardStore) Unban(ip string) (int, error) {
+	if _, ok := s.bans[ip]; !ok {
+		return 0, nil
+	}
+	delete(s.bans, ip)
+	return 1, nil
+}
+
+func (s *testGuardStore) Bans() ([]Ban, error) {
+	out := make([]Ban, 0, len(s.bans))
+	for _, v := range s.bans {
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func TestGuardShared(t *testing.T) {
+	store := testGuardStore{failures: make(map[string]int), bans: make(map[string]Ban)}
+	cfg := GuardConfig{MaxFailures: 3, FailWindow: time.Minute, BanDuration: time.Minute}
+	a, b := NewGuard(cfg), NewGuard(cfg)
+	a.SetStore(&store)
+	b.SetStore(&store)
+	const ip = "10.0.0.1"
+
+	// 失败次数跨实例累计
+	a.Fail(ip, "wrong password")
+	b.Fail(ip, "wrong password")
+	if !a.Fail(ip, "wrong password") {
+		t.Fatal("expect banned by shared failures")
+	}
+	// 同步后其它实例也拦截
+	if b.Banned(ip) {
+		t.Fatal("expect not banned before sync")
+	}
+	if err := b.Sync(); err != nil || !b.Banned(ip) {
+		t.Fatalf("expect banned after sync, %v", err)
+	}
+	// 任一实例解除后，同步到其它实例
+	if b.Unban(ip) != 1 {
+		t.Fatal("expect unbanned")
+	}
+	if err := a.Sync(); err != nil || a.Banned(ip) {
+		t.Fatalf("expect unban synced, %v", err)
+	}
+}
+
+func TestSourceIP(t *testing.T) {
+	if ip := SourceIP(&net.UDPAddr{IP: net.IPv4(192, 168, 1, 2), Port: 5060}); ip != "192.168.1.2" {
+		t.Fatal(ip)
+	}
+	if ip := SourceIP(&net.TCPAddr{IP: net.ParseIP("::1"), Port: 5060}); ip != "::1" {
+		t.Fatal(ip)
+	}
+}
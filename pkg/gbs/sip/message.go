@@ -17,14 +17,14 @@ type MessageID string
 // It's nicer to avoid using raw strings to represent methods, so the following standard
 // method names are defined here as constants for convenience.
 const (
-	MethodInvite   = "INVITE"
-	MethodACK      = "ACK"
-	MethodCancel   = "CANCEL"
-	MethodBYE      = "BYE"
-	MethodRegister = "REGISTER"
-	MethodOptions  = "OPTIONS"
-	// SUBSCRIBE  = "SUBSCRIBE"
-	MethodNotify = "NOTIFY"
+	MethodInvite    = "INVITE"
+	MethodACK       = "ACK"
+	MethodCancel    = "CANCEL"
+	MethodBYE       = "BYE"
+	MethodRegister  = "REGISTER"
+	MethodOptions   = "OPTIONS"
+	MethodSubscribe = "SUBSCRIBE"
+	MethodNotify    = "NOTIFY"
 	// REFER    = "REFER"
 	MethodInfo    = "INFO"
 	MethodMessage = "MESSAGE"
@@ -39,6 +39,8 @@ type Message interface {
 	StartLine() string
 	// String returns string representation of SIP message in RFC 3261 form.
 	String() string
+	// Raw returns received bytes, or String() for local messages.
+	Raw() []byte
 	// SipVersion returns SIP protocol version.
 	SipVersion() string
 	// SetSipVersion sets SIP protocol version.
@@ -94,6 +96,7 @@ type message struct {
 	body         []byte
 	source, dest net.Addr
 	startLine    func() string
+	raw          []byte // 接收到的原始报文
 
 	conn Connection `json:"-"`
 }
@@ -122,6 +125,14 @@ func (msg *message) String() string {
 	return buffer.String()
 }
 
+// Raw 接收到的原始报文，本端创建的消息返回序列化后的报文
+func (msg *message) Raw() []byte {
+	if msg.raw != nil {
+		return msg.raw
+	}
+	return []byte(msg.String())
+}
+
 // SipVersion SipVersion
 func (msg *message) SipVersion() string {
 	return msg.sipVersion
@@ -0,0 +1,43 @@
+package sip
+
+import (
+	"strconv"
+	"strings"
+
+	"wvp/pkg/metrics"
+)
+
+const (
+	directionIn  = "in"
+	directionOut = "out"
+)
+
+var (
+	requestsTotal  = metrics.NewCounterVec("wvp_sip_requests_total", "SIP requests by direction and method.", "direction", "method")
+	responsesTotal = metrics.NewCounterVec("wvp_sip_responses_total", "SIP responses by direction, method and status code.", "direction", "method", "status")
+	blockedTotal   = metrics.NewCounterVec("wvp_sip_blocked_requests_total", "SIP requests dropped by rate limiting or bans.", "reason")
+	bansTotal      = metrics.NewCounterVec("wvp_sip_bans_total", "Source IPs banned after repeated authentication failures.")
+)
+
+// metricMethod 未知方法归为 OTHER，避免标签基数失控
+func metricMethod(method string) string {
+	method = strings.ToUpper(method)
+	switch method {
+	case MethodInvite, MethodACK, MethodCancel, MethodBYE, MethodRegister,
+		MethodOptions, MethodNotify, MethodInfo, MethodMessage, MethodSubscribe:
+		return method
+	}
+	return "OTHER"
+}
+
+func observeRequest(direction string, req *Request) {
+	requestsTotal.Inc(direction, metricMethod(req.Method()))
+}
+
+func observeResponse(direction string, res *Response) {
+	var method string
+	if cseq, ok := res.CSeq(); ok {
+		method = cseq.MethodName
+	}
+	responsesTotal.Inc(direction, metricMethod(method), strconv.Itoa(res.StatusCode()))
+}
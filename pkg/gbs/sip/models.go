@@ -104,9 +104,23 @@ var (
 <SN>%d</SN>
 <DeviceID>%s</DeviceID>
 </Query>
+`
+	// ConfigDownloadXML 设备配置查询xml样式
+	ConfigDownloadXML = `<?xml version="1.0" encoding="GB2312"?>
+<Query>
+<CmdType>ConfigDownload</CmdType>
+<SN>%d</SN>
+<DeviceID>%s</DeviceID>
+<ConfigType>%s</ConfigType>
+</Query>
 `
 )
 
+// GetConfigDownloadXML 设备配置查询指令，configType 如 BasicParam
+func GetConfigDownloadXML(id, configType string) []byte {
+	return []byte(fmt.Sprintf(ConfigDownloadXML, RandInt(100000, 999999), id, configType))
+}
+
 // GetDeviceInfoXML 获取设备详情指令
 func GetDeviceInfoXML(id string) []byte {
 	return []byte(fmt.Sprintf(DeviceInfoXML, RandInt(100000, 999999), id))
@@ -0,0 +1,91 @@
+package sip
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrStaleNonce nonce 不存在、已过期或被重放，需要重新质询
+var ErrStaleNonce = errors.New("stale nonce")
+
+// NonceStorer 已下发 nonce 的存储，集群部署时设备重新注册可能到达其它实例，需多个实例共享
+type NonceStorer interface {
+	Issue() (string, error)
+	Use(nonce, nc string) error // nonce 失效时返回 ErrStaleNonce
+}
+
+var _ NonceStorer = (*NonceStore)(nil)
+
+// NonceStore 记录已下发的 nonce，防止截获的 Authorization 被重放
+type NonceStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	items     map[string]*nonceItem
+	cleanedAt time.Time
+}
+
+type nonceItem struct {
+	expiresAt time.Time
+	nc        uint64 // 已使用的最大 nonce-count
+	used      bool   // 未携带 qop 时 nonce 只能使用一次
+}
+
+// NewNonceStore ttl 为 nonce 有效期
+func NewNonceStore(ttl time.Duration) *NonceStore {
+	return &NonceStore{
+		ttl:   ttl,
+		items: make(map[string]*nonceItem),
+	}
+}
+
+// Issue 下发新的 nonce
+func (s *NonceStore) Issue() (string, error) {
+	nonce := RandString(32)
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.cleanedAt) >= s.ttl {
+		s.cleanedAt = now
+		for k, v := range s.items {
+			if now.After(v.expiresAt) {
+				delete(s.items, k)
+			}
+		}
+	}
+	s.items[nonce] = &nonceItem{expiresAt: now.Add(s.ttl)}
+	return nonce, nil
+}
+
+// Use 校验并使用 nonce，nc 为十六进制 nonce-count，必须严格递增
+// 未携带 qop 时 nc 为空，nonce 仅能使用一次
+func (s *NonceStore) Use(nonce, nc string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[nonce]
+	if !ok {
+		return ErrStaleNonce
+	}
+	if time.Now().After(item.expiresAt) {
+		delete(s.items, nonce)
+		return ErrStaleNonce
+	}
+
+	if nc == "" {
+		if item.used {
+			return ErrStaleNonce
+		}
+		item.used = true
+		return nil
+	}
+	n, err := strconv.ParseUint(nc, 16, 64)
+	if err != nil || n <= item.nc {
+		return ErrStaleNonce
+	}
+	item.nc = n
+	item.used = true
+	return nil
+}
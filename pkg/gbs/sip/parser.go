@@ -558,9 +558,8 @@ func parseRecordRouteHeader(headerName string, headerText string) (headers []Hea
 }
 
 type parser struct {
-	out    chan Message
-	in     chan Packet
-	isStop bool
+	out chan Message
+	in  chan Packet
 }
 
 func newParser() *parser {
@@ -569,18 +568,18 @@ func newParser() *parser {
 	return p
 }
 
+// stop 关闭输入，解析协程处理完剩余数据后关闭输出，由写入 in 的协程调用
 func (p *parser) stop() {
-	p.isStop = true
+	close(p.in)
 }
 
 func (p *parser) start() {
+	defer close(p.out)
 	var termErr error
 	var msg Message
-	var packet Packet
 
-	for !p.isStop {
+	for packet := range p.in {
 		termErr = nil
-		packet = <-p.in
 		startLine, err := packet.nextLine()
 		if err != nil {
 			slog.Error("start nextLine", "err", err, "line", startLine)
@@ -663,6 +662,12 @@ func (p *parser) start() {
 		if len(body) != 0 {
 			msg.SetBody(body, false)
 		}
+		switch v := msg.(type) {
+		case *Request:
+			v.raw = packet.data
+		case *Response:
+			v.raw = packet.data
+		}
 		msg.SetSource(packet.raddr)
 		msg.SetConnection(packet.conn)
 		p.out <- msg
@@ -0,0 +1,170 @@
+package sip
+
+import (
+	"encoding/binary"
+	"io"
+	"net/netip"
+)
+
+const (
+	pcapMagic     = 0xa1b2c3d4
+	pcapSnapLen   = 65535
+	linkTypeRaw   = 101 // 链路层为裸 IP 报文
+	protocolTCP   = 6
+	protocolUDP   = 17
+	tcpFlagPshAck = 0x18
+)
+
+// pcapFlow tcp 单向流
+type pcapFlow struct {
+	src, dst netip.AddrPort
+}
+
+// WritePcap 将信令写为 pcap 文件，合成 IP 与 UDP/TCP 头部供 wireshark 解析
+// tcp 不合成握手，tls 信令以解密后的明文写入 tcp 报文
+func WritePcap(w io.Writer, msgs []CaptureMessage) error {
+	var hdr [24]byte
+	binary.LittleEndian.PutUint32(hdr[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	binary.LittleEndian.PutUint32(hdr[16:], pcapSnapLen)
+	binary.LittleEndian.PutUint32(hdr[20:], linkTypeRaw)
+	if _, err := w.Write(hdr[:]); err != nil {
+		return err
+	}
+
+	seqs := make(map[pcapFlow]uint32)
+	var id uint16
+	for _, msg := range msgs {
+		src, err1 := netip.ParseAddrPort(msg.Src)
+		dst, err2 := netip.ParseAddrPort(msg.Dst)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		src, dst = pcapAddrs(src, dst)
+
+		// ip 报文总长度不超过 65535，预留 ipv6 与 tcp 头部
+		payload := []byte(msg.Data)
+		payload = payload[:min(len(payload), pcapSnapLen-40-20)]
+		var (
+			protocol  byte = protocolUDP
+			transport []byte
+		)
+		if msg.Network == "udp" {
+			transport = udpSegment(src, dst, payload)
+		} else {
+			protocol = protocolTCP
+			flow := pcapFlow{src: src, dst: dst}
+			seq, ack := seqs[flow]+1, seqs[pcapFlow{src: dst, dst: src}]+1
+			seqs[flow] += uint32(len(payload))
+			transport = tcpSegment(src, dst, seq, ack, payload)
+		}
+
+		id++
+		packet := append(ipHeader(src.Addr(), dst.Addr(), protocol, len(transport), id), transport...)
+		var rec [16]byte
+		binary.LittleEndian.PutUint32(rec[0:], uint32(msg.Time.Unix()))
+		binary.LittleEndian.PutUint32(rec[4:], uint32(msg.Time.Nanosecond()/1000))
+		binary.LittleEndian.PutUint32(rec[8:], uint32(len(packet)))
+		binary.LittleEndian.PutUint32(rec[12:], uint32(len(packet)))
+		if _, err := w.Write(rec[:]); err != nil {
+			return err
+		}
+		if _, err := w.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pcapAddrs 统一地址族，ipv4 与 ipv6 混用时转为 ipv6
+func pcapAddrs(src, dst netip.AddrPort) (netip.AddrPort, netip.AddrPort) {
+	s, d := src.Addr().Unmap(), dst.Addr().Unmap()
+	if s.Is4() != d.Is4() {
+		s, d = netip.AddrFrom16(s.As16()), netip.AddrFrom16(d.As16())
+	}
+	return netip.AddrPortFrom(s, src.Port()), netip.AddrPortFrom(d, dst.Port())
+}
+
+// ipHeader 生成 ip 头部，length 为 ip 负载长度
+func ipHeader(src, dst netip.Addr, protocol byte, length int, id uint16) []byte {
+	if src.Is4() {
+		b := make([]byte, 20)
+		b[0] = 0x45
+		binary.BigEndian.PutUint16(b[2:], uint16(20+length))
+		binary.BigEndian.PutUint16(b[4:], id)
+		b[6] = 0x40 // don't fragment
+		b[8] = 64
+		b[9] = protocol
+		s, d := src.As4(), dst.As4()
+		copy(b[12:], s[:])
+		copy(b[16:], d[:])
+		binary.BigEndian.PutUint16(b[10:], ^uint16(fold(checksum(0, b))))
+		return b
+	}
+	b := make([]byte, 40)
+	b[0] = 0x60
+	binary.BigEndian.PutUint16(b[4:], uint16(length))
+	b[6] = protocol
+	b[7] = 64
+	s, d := src.As16(), dst.As16()
+	copy(b[8:], s[:])
+	copy(b[24:], d[:])
+	return b
+}
+
+func udpSegment(src, dst netip.AddrPort, payload []byte) []byte {
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint16(b[0:], src.Port())
+	binary.BigEndian.PutUint16(b[2:], dst.Port())
+	binary.BigEndian.PutUint16(b[4:], uint16(8+len(payload)))
+	b = append(b, payload...)
+	binary.BigEndian.PutUint16(b[6:], transportChecksum(src.Addr(), dst.Addr(), protocolUDP, b))
+	return b
+}
+
+func tcpSegment(src, dst netip.AddrPort, seq, ack uint32, payload []byte) []byte {
+	b := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(b[0:], src.Port())
+	binary.BigEndian.PutUint16(b[2:], dst.Port())
+	binary.BigEndian.PutUint32(b[4:], seq)
+	binary.BigEndian.PutUint32(b[8:], ack)
+	b[12] = 5 << 4 // 数据偏移，20 字节
+	b[13] = tcpFlagPshAck
+	binary.BigEndian.PutUint16(b[14:], 65535)
+	b = append(b, payload...)
+	binary.BigEndian.PutUint16(b[16:], transportChecksum(src.Addr(), dst.Addr(), protocolTCP, b))
+	return b
+}
+
+// transportChecksum 计算包含伪首部的 udp/tcp 校验和
+func transportChecksum(src, dst netip.Addr, protocol byte, segment []byte) uint16 {
+	var sum uint32
+	sum = checksum(sum, src.AsSlice())
+	sum = checksum(sum, dst.AsSlice())
+	sum += uint32(protocol) + uint32(len(segment))
+	v := ^uint16(fold(checksum(sum, segment)))
+	if v == 0 && protocol == protocolUDP {
+		// udp 校验和为 0 表示未计算
+		return 0xffff
+	}
+	return v
+}
+
+// checksum 按 16 位累加，结果需经 fold 折叠
+func checksum(sum uint32, b []byte) uint32 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(b[i:]))
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	return sum
+}
+
+func fold(sum uint32) uint32 {
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return sum
+}
@@ -76,10 +76,14 @@ func NewRequestFromResponse(method string, inviteResponse *Response) *Request {
 	CopyHeaders("From", inviteResponse, ackRequest)
 	CopyHeaders("To", inviteResponse, ackRequest)
 	CopyHeaders("Call-ID", inviteResponse, ackRequest)
-	cseq, _ := inviteResponse.CSeq()
-	cseq.MethodName = method
-	cseq.SeqNo++
-	ackRequest.AppendHeader(cseq)
+	// 复制 CSeq，避免修改原响应；ACK 与 INVITE 使用相同序号
+	if v, ok := inviteResponse.CSeq(); ok {
+		cseq := CSeq{SeqNo: v.SeqNo, MethodName: method}
+		if method != MethodACK {
+			cseq.SeqNo++
+		}
+		ackRequest.AppendHeader(&cseq)
+	}
 	ackRequest.SetSource(inviteResponse.Destination())
 	ackRequest.SetDestination(inviteResponse.Source())
 	return ackRequest
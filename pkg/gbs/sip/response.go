@@ -7,6 +7,9 @@ import (
 	"github.com/gofrs/uuid"
 )
 
+// StatusCallTransactionDoesNotExist 对话或事务不存在 (RFC 3261 21.4.19)
+const StatusCallTransactionDoesNotExist = 481
+
 // Response Response
 type Response struct {
 	message
@@ -100,6 +103,16 @@ func (res *Response) StatusCode() int {
 	return res.statusCode
 }
 
+// IsProvisional 1xx 临时响应
+func (res *Response) IsProvisional() bool {
+	return res.statusCode < 200
+}
+
+// IsSuccess 2xx 成功响应
+func (res *Response) IsSuccess() bool {
+	return res.statusCode >= 200 && res.statusCode < 300
+}
+
 // StartLine returns Response Status Line - RFC 2361 7.2.
 func (res *Response) StartLine() string {
 	var buffer bytes.Buffer
@@ -11,7 +11,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/ixugo/goweb/pkg/conc"
 )
@@ -32,6 +32,8 @@ type Server struct {
 
 	route conc.Map[string, []HandlerFunc] // 路由表，存储不同方法对应的处理函数
 
+	dialogs conc.Map[string, *Dialog] // 已建立的对话
+
 	port *Port  // UDP端口
 	host net.IP // 服务器IP地址
 
@@ -40,18 +42,28 @@ type Server struct {
 
 	tcpaddr net.Addr // TCP服务器地址
 
+	tlsPort     *Port            // TLS端口
+	tlsListener *net.TCPListener // TLS监听器
+	tlsaddr     net.Addr         // TLS服务器地址
+
 	ctx    context.Context    // 上下文，用于控制服务器生命周期
 	cancel context.CancelFunc // 取消函数
 
 	from *Address // 服务器地址信息
+
+	onTCPClose func(Connection) // tcp 连接断开回调
+
+	guard *Guard // 来源 IP 限速与封禁
+
+	capture *Capture // 信令抓包，为 nil 时不抓包
 }
 
 // NewServer sip server
 func NewServer(form *Address) *Server {
-	activeTX = &transacionts{txs: map[string]*Transaction{}, rwm: &sync.RWMutex{}}
 	ctx, cancel := context.WithCancel(context.TODO())
 	srv := &Server{
-		txs:    activeTX,
+		txs:    newTransactions(),
+		guard:  NewGuard(GuardConfig{}),
 		ctx:    ctx,
 		cancel: cancel,
 		from:   form,
@@ -77,22 +89,58 @@ func (s *Server) Notify(handler ...HandlerFunc) *RouteGroup {
 	return newRouteGroup(MethodNotify, s, handler...)
 }
 
+// Bye 对端结束对话，对话已由服务器删除
+func (s *Server) Bye(handler ...HandlerFunc) {
+	s.addRoute(MethodBYE, handler...)
+}
+
+// OnTCPClose 设置 tcp 连接断开回调，包括设备主动连接与服务端主动发起的连接
+func (s *Server) OnTCPClose(fn func(Connection)) {
+	s.onTCPClose = fn
+}
+
+// SetGuard 设置信令防护，需在监听前调用
+func (s *Server) SetGuard(g *Guard) {
+	s.guard = g
+}
+
+// Guard 信令防护
+func (s *Server) Guard() *Guard {
+	return s.guard
+}
+
+// SetCapture 开启信令抓包，传入 nil 时关闭
+func (s *Server) SetCapture(c *Capture) {
+	s.capture = c
+	s.txs.capture = c
+}
+
+// Capture 信令抓包，未开启时返回 nil
+func (s *Server) Capture() *Capture {
+	return s.capture
+}
+
+// acceptConn 拒绝被封禁 IP 的 tcp 连接
+func (s *Server) acceptConn(conn net.Conn) bool {
+	ip := SourceIP(conn.RemoteAddr())
+	if !s.guard.Banned(ip) {
+		return true
+	}
+	blockedTotal.Inc(BlockBanned)
+	_ = conn.Close()
+	return false
+}
+
 func (s *Server) getTX(key string) *Transaction {
 	return s.txs.getTX(key)
 }
 
-func (s *Server) mustTX(msg *Request) *Transaction {
-	key := getTXKey(msg)
-	tx := s.txs.getTX(key)
-
-	if tx == nil {
-		if msg.conn.Network() == "udp" {
-			tx = s.txs.newTX(key, s.udpConn)
-		} else {
-			tx = s.txs.newTX(key, msg.conn)
-		}
+// txConn 事务使用的连接，udp 统一使用监听连接
+func (s *Server) txConn(msg *Request) Connection {
+	if msg.conn == nil || msg.conn.Network() == "udp" {
+		return s.udpConn
 	}
-	return tx
+	return msg.conn
 }
 
 func (s *Server) UDPConn() Connection {
@@ -182,6 +230,9 @@ func (s *Server) ListenTCPServer(addr string) {
 				slog.Error("net.ListenTCP", "err", err, "addr", addr)
 				return
 			}
+			if !s.acceptConn(conn) {
+				continue
+			}
 			go s.ProcessTcpConn(conn)
 		}
 	}
@@ -200,13 +251,38 @@ func (s *Server) Close() {
 		s.tcpListener.Close()
 		s.tcpListener = nil
 	}
+	if s.tlsListener != nil {
+		s.tlsListener.Close()
+		s.tlsListener = nil
+	}
 }
 
 // ProcessTcpConn 处理传入的 TCP 连接。
 func (s *Server) ProcessTcpConn(conn net.Conn) {
-	defer conn.Close()
-	reader := bufio.NewReader(conn)
+	s.serveTCP(NewTCPConnection(conn))
+}
+
+// DialTCP 主动连接设备，设备没有可用的 tcp 连接时使用
+func (s *Server) DialTCP(addr string) (Connection, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
 	c := NewTCPConnection(conn)
+	go s.serveTCP(c)
+	return c, nil
+}
+
+// serveTCP 读取 tcp 连接上的 sip 消息，连接断开时触发 onTCPClose
+func (s *Server) serveTCP(c Connection) {
+	defer func() {
+		_ = c.Close()
+		if s.onTCPClose != nil {
+			s.onTCPClose(c)
+		}
+	}()
+	reader := bufio.NewReader(c)
+	raddr := c.RemoteAddr()
 
 	parser := newParser()
 	defer parser.stop()
@@ -248,36 +324,35 @@ func (s *Server) ProcessTcpConn(conn net.Conn) {
 			}
 		}
 
-		parser.in <- newPacket(buffer.Bytes(), conn.RemoteAddr(), c)
+		parser.in <- newPacket(buffer.Bytes(), raddr, c)
 	}
 }
 
-// handlerListen 处理接收到的SIP消息
+// handlerListen 处理接收到的SIP消息，解析器关闭输出后退出
 func (s *Server) handlerListen(msgs chan Message) {
-	var msg Message
-	for {
-		msg = <-msgs
+	for msg := range msgs {
 		switch tmsg := msg.(type) {
 		case *Request:
 			// 处理SIP请求消息
 			req := tmsg
-
-			dst := s.udpaddr
-			if req.conn.Network() == "tcp" {
-				dst = s.tcpaddr
+			// 限速与封禁在分发前处理，封禁的来源不响应
+			if reason, ok := s.guard.Allow(SourceIP(req.Source())); !ok {
+				blockedTotal.Inc(reason)
+				if reason == BlockRateLimit && !req.IsAck() {
+					s.rejectRateLimited(req)
+				}
+				continue
 			}
+			s.capture.record(directionIn, req, req.Raw(), req.conn, req.Source())
 
-			req.SetDestination(dst)
+			req.SetDestination(s.listenAddr(req.conn))
 			s.handlerRequest(req)
 		case *Response:
 			// 处理SIP响应消息
 			resp := tmsg
+			s.capture.record(directionIn, resp, resp.Raw(), resp.conn, resp.Source())
 
-			dst := s.udpaddr
-			if resp.conn.Network() == "tcp" {
-				dst = s.tcpaddr
-			}
-			resp.SetDestination(dst)
+			resp.SetDestination(s.listenAddr(resp.conn))
 			s.handlerResponse(resp)
 		default:
 			// 未知消息类型
@@ -286,7 +361,34 @@ func (s *Server) handlerListen(msgs chan Message) {
 }
 
 func (s *Server) handlerRequest(msg *Request) {
-	tx := s.mustTX(msg)
+	observeRequest(directionIn, msg)
+	txKey := serverTXKey(msg)
+	if tx := s.getTX(txKey); tx != nil {
+		// 重传的请求与非 2xx 响应的 ACK 由事务层吸收
+		tx.receiveRequest(msg)
+		return
+	}
+	if msg.IsAck() {
+		// 2xx 的 ACK 不属于任何事务
+		return
+	}
+	tx := s.txs.newServerTX(txKey, msg, s.txConn(msg))
+
+	dialog, ok := s.matchDialog(msg)
+	if ok {
+		if err := dialog.receiveRequest(msg); err != nil {
+			slog.Error("dialog request", "err", err, "method", msg.Method())
+			go handlerStatus(msg, tx, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			return
+		}
+	}
+	if msg.Method() == MethodBYE {
+		if !ok {
+			go handlerStatus(msg, tx, StatusCallTransactionDoesNotExist, "Call/Transaction Does Not Exist")
+			return
+		}
+		s.RemoveDialog(dialog)
+	}
 	// logrus.Traceln("receive request from:", msg.Source(), ",method:", msg.Method(), "txKey:", tx.key, "message: \n", msg.String())
 
 	key := msg.Method()
@@ -312,6 +414,7 @@ func (s *Server) handlerRequest(msg *Request) {
 	}
 
 	ctx := newContext(msg, tx)
+	ctx.Dialog = dialog
 	ctx.handlers = handlers
 	ctx.From = s.from
 	ctx.svr = s
@@ -319,7 +422,8 @@ func (s *Server) handlerRequest(msg *Request) {
 }
 
 func (s *Server) handlerResponse(msg *Response) {
-	tx := s.getTX(getTXKey(msg))
+	observeResponse(directionIn, msg)
+	tx := s.getTX(clientTXKey(msg))
 	if tx == nil {
 		// logrus.Infoln("not found tx. receive response from:", msg.Source(), "message: \n", msg.String())
 	} else {
@@ -328,26 +432,81 @@ func (s *Server) handlerResponse(msg *Response) {
 	}
 }
 
+// listenAddr 连接对应的本地监听地址
+func (s *Server) listenAddr(conn Connection) net.Addr {
+	switch conn.Network() {
+	case "tcp":
+		return s.tcpaddr
+	case "tls":
+		return s.tlsaddr
+	}
+	return s.udpaddr
+}
+
+// ContactAddress 按连接类型生成联系地址，tls 连接使用 tls 端口并携带 transport=tls
+func (s *Server) ContactAddress(from *Address, conn Connection) *Address {
+	if conn == nil || conn.Network() != "tls" || s.tlsPort == nil {
+		return from
+	}
+	addr := from.Clone()
+	addr.URI.FPort = s.tlsPort.Clone()
+	if addr.URI.FUriParams == nil {
+		addr.URI.FUriParams = NewParams()
+	}
+	addr.URI.FUriParams.Add("transport", String{Str: "tls"})
+	return addr
+}
+
 // Request Request
 func (s *Server) Request(req *Request) (*Transaction, error) {
 	viaHop, ok := req.ViaHop()
 	if !ok {
 		return nil, fmt.Errorf("missing required 'Via' header")
 	}
+	if req.conn == nil {
+		return nil, fmt.Errorf("missing connection")
+	}
 	viaHop.Host = s.host.String()
 	viaHop.Port = s.port
+	switch req.conn.Network() {
+	case "tcp":
+		viaHop.Transport = "TCP"
+		viaHop.Port = s.tcpPort
+	case "tls":
+		viaHop.Transport = "TLS"
+		viaHop.Port = s.tlsPort
+	}
 	if viaHop.Params == nil {
-		viaHop.Params = NewParams().Add("branch", String{Str: GenerateBranch()})
+		viaHop.Params = NewParams()
+	}
+	// 每个客户端事务需要唯一的 branch
+	if v, ok := viaHop.Params.Get("branch"); !ok || v == nil || v.String() == "" {
+		viaHop.Params.Add("branch", String{Str: GenerateBranch()})
 	}
 	if !viaHop.Params.Has("rport") {
 		viaHop.Params.Add("rport", nil)
 	}
 
-	tx := s.mustTX(req)
-	return tx, tx.Request(req)
+	return s.txs.newClientTX(req, s.txConn(req))
+}
+
+// rejectRateLimited 被限速的请求返回 503，设备按 Retry-After 重试，不创建事务 (RFC 3261 21.5.4)
+func (s *Server) rejectRateLimited(req *Request) {
+	res := NewResponseFromRequest("", req, http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable), nil)
+	res.AppendHeader(&GenericHeader{HeaderName: "Retry-After", Contents: strconv.Itoa(s.guard.RetryAfter())})
+	observeResponse(directionOut, res)
+	data := []byte(res.String())
+	s.capture.record(directionOut, res, data, req.conn, res.Destination())
+	if _, err := req.conn.WriteTo(data, res.Destination()); err != nil {
+		slog.Debug("rejectRateLimited", "err", err, "ip", SourceIP(req.Source()))
+	}
 }
 
 func handlerMethodNotAllowed(req *Request, tx *Transaction) {
-	resp := NewResponseFromRequest("", req, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), []byte{})
+	handlerStatus(req, tx, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+}
+
+func handlerStatus(req *Request, tx *Transaction, status int, reason string) {
+	resp := NewResponseFromRequest("", req, status, reason, []byte{})
 	tx.Respond(resp)
 }
@@ -0,0 +1,45 @@
+package sip
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestDialTCPClose(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s := NewServer(&Address{})
+	closed := make(chan Connection, 1)
+	s.OnTCPClose(func(c Connection) {
+		closed <- c
+	})
+
+	conn, err := s.DialTCP(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Network() != "tcp" {
+		t.Fatalf("expect tcp, got %s", conn.Network())
+	}
+
+	// 对端关闭连接，触发回调
+	peer, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	peer.Close()
+
+	select {
+	case c := <-closed:
+		if c != conn {
+			t.Fatal("closed connection mismatch")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("close callback not triggered")
+	}
+}
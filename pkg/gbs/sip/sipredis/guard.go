@@ -0,0 +1,105 @@
+package sipredis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"wvp/pkg/gbs/sip"
+	"wvp/pkg/redis"
+)
+
+var _ sip.GuardStore = GuardStore{}
+
+// failScript 首次失败时设置统计窗口
+const failScript = `local n = redis.call("INCR", KEYS[1]) if n == 1 then redis.call("PEXPIRE", KEYS[1], ARGV[1]) end return n`
+
+// GuardStore 多个实例共享的鉴权失败次数与封禁记录
+// 失败次数保存在 {prefix}sip_failures:{ip}，封禁记录保存在 {prefix}sip_bans 哈希表中
+type GuardStore struct {
+	cli    *redis.Client
+	prefix string
+}
+
+// NewGuardStore prefix 为键前缀
+func NewGuardStore(cli *redis.Client, prefix string) GuardStore {
+	return GuardStore{cli: cli, prefix: prefix}
+}
+
+func (s GuardStore) failKey(ip string) string {
+	return s.prefix + "sip_failures:" + ip
+}
+
+func (s GuardStore) bansKey() string {
+	return s.prefix + "sip_bans"
+}
+
+// Fail implements sip.GuardStore.
+func (s GuardStore) Fail(ip string, window time.Duration) (int, error) {
+	n, err := redis.Int64(s.cli.Do(context.Background(), "EVAL", failScript, "1", s.failKey(ip), ms(window)))
+	return int(n), err
+}
+
+// Success implements sip.GuardStore.
+func (s GuardStore) Success(ip string) error {
+	_, err := s.cli.Do(context.Background(), "DEL", s.failKey(ip))
+	return err
+}
+
+// Ban implements sip.GuardStore.
+func (s GuardStore) Ban(ban sip.Ban) error {
+	b, err := json.Marshal(ban)
+	if err != nil {
+		return err
+	}
+	_, err = s.cli.Do(context.Background(), "HSET", s.bansKey(), ban.IP, string(b))
+	return err
+}
+
+// Unban implements sip.GuardStore.
+func (s GuardStore) Unban(ip string) (int, error) {
+	ctx := context.Background()
+	if ip == "" {
+		n, err := redis.Int64(s.cli.Do(ctx, "HLEN", s.bansKey()))
+		if err != nil {
+			return 0, err
+		}
+		_, err = s.cli.Do(ctx, "DEL", s.bansKey())
+		return int(n), err
+	}
+	if _, err := s.cli.Do(ctx, "DEL", s.failKey(ip)); err != nil {
+		return 0, err
+	}
+	n, err := redis.Int64(s.cli.Do(ctx, "HDEL", s.bansKey(), ip))
+	return int(n), err
+}
+
+// Bans implements sip.GuardStore. 同时清理已过期的封禁记录
+func (s GuardStore) Bans() ([]sip.Ban, error) {
+	ctx := context.Background()
+	values, err := redis.Strings(s.cli.Do(ctx, "HGETALL", s.bansKey()))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	out := make([]sip.Ban, 0, len(values)/2)
+	expired := make([]string, 0, 2)
+	for i := 0; i+1 < len(values); i += 2 {
+		var ban sip.Ban
+		if err := json.Unmarshal([]byte(values[i+1]), &ban); err != nil {
+			return nil, fmt.Errorf("decode ban %s: %w", values[i], err)
+		}
+		if now.After(ban.ExpiresAt) {
+			expired = append(expired, values[i])
+			continue
+		}
+		out = append(out, ban)
+	}
+	if len(expired) > 0 {
+		if _, err := s.cli.Do(ctx, append([]string{"HDEL", s.bansKey()}, expired...)...); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package sipredis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"wvp/pkg/gbs/sip"
+	"wvp/pkg/redis"
+)
+
+var _ sip.NonceStorer = NonceStore{}
+
+const (
+	issueScript = `redis.call("HSET", KEYS[1], "nc", 0, "used", 0) return redis.call("PEXPIRE", KEYS[1], ARGV[1])`
+	// useScript ARGV[1] 为空时 nonce 仅能使用一次，否则 nonce-count 必须严格递增
+	useScript = `if redis.call("EXISTS", KEYS[1]) == 0 then return 0 end
+if ARGV[1] == "" then
+	if redis.call("HGET", KEYS[1], "used") == "1" then return 0 end
+else
+	if tonumber(ARGV[1]) <= tonumber(redis.call("HGET", KEYS[1], "nc")) then return 0 end
+	redis.call("HSET", KEYS[1], "nc", ARGV[1])
+end
+redis.call("HSET", KEYS[1], "used", 1)
+return 1`
+)
+
+// NonceStore 多个实例共享已下发的 nonce，每个 nonce 保存在 {prefix}sip_nonce:{nonce} 哈希表中
+type NonceStore struct {
+	cli    *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// NewNonceStore prefix 为键前缀，ttl 为 nonce 有效期
+func NewNonceStore(cli *redis.Client, prefix string, ttl time.Duration) NonceStore {
+	return NonceStore{cli: cli, prefix: prefix, ttl: ttl}
+}
+
+func (s NonceStore) key(nonce string) string {
+	return s.prefix + "sip_nonce:" + nonce
+}
+
+// Issue implements sip.NonceStorer.
+func (s NonceStore) Issue() (string, error) {
+	nonce := sip.RandString(32)
+	_, err := s.cli.Do(context.Background(), "EVAL", issueScript, "1", s.key(nonce), ms(s.ttl))
+	return nonce, err
+}
+
+// Use implements sip.NonceStorer.
+func (s NonceStore) Use(nonce, nc string) error {
+	if nc != "" {
+		n, err := strconv.ParseUint(nc, 16, 64)
+		if err != nil {
+			return sip.ErrStaleNonce
+		}
+		nc = strconv.FormatUint(n, 10)
+	}
+	n, err := redis.Int64(s.cli.Do(context.Background(), "EVAL", useScript, "1", s.key(nonce), nc))
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return sip.ErrStaleNonce
+	}
+	return nil
+}
+
+func ms(ttl time.Duration) string {
+	return strconv.FormatInt(max(1, ttl.Milliseconds()), 10)
+}
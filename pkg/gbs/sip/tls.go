@@ -0,0 +1,134 @@
+package sip
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// certCheckInterval 证书文件变更检查间隔
+const certCheckInterval = 10 * time.Second
+
+// certReloader 证书热加载，文件变更后新的握手使用新证书，已建立的连接不受影响
+type certReloader struct {
+	certFile, keyFile string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	c := certReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// latestModTime 证书与私钥中较新的修改时间
+func (c *certReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{c.certFile, c.keyFile} {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return latest, err
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+func (c *certReloader) reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	c.checkedAt = time.Now()
+	return nil
+}
+
+// GetCertificate 实现 tls.Config.GetCertificate，定期检查文件是否变更
+func (c *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	cert, modTime, checkedAt := c.cert, c.modTime, c.checkedAt
+	c.mu.RUnlock()
+	if time.Since(checkedAt) < certCheckInterval {
+		return cert, nil
+	}
+
+	c.mu.Lock()
+	c.checkedAt = time.Now()
+	c.mu.Unlock()
+
+	latest, err := c.latestModTime()
+	if err != nil || !latest.After(modTime) {
+		return cert, nil
+	}
+	if err := c.reload(); err != nil {
+		// 加载失败继续使用旧证书，可能是证书与私钥未同时更新完成
+		slog.Error("reload sip tls cert", "err", err)
+		return cert, nil
+	}
+	slog.Info("reload sip tls cert", "cert", c.certFile)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// ListenTLSServer 启动 TLS 服务器，证书文件变更后自动加载
+func (s *Server) ListenTLSServer(addr, certFile, keyFile string) {
+	tcpaddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		panic(fmt.Errorf("net.ResolveTCPAddr err[%w]", err))
+	}
+	certs, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		panic(fmt.Errorf("load sip tls cert err[%w]", err))
+	}
+	s.tlsaddr = tcpaddr
+	s.tlsPort = NewPort(tcpaddr.Port)
+
+	tcp, err := net.ListenTCP("tcp", tcpaddr)
+	if err != nil {
+		panic(fmt.Errorf("net.ListenTCP err[%w]", err))
+	}
+	s.tlsListener = tcp
+	l := tls.NewListener(tcp, &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.GetCertificate,
+	})
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			slog.Info("ListenTLSServer Has Been Exits")
+			return
+		default:
+			conn, err := l.Accept()
+			if err != nil {
+				slog.Error("tls.Accept", "err", err, "addr", addr)
+				return
+			}
+			if !s.acceptConn(conn) {
+				continue
+			}
+			go s.serveTCP(NewTLSConnection(conn))
+		}
+	}
+}
@@ -1,25 +1,47 @@
 package sip
 
 import (
-	"net/http"
+	"net"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
-	"unsafe"
 )
 
-var activeTX *transacionts
+// RFC 3261 17 事务层定时器基准值
+const (
+	T1 = 500 * time.Millisecond // RTT 估计值
+	T2 = 4 * time.Second        // 非 INVITE 请求与 INVITE 响应的最大重传间隔
+	T4 = 5 * time.Second        // 消息在网络中的最大存活时间
+)
+
+// txState 事务状态
+type txState int
+
+const (
+	txCalling    txState = iota // INVITE 客户端已发送请求
+	txTrying                    // 非 INVITE 已发送或收到请求
+	txProceeding                // 收到或发送临时响应
+	txCompleted                 // 收到或发送最终响应，吸收重传
+	txConfirmed                 // INVITE 服务端收到 ACK
+	txAccepted                  // INVITE 2xx 后吸收重传 (RFC 6026)
+	txTerminated
+)
 
 type transacionts struct {
-	txs map[string]*Transaction
-	rwm *sync.RWMutex
+	txs     map[string]*Transaction
+	rwm     *sync.RWMutex
+	capture *Capture
+}
+
+func newTransactions() *transacionts {
+	return &transacionts{txs: map[string]*Transaction{}, rwm: &sync.RWMutex{}}
 }
 
-func (txs *transacionts) newTX(key string, conn Connection) *Transaction {
-	tx := NewTransaction(key, conn)
+func (txs *transacionts) add(tx *Transaction) {
 	txs.rwm.Lock()
-	txs.txs[key] = tx
+	txs.txs[tx.key] = tx
 	txs.rwm.Unlock()
-	return tx
 }
 
 func (txs *transacionts) getTX(key string) *Transaction {
@@ -34,23 +56,92 @@ func (txs *transacionts) getTX(key string) *Transaction {
 
 func (txs *transacionts) rmTX(tx *Transaction) {
 	txs.rwm.Lock()
-	delete(txs.txs, tx.key)
+	if txs.txs[tx.key] == tx {
+		delete(txs.txs, tx.key)
+	}
 	txs.rwm.Unlock()
 }
 
-// Transaction Transaction
+// Transaction 客户端或服务端事务
 type Transaction struct {
-	conn   Connection
-	key    string
-	resp   chan *Response
-	active chan int
+	key      string
+	method   string
+	server   bool
+	reliable bool // tcp/tls 无需重传
+	conn     Connection
+	txs      *transacionts
+
+	mu       sync.Mutex
+	state    txState
+	req      *Request  // 客户端为发出的请求，服务端为收到的请求
+	data     []byte    // 客户端请求报文，用于重传
+	ack      *Request  // INVITE 客户端发出的 ACK，收到重传的最终响应时重发
+	last     *Response // 服务端最后发送的响应，收到重传的请求时重发
+	interval time.Duration
+
+	retrans    *time.Timer // Timer A/E/G
+	retransGen int
+	timeout    *time.Timer // Timer B/D/F/H/I/J/K/M
+	timeoutGen int
+
+	resp chan *Response
+	done chan struct{}
+}
+
+func newTransaction(txs *transacionts, key string, req *Request, conn Connection, server bool) *Transaction {
+	tx := Transaction{
+		key:      key,
+		method:   req.Method(),
+		server:   server,
+		reliable: conn.Network() != "udp",
+		conn:     conn,
+		txs:      txs,
+		req:      req,
+		resp:     make(chan *Response, 8),
+		done:     make(chan struct{}),
+	}
+	return &tx
+}
+
+// newClientTX 创建客户端事务并发送请求
+func (txs *transacionts) newClientTX(req *Request, conn Connection) (*Transaction, error) {
+	tx := newTransaction(txs, clientTXKey(req), req, conn, false)
+	tx.state = txTrying
+	if req.IsInvite() {
+		tx.state = txCalling
+	}
+	txs.add(tx)
+
+	observeRequest(directionOut, req)
+	tx.data = []byte(req.String())
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if err := tx.send(req, tx.data); err != nil {
+		tx.terminate()
+		return tx, err
+	}
+	if !tx.reliable {
+		tx.interval = T1
+		tx.setRetransmit(T1)
+	}
+	tx.setTimeout(64 * T1)
+	return tx, nil
 }
 
-// NewTransaction NewTransaction
-func NewTransaction(key string, conn Connection) *Transaction {
-	// logrus.Traceln("new tx", key, time.Now().Format("2006-01-02 15:04:05"))
-	tx := &Transaction{conn: conn, key: key, resp: make(chan *Response, 10), active: make(chan int, 1)}
-	go tx.watch()
+// newServerTX 创建服务端事务
+func (txs *transacionts) newServerTX(key string, req *Request, conn Connection) *Transaction {
+	tx := newTransaction(txs, key, req, conn, true)
+	tx.state = txTrying
+	if req.IsInvite() {
+		tx.state = txProceeding
+	}
+	txs.add(tx)
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	// 上层未响应时避免事务常驻
+	tx.setTimeout(64 * T1)
 	return tx
 }
 
@@ -59,78 +150,315 @@ func (tx *Transaction) Key() string {
 	return tx.key
 }
 
-func (tx *Transaction) watch() {
+// GetResponse 等待最终响应，跳过临时响应，事务超时返回 nil
+func (tx *Transaction) GetResponse() *Response {
 	for {
 		select {
-		case <-tx.active:
-			// logrus.Traceln("active tx", tx.Key(), time.Now().Format("2006-01-02 15:04:05"))
-		case <-time.After(20 * time.Second):
-			tx.Close()
-			// logrus.Traceln("watch closed tx", tx.key, time.Now().Format("2006-01-02 15:04:05"))
-			return
+		case res := <-tx.resp:
+			if res.IsProvisional() {
+				continue
+			}
+			return res
+		case <-tx.done:
+			for {
+				select {
+				case res := <-tx.resp:
+					if !res.IsProvisional() {
+						return res
+					}
+				default:
+					return nil
+				}
+			}
 		}
 	}
 }
 
-// GetResponse GetResponse
-func (tx *Transaction) GetResponse() *Response {
-	for {
-		res := <-tx.resp
-		if res == nil {
-			return res
+// Close 终止事务
+func (tx *Transaction) Close() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	tx.terminate()
+}
+
+// receiveResponse 客户端事务收到响应
+func (tx *Transaction) receiveResponse(res *Response) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	invite := tx.method == MethodInvite
+	switch tx.state {
+	case txCalling, txTrying, txProceeding:
+		tx.deliver(res)
+		if res.IsProvisional() {
+			tx.state = txProceeding
+			if invite {
+				// Timer A 在 Proceeding 状态停止
+				tx.setRetransmit(0)
+			}
+			return
+		}
+		tx.setRetransmit(0)
+		switch {
+		case invite && res.IsSuccess():
+			// 2xx 的 ACK 由上层发送，事务吸收 2xx 重传 (RFC 6026 Timer M)
+			tx.state = txAccepted
+			tx.setTimeout(64 * T1)
+		case invite:
+			// 3xx-6xx 由事务层发送 ACK (RFC 3261 17.1.1.3)
+			tx.ack = tx.ackFor(res)
+			_ = tx.send(tx.ack, []byte(tx.ack.String()))
+			tx.state = txCompleted
+			tx.setTimeout(tx.unreliable(32 * time.Second)) // Timer D
+		default:
+			tx.state = txCompleted
+			tx.setTimeout(tx.unreliable(T4)) // Timer K
 		}
-		tx.active <- 2
-		// logrus.Traceln("response tx", tx.key, time.Now().Format("2006-01-02 15:04:05"))
-		if res.StatusCode() == http.StatusContinue || res.statusCode == http.StatusSwitchingProtocols {
-			// Trying and Dialog Establishement 等待下一个返回
-			continue
+	case txCompleted, txAccepted:
+		// 重传的最终响应，重发 ACK
+		if invite && tx.ack != nil && !res.IsProvisional() {
+			_ = tx.write([]byte(tx.ack.String()), tx.ack.Destination())
 		}
-		return res
 	}
 }
 
-// Close Close
-func (tx *Transaction) Close() {
-	// logrus.Traceln("closed tx", tx.key, time.Now().Format("2006-01-02 15:04:05"))
-	activeTX.rmTX(tx)
-	close(tx.resp)
-	close(tx.active)
-}
+// receiveRequest 服务端事务收到重传的请求或 ACK
+func (tx *Transaction) receiveRequest(req *Request) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
 
-// Response Response
-func (tx *Transaction) receiveResponse(msg *Response) {
-	defer func() {
-		if r := recover(); r != nil {
-			// logrus.Errorln("send to closed channel, txkey:", tx.key, "message: \n", msg.String())
+	if req.IsAck() {
+		if tx.method == MethodInvite && tx.state == txCompleted {
+			tx.state = txConfirmed
+			tx.setRetransmit(0)
+			tx.setTimeout(tx.unreliable(T4)) // Timer I
 		}
-	}()
-	// logrus.Traceln("receiveResponse tx", tx.Key(), time.Now().Format("2006-01-02 15:04:05"))
-	tx.resp <- msg
-	tx.active <- 1
+		return
+	}
+	// 请求重传，重发最后的响应
+	if tx.last != nil && tx.state != txTerminated {
+		_ = tx.write([]byte(tx.last.String()), tx.last.Destination())
+	}
 }
 
-// Respond Respond
+// Respond 服务端事务发送响应
 func (tx *Transaction) Respond(res *Response) error {
-	// logrus.Traceln("send response,to:", res.dest.String(), "txkey:", tx.key, "message: \n", res.String())
-	_, err := tx.conn.WriteTo([]byte(res.String()), res.dest)
+	observeResponse(directionOut, res)
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	err := tx.send(res, []byte(res.String()))
+	if !tx.server {
+		return err
+	}
+	switch tx.state {
+	case txTrying, txProceeding:
+	default:
+		return err
+	}
+
+	tx.last = res
+	invite := tx.method == MethodInvite
+	switch {
+	case res.IsProvisional():
+		tx.state = txProceeding
+	case invite && res.IsSuccess():
+		// 吸收重传的 INVITE (RFC 6026 Timer L)
+		tx.state = txAccepted
+		tx.setTimeout(64 * T1)
+	case invite:
+		tx.state = txCompleted
+		if !tx.reliable {
+			tx.interval = T1
+			tx.setRetransmit(T1) // Timer G
+		}
+		tx.setTimeout(64 * T1) // Timer H
+	default:
+		tx.state = txCompleted
+		tx.setTimeout(tx.unreliable(64 * T1)) // Timer J
+	}
 	return err
 }
 
-// Request Request
+// Request 使用事务的连接发送请求，用于发送 2xx 的 ACK
 func (tx *Transaction) Request(req *Request) error {
-	str := req.String()
-	s := unsafe.Slice(unsafe.StringData(str), len(str))
-	// logrus.Traceln("send request,to:", req.dest.String(), "txkey:", tx.key, "message: \n", req.String())
-	_, err := tx.conn.WriteTo(s, req.dest)
+	observeRequest(directionOut, req)
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if req.IsAck() && tx.state == txAccepted {
+		tx.ack = req
+	}
+	return tx.send(req, []byte(req.String()))
+}
+
+// ackFor 生成非 2xx 最终响应的 ACK，与 INVITE 使用相同的 branch
+func (tx *Transaction) ackFor(res *Response) *Request {
+	ack := NewRequest("", MethodACK, tx.req.Recipient(), tx.req.SipVersion(), []Header{}, nil)
+	if via, ok := tx.req.ViaHop(); ok {
+		ack.AppendHeader(ViaHeader{via.Clone()})
+	}
+	CopyHeaders("Route", tx.req, ack)
+	CopyHeaders("From", tx.req, ack)
+	CopyHeaders("To", res, ack)
+	CopyHeaders("Call-ID", tx.req, ack)
+	if cseq, ok := tx.req.CSeq(); ok {
+		ack.AppendHeader(&CSeq{SeqNo: cseq.SeqNo, MethodName: MethodACK})
+	}
+	ack.SetSource(tx.req.Source())
+	ack.SetDestination(tx.req.Destination())
+	ack.SetConnection(tx.conn)
+	return ack
+}
+
+func (tx *Transaction) onRetransmit(gen int) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if gen != tx.retransGen || tx.state == txTerminated {
+		return
+	}
+
+	if tx.server {
+		// Timer G 重发最终响应
+		if tx.state != txCompleted || tx.last == nil {
+			return
+		}
+		_ = tx.write([]byte(tx.last.String()), tx.last.Destination())
+		tx.interval = min(2*tx.interval, T2)
+		tx.setRetransmit(tx.interval)
+		return
+	}
+
+	switch tx.state {
+	case txCalling:
+		// Timer A 每次加倍
+		tx.interval *= 2
+	case txTrying:
+		// Timer E 加倍直到 T2
+		tx.interval = min(2*tx.interval, T2)
+	case txProceeding:
+		if tx.method == MethodInvite {
+			return
+		}
+		tx.interval = T2
+	default:
+		return
+	}
+	_ = tx.write(tx.data, tx.req.Destination())
+	tx.setRetransmit(tx.interval)
+}
+
+// setRetransmit 设置重传定时器，d 为 0 时停止
+func (tx *Transaction) setRetransmit(d time.Duration) {
+	if tx.retrans != nil {
+		tx.retrans.Stop()
+	}
+	tx.retransGen++
+	if d <= 0 {
+		return
+	}
+	gen := tx.retransGen
+	tx.retrans = time.AfterFunc(d, func() { tx.onRetransmit(gen) })
+}
+
+// setTimeout 设置事务终止定时器，d 为 0 时立即终止
+func (tx *Transaction) setTimeout(d time.Duration) {
+	if tx.timeout != nil {
+		tx.timeout.Stop()
+	}
+	tx.timeoutGen++
+	if d <= 0 {
+		tx.terminate()
+		return
+	}
+	gen := tx.timeoutGen
+	tx.timeout = time.AfterFunc(d, func() {
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+		if gen == tx.timeoutGen {
+			tx.terminate()
+		}
+	})
+}
+
+// unreliable 可靠传输无需等待重传
+func (tx *Transaction) unreliable(d time.Duration) time.Duration {
+	if tx.reliable {
+		return 0
+	}
+	return d
+}
+
+func (tx *Transaction) terminate() {
+	if tx.state == txTerminated {
+		return
+	}
+	tx.state = txTerminated
+	tx.setRetransmit(0)
+	if tx.timeout != nil {
+		tx.timeout.Stop()
+	}
+	close(tx.done)
+	tx.txs.rmTX(tx)
+}
+
+func (tx *Transaction) deliver(res *Response) {
+	select {
+	case tx.resp <- res:
+	default:
+	}
+}
+
+// send 发送消息并记录抓包，重传直接使用 write
+func (tx *Transaction) send(msg Message, data []byte) error {
+	tx.txs.capture.record(directionOut, msg, data, tx.conn, msg.Destination())
+	return tx.write(data, msg.Destination())
+}
+
+func (tx *Transaction) write(data []byte, dest net.Addr) error {
+	_, err := tx.conn.WriteTo(data, dest)
 	return err
 }
 
-func getTXKey(msg Message) (key string) {
-	callid, ok := msg.CallID()
-	if ok {
-		key = callid.String()
-	} else {
-		key = RandString(10)
+// clientTXKey 客户端事务键 branch + CSeq 方法 (RFC 3261 17.1.3)
+func clientTXKey(msg Message) string {
+	var branch, method string
+	if via, ok := msg.ViaHop(); ok && via.Params != nil {
+		if v, ok := via.Params.Get("branch"); ok && v != nil {
+			branch = v.String()
+		}
+	}
+	if cseq, ok := msg.CSeq(); ok {
+		method = cseq.MethodName
+	}
+	return "c|" + branch + "|" + method
+}
+
+// serverTXKey 服务端事务键 branch + sent-by + 方法，ACK 匹配 INVITE 事务 (RFC 3261 17.2.3)
+// branch 不符合 RFC 3261 时使用 Call-ID + CSeq 序号匹配
+func serverTXKey(req *Request) string {
+	method := req.Method()
+	if method == MethodACK {
+		method = MethodInvite
+	}
+	var branch, sentBy string
+	if via, ok := req.ViaHop(); ok {
+		sentBy = via.Host + ":" + via.Port.String()
+		if via.Params != nil {
+			if v, ok := via.Params.Get("branch"); ok && v != nil {
+				branch = v.String()
+			}
+		}
+	}
+	if strings.HasPrefix(branch, RFC3261BranchMagicCookie) {
+		return "s|" + branch + "|" + sentBy + "|" + method
+	}
+
+	var callID, seq string
+	if v, ok := req.CallID(); ok {
+		callID = v.String()
+	}
+	if v, ok := req.CSeq(); ok {
+		seq = strconv.FormatUint(uint64(v.SeqNo), 10)
 	}
-	return
+	return "s|" + callID + "|" + seq + "|" + sentBy + "|" + method
 }
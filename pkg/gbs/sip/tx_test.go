@@ -0,0 +1,137 @@
+package sip
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRequest(t *testing.T, method string, dest net.Addr) *Request {
+	t.Helper()
+	uri, err := ParseSipURI("sip:34020000001320000001@127.0.0.1:5060")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := Address{URI: &uri, Params: NewParams()}
+	port := NewPort(5060)
+	hb := NewHeaderBuilder().SetTo(&addr).SetFrom(&addr).SetMethod(method).AddVia(&ViaHop{
+		Host:   "127.0.0.1",
+		Port:   port,
+		Params: NewParams().Add("branch", String{Str: GenerateBranch()}),
+	})
+	req := NewRequest("", method, &uri, DefaultSipVersion, hb.Build(), nil)
+	req.SetDestination(dest)
+	return req
+}
+
+// newTestUDP 返回本地 udp 连接与对端
+func newTestUDP(t *testing.T) (Connection, *net.UDPConn) {
+	t.Helper()
+	peer, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		peer.Close()
+		local.Close()
+	})
+	return NewUDPConnection(local), peer
+}
+
+// countPackets 统计对端在 d 时间内收到的报文
+func countPackets(peer *net.UDPConn, d time.Duration) int {
+	var n int
+	buf := make([]byte, 4096)
+	_ = peer.SetReadDeadline(time.Now().Add(d))
+	for {
+		if _, _, err := peer.ReadFrom(buf); err != nil {
+			return n
+		}
+		n++
+	}
+}
+
+func TestClientTXRetransmit(t *testing.T) {
+	conn, peer := newTestUDP(t)
+	txs := newTransactions()
+	req := newTestRequest(t, MethodMessage, peer.LocalAddr())
+
+	tx, err := txs.newClientTX(req, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 首次发送 + T1 后重传
+	if n := countPackets(peer, T1+T1/2); n != 2 {
+		t.Fatalf("expect 2 packets, got %d", n)
+	}
+
+	tx.receiveResponse(NewResponseFromRequest("", req, 200, "OK", nil))
+	if res := tx.GetResponse(); res == nil || res.StatusCode() != 200 {
+		t.Fatal("expect 200 response")
+	}
+	if n := countPackets(peer, 2*T1); n != 0 {
+		t.Fatalf("expect no retransmission after final response, got %d", n)
+	}
+}
+
+func TestClientTXInviteAck(t *testing.T) {
+	conn, peer := newTestUDP(t)
+	txs := newTransactions()
+	req := newTestRequest(t, MethodInvite, peer.LocalAddr())
+
+	tx, err := txs.newClientTX(req, conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	countPackets(peer, T1/2)
+
+	tx.receiveResponse(NewResponseFromRequest("", req, 486, "Busy Here", nil))
+	buf := make([]byte, 4096)
+	_ = peer.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := peer.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ack := string(buf[:n])
+	if !strings.HasPrefix(ack, MethodACK) {
+		t.Fatalf("expect ACK, got %q", ack)
+	}
+	via, _ := req.ViaHop()
+	branch, _ := via.Params.Get("branch")
+	if !strings.Contains(ack, branch.String()) {
+		t.Fatal("expect ACK with same branch")
+	}
+	if !strings.Contains(ack, "CSeq: 1 ACK") {
+		t.Fatalf("expect ACK with same CSeq number, got %q", ack)
+	}
+}
+
+func TestServerTXAbsorbRetransmit(t *testing.T) {
+	conn, peer := newTestUDP(t)
+	txs := newTransactions()
+	req := newTestRequest(t, MethodMessage, peer.LocalAddr())
+	req.SetSource(peer.LocalAddr())
+
+	key := serverTXKey(req)
+	tx := txs.newServerTX(key, req, conn)
+	res := NewResponseFromRequest("", req, 200, "OK", nil)
+	res.SetDestination(peer.LocalAddr())
+	if err := tx.Respond(res); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重传的请求匹配到同一事务，重发最后的响应
+	dup := txs.getTX(serverTXKey(req))
+	if dup != tx {
+		t.Fatal("expect retransmitted request match transaction")
+	}
+	dup.receiveRequest(req)
+	if n := countPackets(peer, T1/2); n != 2 {
+		t.Fatalf("expect 2 responses, got %d", n)
+	}
+}
@@ -1,11 +1,11 @@
 package gbs
 
 import (
-	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"wvp/internal/core/sms"
 	"wvp/pkg/gbs/sip"
 )
 
@@ -45,10 +45,13 @@ type Streams struct {
 	Stream bool `json:"stream" gorm:"column:stream"`
 
 	// ---
-	S, E time.Time     `json:"-" gorm:"-"`
-	ssrc string        // 国标ssrc 10进制字符串
-	Ext  int64         `json:"-" gorm:"-"` // 流等待过期时间
-	Resp *sip.Response `json:"-" gorm:"-"`
+	S, E   time.Time   `json:"-" gorm:"-"`
+	ssrc   string      // 国标ssrc 10进制字符串
+	port   int         // rtp 接收端口
+	Ext    int64       `json:"-" gorm:"-"` // 流等待过期时间
+	Dialog *sip.Dialog `json:"-" gorm:"-"` // 点播建立的对话
+
+	sms *sms.MediaServer // 接收流的媒体服务器
 }
 
 // 当前系统中存在的流列表
@@ -57,27 +60,10 @@ type streamsList struct {
 	Response *sync.Map
 	// key=channelid value={Play}  当前设备直播信息，防止重复直播
 	Succ *sync.Map
-	ssrc int
 }
 
 var StreamList streamsList
 
-func (g *GB28181API) getSSRC(t int) string {
-	r := false
-	for {
-		StreamList.ssrc++
-		// ssrc最大为四位数，超过时从1开始重新计算
-		if StreamList.ssrc > 9000 && !r {
-			StreamList.ssrc = 0
-			r = true
-		}
-		key := fmt.Sprintf("%d%s%04d", t, g.cfg.Domain[3:8], StreamList.ssrc)
-		// stream := Streams{StreamID: ssrc2stream(key), Stop: false}
-		// if err := db.Get(db.DBClient, &stream); db.RecordNotFound(err) || stream.CreatedAt == 0 {
-		return key
-	}
-}
-
 // 定时检查未关闭的流
 // 检查规则：
 // 1. 数据库查询当前status=0在推流状态的所有流信息
@@ -117,34 +103,11 @@ func CheckStreams() {
 			}
 			// logrus.Debugln("checkStreamClosed", stream.StreamID, stream.DeviceID)
 			// 关闭此流
-			channel := Channels{ChannelID: stream.ChannelID}
-			// if err := db.Get(db.DBClient, &channel); err != nil {
-			// 	// logrus.Errorln("checkStreamGetchannelError", stream.StreamID, stream.ChannelID, err)
-			// 	stream.Msg = err.Error()
-			// 	db.Save(db.DBClient, stream)
-			// 	channel = Channels{
-			// 		ChannelID: stream.ChannelID,
-			// 		DeviceID:  stream.DeviceID,
-			// 		URIStr:    fmt.Sprintf("sip:%s@%s", stream.ChannelID, _serverDevices.Region),
-			// 	}
-			// }
-			// channelURI, _ := sip.ParseURI(channel.URIStr)
-			// channel.addr = &sip.Address{URI: channelURI, Params: sip.NewParams()}
-			// for k, v := range stream.Ttag {
-			// 	channel.addr.Params.Add(k, sip.String{Str: v.(string)})
-			// }
-			// for k, v := range stream.Ftag {
-			// 	_serverDevices.addr.Params.Add(k, sip.String{Str: v.(string)})
-			// }
-			callid := sip.CallID(stream.CallID)
-			stream.CseqNo++
-
-			hb := sip.NewHeaderBuilder().SetToWithParam(channel.addr).SetFrom(_serverDevices.addr).AddVia(&sip.ViaHop{
-				Params: sip.NewParams().Add("branch", sip.String{Str: sip.GenerateBranch()}),
-			}).SetContentType(&sip.ContentTypeSDP).SetMethod(sip.MethodBYE).SetContact(_serverDevices.addr).SetCallID(&callid).SetSeqNo(uint(stream.CseqNo))
-			req := sip.NewRequest("", sip.MethodBYE, channel.addr.URI, sip.DefaultSipVersion, hb.Build(), nil)
+			if stream.Dialog == nil {
+				continue
+			}
+			req := stream.Dialog.NewRequest(sip.MethodBYE, nil, nil)
 			req.SetDestination(device.source)
-			req.SetRecipient(channel.addr.URI)
 
 			// 不管成功不成功 程序都删除掉，后面开新流，关闭不成功的后面重试
 			StreamList.Response.Delete(stream.StreamID)
@@ -0,0 +1,348 @@
+// Package metrics 以 prometheus 文本格式输出指标
+// 仅实现项目需要的 counter/gauge/histogram，不依赖 prometheus 客户端库
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType prometheus 文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets 默认耗时分桶(秒)
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default 默认注册表
+var Default = NewRegistry()
+
+// Collector 指标采集
+type Collector interface {
+	Name() string
+	write(w *bufio.Writer)
+}
+
+// Registry 指标注册表
+type Registry struct {
+	mu         sync.RWMutex
+	collectors []Collector
+}
+
+// NewRegistry 创建注册表
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// MustRegister 注册指标，名称重复时 panic
+func (r *Registry) MustRegister(c Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range r.collectors {
+		if v.Name() == c.Name() {
+			panic("metrics: duplicate metric " + c.Name())
+		}
+	}
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteTo 按名称顺序输出全部指标
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.RLock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.RUnlock()
+	slices.SortFunc(collectors, func(a, b Collector) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	cw := countWriter{w: w}
+	bw := bufio.NewWriter(&cw)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// Handler 指标输出
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// NewCounterVec 创建并注册到默认注册表
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := newCounterVec(name, help, labels)
+	Default.MustRegister(c)
+	return c
+}
+
+// NewHistogramVec 创建并注册到默认注册表，buckets 为空时使用 DefaultBuckets
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := newHistogramVec(name, help, buckets, labels)
+	Default.MustRegister(h)
+	return h
+}
+
+// NewGaugeFunc 创建并注册到默认注册表，每次抓取时调用 fn 采集
+func NewGaugeFunc(name, help string, labels []string, fn func(set func(v float64, labelValues ...string))) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help, labels: labels}, fn: fn}
+	Default.MustRegister(g)
+	return g
+}
+
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) Name() string { return d.name }
+
+func (d desc) writeHeader(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, typ)
+}
+
+// key 标签值拼接为 map 键
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expected %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+func (d desc) writeSample(w *bufio.Writer, suffix string, values []string, extraName, extraValue string, v float64) {
+	w.WriteString(d.name)
+	w.WriteString(suffix)
+	if len(values) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, l := range d.labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(l)
+			w.WriteString(`="`)
+			w.WriteString(escapeLabel(values[i]))
+			w.WriteByte('"')
+		}
+		if extraName != "" {
+			if len(values) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName)
+			w.WriteString(`="`)
+			w.WriteString(extraValue)
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+// CounterVec 按标签分组的计数器
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*sample
+}
+
+type sample struct {
+	labels []string
+	value  float64
+}
+
+func newCounterVec(name, help string, labels []string) *CounterVec {
+	return &CounterVec{desc: desc{name: name, help: help, labels: labels}, values: make(map[string]*sample)}
+}
+
+// Inc 加 1
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 增加 v，v 必须非负
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.values[k]
+	if !ok {
+		s = &sample{labels: slices.Clone(labelValues)}
+		c.values[k] = s
+	}
+	s.value += v
+}
+
+// Value 当前值
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	k := c.key(labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s, ok := c.values[k]; ok {
+		return s.value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	samples := make([]sample, 0, len(c.values))
+	for _, s := range c.values {
+		samples = append(samples, *s)
+	}
+	c.mu.Unlock()
+	sortSamples(samples)
+
+	c.writeHeader(w, "counter")
+	for _, s := range samples {
+		c.writeSample(w, "", s.labels, "", "", s.value)
+	}
+}
+
+// GaugeFunc 抓取时计算的仪表盘
+type GaugeFunc struct {
+	desc
+	fn func(set func(v float64, labelValues ...string))
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	samples := make([]sample, 0, 8)
+	g.fn(func(v float64, labelValues ...string) {
+		g.key(labelValues)
+		samples = append(samples, sample{labels: slices.Clone(labelValues), value: v})
+	})
+	sortSamples(samples)
+
+	g.writeHeader(w, "gauge")
+	for _, s := range samples {
+		g.writeSample(w, "", s.labels, "", "", s.value)
+	}
+}
+
+// HistogramVec 按标签分组的直方图
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogram
+}
+
+type histogram struct {
+	labels []string
+	counts []uint64 // 与 buckets 对应，非累计
+	count  uint64
+	sum    float64
+}
+
+func newHistogramVec(name, help string, buckets []float64, labels []string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &HistogramVec{
+		desc:    desc{name: name, help: help, labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.values[k]
+	if !ok {
+		s = &histogram{labels: slices.Clone(labelValues), counts: make([]uint64, len(h.buckets))}
+		h.values[k] = s
+	}
+	if i, _ := slices.BinarySearch(h.buckets, v); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += v
+}
+
+// Since 记录自 start 以来的耗时(秒)
+func (h *HistogramVec) Since(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	items := make([]histogram, 0, len(h.values))
+	for _, s := range h.values {
+		v := *s
+		v.counts = slices.Clone(s.counts)
+		items = append(items, v)
+	}
+	h.mu.Unlock()
+	slices.SortFunc(items, func(a, b histogram) int {
+		return slices.Compare(a.labels, b.labels)
+	})
+
+	h.writeHeader(w, "histogram")
+	for _, s := range items {
+		var cumulative uint64
+		for i, b := range h.buckets {
+			cumulative += s.counts[i]
+			h.writeSample(w, "_bucket", s.labels, "le", formatFloat(b), float64(cumulative))
+		}
+		h.writeSample(w, "_bucket", s.labels, "le", "+Inf", float64(s.count))
+		h.writeSample(w, "_sum", s.labels, "", "", s.sum)
+		h.writeSample(w, "_count", s.labels, "", "", float64(s.count))
+	}
+}
+
+func sortSamples(samples []sample) {
+	slices.SortFunc(samples, func(a, b sample) int {
+		return slices.Compare(a.labels, b.labels)
+	})
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpReplacer.Replace(s) }
+func escapeLabel(s string) string { return labelReplacer.Replace(s) }
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
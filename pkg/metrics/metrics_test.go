@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+
+	c := newCounterVec("test_requests_total", "Requests.", []string{"method"})
+	c.Inc("INVITE")
+	c.Add(2, "MESSAGE")
+	c.Add(-1, "MESSAGE")
+	r.MustRegister(c)
+
+	h := newHistogramVec("test_duration_seconds", "Duration.", []float64{1, 0.1}, nil)
+	h.Observe(0.1)
+	h.Observe(0.5)
+	h.Observe(3)
+	r.MustRegister(h)
+
+	r.MustRegister(&GaugeFunc{desc: desc{name: "test_up", help: "Up.", labels: []string{"id"}}, fn: func(set func(float64, ...string)) {
+		set(1, `a"b`)
+	}})
+
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := `# HELP test_duration_seconds Duration.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{le="0.1"} 1
+test_duration_seconds_bucket{le="1"} 2
+test_duration_seconds_bucket{le="+Inf"} 3
+test_duration_seconds_sum 3.6
+test_duration_seconds_count 3
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{method="INVITE"} 1
+test_requests_total{method="MESSAGE"} 2
+# HELP test_up Up.
+# TYPE test_up gauge
+test_up{id="a\"b"} 1
+`
+	if got := buf.String(); got != expect {
+		t.Fatalf("got:\n%s\nexpect:\n%s", got, expect)
+	}
+}
+
+func TestRegistryDuplicate(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister(newCounterVec("dup_total", "", nil))
+	defer func() {
+		if err := recover(); err == nil || !strings.Contains(err.(string), "dup_total") {
+			t.Fatalf("expect duplicate panic, got %v", err)
+		}
+	}()
+	r.MustRegister(newCounterVec("dup_total", "", nil))
+}
@@ -0,0 +1,318 @@
+// Package redis 精简的 RESP2 客户端，兼容 redis/valkey/keydb 等服务
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNil 键不存在
+var ErrNil = errors.New("redis: nil")
+
+// Error 服务端返回的错误
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Config 连接参数
+type Config struct {
+	Addr     string
+	Password string
+	DB       int
+	Timeout  time.Duration // 单次命令超时，默认 5 秒
+	PoolSize int           // 最大连接数，默认 10
+}
+
+// idleCheck 连接空闲超过该时间，复用前检查是否已被服务端关闭
+const idleCheck = time.Second
+
+// Client 连接池客户端，每条命令独占一个连接，连接断开后自动重连
+type Client struct {
+	cfg Config
+	sem chan struct{} // 限制同时打开的连接数
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+type conn struct {
+	net.Conn
+	rd     *bufio.Reader
+	usedAt time.Time
+}
+
+// NewClient 执行命令时按需建立连接
+func NewClient(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	return &Client{cfg: cfg, sem: make(chan struct{}, cfg.PoolSize)}
+}
+
+// Close 关闭空闲连接，执行中的命令结束后关闭其连接
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for _, cn := range c.idle {
+		if e := cn.Close(); e != nil {
+			err = e
+		}
+	}
+	c.idle = nil
+	return err
+}
+
+// Do 执行命令，返回值为 string/int64/[]any，空值返回 nil
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() { <-c.sem }()
+
+	cn, reused, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v, err := c.do(ctx, cn, args)
+	// 复用的连接可能已被服务端关闭，仅在命令未发出时使用新连接重试
+	// 已发出的命令可能已被执行，SET NX、EVAL 等非幂等命令不能重放
+	var we writeError
+	if err != nil && reused && errors.As(err, &we) {
+		if cn, err = c.dial(ctx); err != nil {
+			return nil, err
+		}
+		v, err = c.do(ctx, cn, args)
+	}
+	return v, err
+}
+
+// do 执行命令，连接层错误时关闭连接，否则放回连接池
+func (c *Client) do(ctx context.Context, cn *conn, args []string) (any, error) {
+	v, err := c.roundTrip(ctx, cn, args)
+	if isConnErr(err) {
+		_ = cn.Close()
+		return v, err
+	}
+	cn.usedAt = time.Now()
+	c.mu.Lock()
+	c.idle = append(c.idle, cn)
+	c.mu.Unlock()
+	return v, err
+}
+
+// get 取出最近使用的空闲连接，没有可用连接时新建
+func (c *Client) get(ctx context.Context) (*conn, bool, error) {
+	for {
+		c.mu.Lock()
+		n := len(c.idle)
+		if n == 0 {
+			c.mu.Unlock()
+			cn, err := c.dial(ctx)
+			return cn, false, err
+		}
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		if !cn.stale() {
+			return cn, true, nil
+		}
+		_ = cn.Close()
+	}
+}
+
+// stale 空闲的连接是否已不可用，服务端关闭的连接读取时立即返回 EOF，正常的连接读取超时
+func (cn *conn) stale() bool {
+	if time.Since(cn.usedAt) < idleCheck {
+		return false
+	}
+	_ = cn.SetReadDeadline(time.Now().Add(time.Millisecond))
+	_, err := cn.rd.Peek(1)
+	var ne net.Error
+	return !errors.As(err, &ne) || !ne.Timeout()
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	d := net.Dialer{Timeout: c.cfg.Timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	cn := conn{Conn: nc, rd: bufio.NewReader(nc)}
+	if c.cfg.Password != "" {
+		if _, err := c.roundTrip(ctx, &cn, []string{"AUTH", c.cfg.Password}); err != nil {
+			_ = cn.Close()
+			return nil, err
+		}
+	}
+	if c.cfg.DB > 0 {
+		if _, err := c.roundTrip(ctx, &cn, []string{"SELECT", strconv.Itoa(c.cfg.DB)}); err != nil {
+			_ = cn.Close()
+			return nil, err
+		}
+	}
+	return &cn, nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, cn *conn, args []string) (any, error) {
+	deadline := time.Now().Add(c.cfg.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = cn.SetDeadline(deadline)
+
+	buf := make([]byte, 0, 64)
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	if _, err := cn.Write(buf); err != nil {
+		return nil, writeError{err}
+	}
+	return readReply(cn.rd)
+}
+
+// writeError 命令写入失败，服务端未收到完整命令，可以安全重试
+type writeError struct{ err error }
+
+func (e writeError) Error() string { return e.err.Error() }
+
+func (e writeError) Unwrap() error { return e.err }
+
+// readReply 解析 RESP2 应答
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, errProtocol(line)
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, Error(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errProtocol(line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, b); err != nil {
+			return nil, err
+		}
+		return string(b[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errProtocol(line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		out := make([]any, n)
+		for i := range out {
+			v, err := readReply(rd)
+			// 数组内的错误不影响其它元素
+			var e Error
+			if err != nil && !errors.As(err, &e) {
+				return nil, err
+			}
+			out[i] = v
+		}
+		return out, nil
+	}
+	return nil, errProtocol(line)
+}
+
+type protocolError string
+
+func (e protocolError) Error() string { return "redis: protocol error, " + string(e) }
+
+func errProtocol(line string) error {
+	return protocolError(fmt.Sprintf("%q", line))
+}
+
+// isConnErr 连接层错误，服务端错误与空值不需要重连
+func isConnErr(err error) bool {
+	var e Error
+	return err != nil && !errors.As(err, &e) && !errors.Is(err, ErrNil)
+}
+
+// String 转换为字符串，空值返回 ErrNil
+func String(v any, err error) (string, error) {
+	if err != nil {
+		return "", err
+	}
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case int64:
+		return strconv.FormatInt(s, 10), nil
+	case nil:
+		return "", ErrNil
+	}
+	return "", fmt.Errorf("redis: unexpected type %T", v)
+}
+
+// Int64 转换为整数
+func Int64(v any, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	switch s := v.(type) {
+	case int64:
+		return s, nil
+	case string:
+		return strconv.ParseInt(s, 10, 64)
+	case nil:
+		return 0, ErrNil
+	}
+	return 0, fmt.Errorf("redis: unexpected type %T", v)
+}
+
+// Strings 转换为字符串数组，空值元素为空串
+func Strings(v any, err error) ([]string, error) {
+	if err != nil {
+		return nil, err
+	}
+	arr, ok := v.([]any)
+	if !ok {
+		if v == nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redis: unexpected type %T", v)
+	}
+	out := make([]string, len(arr))
+	for i, item := range arr {
+		s, _ := item.(string)
+		out[i] = s
+	}
+	return out, nil
+}
@@ -0,0 +1,243 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer 仅支持测试用到的命令
+type fakeServer struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	kv    map[string]string
+	conns []net.Conn
+	execs int
+	block chan struct{}
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := fakeServer{ln: ln, kv: make(map[string]string), block: make(chan struct{})}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns = append(s.conns, conn)
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return &s
+}
+
+// dropConns 断开全部连接，模拟服务端重启
+func (s *fakeServer) dropConns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.conns {
+		c.Close()
+	}
+	s.conns = nil
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		v, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		arr, _ := v.([]any)
+		args := make([]string, len(arr))
+		for i, a := range arr {
+			args[i], _ = a.(string)
+		}
+		// 执行后不应答直接断开，模拟应答丢失
+		if strings.ToUpper(args[0]) == "HANG" {
+			s.mu.Lock()
+			s.execs++
+			s.mu.Unlock()
+			return
+		}
+		// 阻塞至测试放行，模拟慢命令
+		if strings.ToUpper(args[0]) == "BLOCK" {
+			<-s.block
+		}
+		if _, err := io.WriteString(conn, s.exec(args)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) exec(args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch strings.ToUpper(args[0]) {
+	case "AUTH":
+		if args[1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SET":
+		s.kv[args[1]] = args[2]
+		return "+OK\r\n"
+	case "GET":
+		v, ok := s.kv[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+	case "MGET":
+		out := "*" + strconv.Itoa(len(args)-1) + "\r\n"
+		for _, k := range args[1:] {
+			v, ok := s.kv[k]
+			if !ok {
+				out += "$-1\r\n"
+				continue
+			}
+			out += "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+		}
+		return out
+	case "BLOCK":
+		return "+OK\r\n"
+	case "DEL":
+		var n int
+		for _, k := range args[1:] {
+			if _, ok := s.kv[k]; ok {
+				delete(s.kv, k)
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	}
+	return "-ERR unknown command '" + args[0] + "'\r\n"
+}
+
+func TestClientDo(t *testing.T) {
+	s := newFakeServer(t)
+	c := NewClient(Config{Addr: s.ln.Addr().String(), Password: "secret"})
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, err := c.Do(ctx, "SET", "k1", "hello\r\nworld"); err != nil {
+		t.Fatal(err)
+	}
+	v, err := String(c.Do(ctx, "GET", "k1"))
+	if err != nil || v != "hello\r\nworld" {
+		t.Fatalf("expect bulk string, got %q %v", v, err)
+	}
+	if _, err := String(c.Do(ctx, "GET", "k2")); !errors.Is(err, ErrNil) {
+		t.Fatalf("expect ErrNil, got %v", err)
+	}
+	items, err := Strings(c.Do(ctx, "MGET", "k1", "k2"))
+	if err != nil || len(items) != 2 || items[0] != "hello\r\nworld" || items[1] != "" {
+		t.Fatalf("unexpected mget %q %v", items, err)
+	}
+	var e Error
+	if _, err := c.Do(ctx, "PING"); !errors.As(err, &e) {
+		t.Fatalf("expect server error, got %v", err)
+	}
+
+	// 服务端断开后，空闲的连接复用前检查并重连
+	s.dropConns()
+	for _, cn := range c.idle {
+		cn.usedAt = cn.usedAt.Add(-idleCheck)
+	}
+	n, err := Int64(c.Do(ctx, "DEL", "k1"))
+	if err != nil || n != 1 {
+		t.Fatalf("expect reconnect and del 1, got %d %v", n, err)
+	}
+}
+
+func TestClientAuthFailed(t *testing.T) {
+	s := newFakeServer(t)
+	c := NewClient(Config{Addr: s.ln.Addr().String(), Password: "wrong"})
+	defer c.Close()
+	var e Error
+	if _, err := c.Do(context.Background(), "GET", "k1"); !errors.As(err, &e) {
+		t.Fatalf("expect auth error, got %v", err)
+	}
+}
+
+func TestClientNoReplay(t *testing.T) {
+	s := newFakeServer(t)
+	c := NewClient(Config{Addr: s.ln.Addr().String()})
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, err := c.Do(ctx, "SET", "k1", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	// 命令已发出但未收到应答，不能重试
+	if _, err := c.Do(ctx, "HANG"); err == nil {
+		t.Fatal("expect connection error")
+	}
+	s.mu.Lock()
+	execs := s.execs
+	s.mu.Unlock()
+	if execs != 1 {
+		t.Fatalf("expect command executed once, got %d", execs)
+	}
+	if v, err := String(c.Do(ctx, "GET", "k1")); err != nil || v != "v1" {
+		t.Fatalf("expect reconnect, got %q %v", v, err)
+	}
+}
+
+func TestClientPool(t *testing.T) {
+	s := newFakeServer(t)
+	c := NewClient(Config{Addr: s.ln.Addr().String(), PoolSize: 2})
+	defer c.Close()
+	ctx := context.Background()
+
+	// 慢命令占用一个连接时，其它命令使用另一个连接
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Do(ctx, "BLOCK")
+		done <- err
+	}()
+	for i := 0; i < 3; i++ {
+		if _, err := c.Do(ctx, "SET", "k1", "v1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 连接数已满时等待可用连接，超时返回
+	go func() { _, _ = c.Do(ctx, "BLOCK") }()
+	for {
+		if len(c.sem) == 2 {
+			break
+		}
+		runtime.Gosched()
+	}
+	tctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Do(tctx, "GET", "k1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect deadline exceeded, got %v", err)
+	}
+
+	close(s.block)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if v, err := String(c.Do(ctx, "GET", "k1")); err != nil || v != "v1" {
+		t.Fatalf("expect v1, got %q %v", v, err)
+	}
+}
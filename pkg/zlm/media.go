@@ -0,0 +1,46 @@
+package zlm
+
+const (
+	getMediaList = `/index/api/getMediaList`
+)
+
+type GetMediaListRequest struct {
+	Schema string `json:"schema,omitempty"` // 筛选协议，例如 rtsp 或 rtmp
+	Vhost  string `json:"vhost,omitempty"`  // 筛选虚拟主机，例如__defaultVhost__
+	App    string `json:"app,omitempty"`    // 筛选应用名，例如 live
+	Stream string `json:"stream,omitempty"` // 筛选流 id，例如 test
+}
+
+type GetMediaListResponse struct {
+	FixedHeader
+	Data []MediaItem `json:"data"`
+}
+
+type MediaItem struct {
+	App              string `json:"app"`              // 应用名
+	Stream           string `json:"stream"`           // 流 id
+	Schema           string `json:"schema"`           // 协议
+	Vhost            string `json:"vhost"`            // 虚拟主机名
+	OriginType       int    `json:"originType"`       // 产生源类型，3 为 rtp 推流
+	ReaderCount      int    `json:"readerCount"`      // 本协议观看人数
+	TotalReaderCount int    `json:"totalReaderCount"` // 观看总人数，包括 hls/rtsp/rtmp/http-flv/ws-flv/rtc
+	AliveSecond      int    `json:"aliveSecond"`      // 存活时间，单位秒
+	BytesSpeed       int    `json:"bytesSpeed"`       // 数据产生速度，单位 byte/s
+}
+
+// GetMediaList 获取流列表，可选筛选参数
+// https://docs.zlmediakit.com/zh/guide/media_server/restful_api.html#_5%E3%80%81-index-api-getmedialist
+func (e *Engine) GetMediaList(in GetMediaListRequest) (*GetMediaListResponse, error) {
+	body, err := struct2map(in)
+	if err != nil {
+		return nil, err
+	}
+	var resp GetMediaListResponse
+	if err := e.post(getMediaList, body, &resp); err != nil {
+		return nil, err
+	}
+	if err := e.ErrHandle(resp.Code, resp.Msg); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
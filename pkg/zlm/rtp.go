@@ -3,6 +3,7 @@ package zlm
 const (
 	openRtpServer  = `/index/api/openRtpServer`
 	closeRtpServer = `/index/api/closeRtpServer`
+	getRtpInfo     = `/index/api/getRtpInfo`
 )
 
 type OpenRTPServerResponse struct {
@@ -11,9 +12,10 @@ type OpenRTPServerResponse struct {
 	Port int    `json:"port"` // 接收端口，方便获取随机端口号
 }
 type OpenRTPServerRequest struct {
-	Port     int    `json:"port"`      // 接收端口，0 则为随机端口
-	TCPMode  int8   `json:"tcp_mode"`  // 0 udp 模式，1 tcp 被动模式, 2 tcp 主动模式。 (兼容 enable_tcp 为 0/1)
-	StreamID string `json:"stream_id"` // 该端口绑定的流 ID，该端口只能创建这一个流(而不是根据 ssrc 创建多个)
+	Port     int    `json:"port"`           // 接收端口，0 则为随机端口
+	TCPMode  int8   `json:"tcp_mode"`       // 0 udp 模式，1 tcp 被动模式, 2 tcp 主动模式。 (兼容 enable_tcp 为 0/1)
+	StreamID string `json:"stream_id"`      // 该端口绑定的流 ID，该端口只能创建这一个流(而不是根据 ssrc 创建多个)
+	SSRC     uint32 `json:"ssrc,omitempty"` // 指定 ssrc，不匹配的 rtp 包将被丢弃
 }
 
 // OpenRTPServer 创建 GB28181 RTP 接收端口，如果该端口接收数据超时，则会自动被回收(不用调用 closeRtpServer 接口)
@@ -58,3 +60,33 @@ func (e *Engine) CloseRTPServer(in CloseRTPServerRequest) (*CloseRTPServerRespon
 	}
 	return &resp, nil
 }
+
+type GetRTPInfoRequest struct {
+	StreamID string `json:"stream_id"` // 流 ID
+}
+
+type GetRTPInfoResponse struct {
+	FixedHeader
+	Exist     bool   `json:"exist"`      // 是否存在，仅收到 rtp 数据后存在
+	PeerIP    string `json:"peer_ip"`    // 推流客户端 ip
+	PeerPort  int    `json:"peer_port"`  // 推流客户端端口号
+	LocalIP   string `json:"local_ip"`   // 本地监听的网卡 ip
+	LocalPort int    `json:"local_port"` // 本地监听端口号
+}
+
+// GetRTPInfo 获取 rtp 推流信息
+// https://docs.zlmediakit.com/zh/guide/media_server/restful_api.html#_21%E3%80%81-index-api-getrtpinfo
+func (e *Engine) GetRTPInfo(in GetRTPInfoRequest) (*GetRTPInfoResponse, error) {
+	body, err := struct2map(in)
+	if err != nil {
+		return nil, err
+	}
+	var resp GetRTPInfoResponse
+	if err := e.post(getRtpInfo, body, &resp); err != nil {
+		return nil, err
+	}
+	if err := e.ErrHandle(resp.Code, resp.Msg); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -8,8 +8,13 @@ import (
 	"maps"
 	"net/http"
 	"time"
+
+	"wvp/pkg/metrics"
 )
 
+// apiErrorsTotal 按接口统计 zlm 调用失败次数
+var apiErrorsTotal = metrics.NewCounterVec("wvp_zlm_api_errors_total", "ZLMediaKit API call errors by path and reason.", "path", "reason")
+
 const (
 	Exception   = -400 // 代码抛异常
 	InvalidArgs = -300 // 参数不合法
@@ -57,12 +62,27 @@ func (e *Engine) post(path string, data map[string]any, out any) error {
 
 	resp, err := e.cli.Post(e.cfg.URL+path, "application/json", bytes.NewReader(body))
 	if err != nil {
+		apiErrorsTotal.Inc(path, "request")
 		return err
 	}
 	defer resp.Body.Close()
-	// b, _ := io.ReadAll(resp.Body)
-	// fmt.Println(string(b))
-	return json.NewDecoder(resp.Body).Decode(out)
+	if resp.StatusCode != http.StatusOK {
+		apiErrorsTotal.Inc(path, "status")
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		apiErrorsTotal.Inc(path, "request")
+		return err
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		apiErrorsTotal.Inc(path, "decode")
+		return err
+	}
+	var header FixedHeader
+	if json.Unmarshal(b, &header) == nil && header.Code != Success {
+		apiErrorsTotal.Inc(path, "code")
+	}
+	return nil
 }
 
 // post2 直接读取全部响应返回